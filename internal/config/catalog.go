@@ -0,0 +1,188 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"log-monitor/internal/logger"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CatalogConfig points at a shared, read-only list of servers maintained
+// elsewhere (e.g. by the infra team).
+type CatalogConfig struct {
+	Source  string `yaml:"source"`   // http(s) URL or local file path
+	GitPull bool   `yaml:"git_pull"` // run `git pull --ff-only` in the file's directory before reading
+}
+
+// maxCatalogSize caps how much is read from a catalog source.
+const maxCatalogSize = 4 << 20
+
+// FetchCatalog retrieves the shared server catalog and parses it with the
+// user's defaults applied.
+func FetchCatalog(ctx context.Context, c CatalogConfig, d Defaults) ([]ServerConfig, error) {
+	data, err := readCatalog(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	servers, err := ParseCatalog(data, d)
+	if err != nil {
+		return nil, err
+	}
+	logger.Log("catalog", "loaded %d servers from %s", len(servers), c.Source)
+	return servers, nil
+}
+
+func readCatalog(ctx context.Context, c CatalogConfig) ([]byte, error) {
+	if strings.HasPrefix(c.Source, "http://") || strings.HasPrefix(c.Source, "https://") {
+		return readCatalogURL(ctx, c.Source)
+	}
+	if c.GitPull {
+		dir := filepath.Dir(c.Source)
+		logger.Log("catalog", "git pull in %s", dir)
+		out, err := exec.CommandContext(ctx, "git", "-C", dir, "pull", "--ff-only", "--quiet").CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("git pull in %s: %w: %s", dir, err, strings.TrimSpace(string(out)))
+		}
+	}
+	data, err := os.ReadFile(c.Source)
+	if err != nil {
+		return nil, fmt.Errorf("reading catalog: %w", err)
+	}
+	return data, nil
+}
+
+func readCatalogURL(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetching catalog: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching catalog: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching catalog %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCatalogSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetching catalog: %w", err)
+	}
+	if len(data) > maxCatalogSize {
+		return nil, fmt.Errorf("catalog %s is larger than %d bytes", url, maxCatalogSize)
+	}
+	return data, nil
+}
+
+// ParseCatalog parses catalog YAML, which uses the same `servers:` layout
+// (and versions) as the config file. The user's defaults are applied to
+// every entry.
+func ParseCatalog(data []byte, d Defaults) ([]ServerConfig, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing catalog: %w", err)
+	}
+	if _, err := migrate(&doc); err != nil {
+		return nil, fmt.Errorf("parsing catalog: %w", err)
+	}
+	var cat struct {
+		Servers []ServerConfig `yaml:"servers"`
+	}
+	if len(doc.Content) > 0 {
+		if err := doc.Decode(&cat); err != nil {
+			return nil, fmt.Errorf("parsing catalog: %w", err)
+		}
+	}
+	if _, err := resolveSSHAliases(cat.Servers, d); err != nil {
+		return nil, fmt.Errorf("validating catalog: %w", locate(err, &doc))
+	}
+	for _, w := range unknownKeys(&doc, cat) {
+		logger.Log("catalog", "%s", w)
+	}
+	for i := range cat.Servers {
+		applyServerDefaults(&cat.Servers[i], d)
+		cat.Servers[i].FromCatalog = true
+	}
+	if err := validateServers(cat.Servers); err != nil {
+		return nil, fmt.Errorf("validating catalog: %w", locate(err, &doc))
+	}
+	return cat.Servers, nil
+}
+
+// MergeCatalog replaces the catalog servers in cfg with the given ones,
+// after the local servers. A local server wins over a catalog server with
+// the same name; the names of shadowed catalog entries are returned.
+func (cfg *Config) MergeCatalog(servers []ServerConfig) (shadowed []string, err error) {
+	merged := make([]ServerConfig, 0, len(cfg.Servers)+len(servers))
+	local := make(map[string]bool)
+	for _, s := range cfg.Servers {
+		if !s.FromCatalog {
+			merged = append(merged, s)
+			local[s.Name] = true
+		}
+	}
+	for _, s := range servers {
+		if local[s.Name] {
+			shadowed = append(shadowed, s.Name)
+			continue
+		}
+		merged = append(merged, s)
+	}
+
+	next := *cfg
+	next.Servers = merged
+	if next.sshConfig == nil {
+		for _, s := range servers {
+			if s.SSHConfigHost != "" {
+				// Already parsed successfully by ParseCatalog.
+				next.sshConfig, _ = loadSSHConfig(cfg.Defaults.SSHConfig)
+				break
+			}
+		}
+	}
+	if err := resolveJumpHosts(&next); err != nil {
+		return nil, fmt.Errorf("validating catalog: %w", err)
+	}
+	cfg.Servers = next.Servers
+	cfg.sshConfig = next.sshConfig
+	return shadowed, nil
+}
+
+// DiffServers describes how a server list changed, one line per server:
+// "+ name" for added, "- name" for removed and "~ name" for modified.
+func DiffServers(before, after []ServerConfig) []string {
+	old := make(map[string]ServerConfig, len(before))
+	for _, s := range before {
+		old[s.Name] = s
+	}
+	var diff []string
+	seen := make(map[string]bool, len(after))
+	for _, s := range after {
+		seen[s.Name] = true
+		prev, ok := old[s.Name]
+		switch {
+		case !ok:
+			diff = append(diff, fmt.Sprintf("+ %s (%s@%s)", s.Name, s.User, s.Host))
+		case !reflect.DeepEqual(prev, s):
+			diff = append(diff, fmt.Sprintf("~ %s (%s@%s)", s.Name, s.User, s.Host))
+		}
+	}
+	var removed []string
+	for name := range old {
+		if !seen[name] {
+			removed = append(removed, "- "+name)
+		}
+	}
+	sort.Strings(removed)
+	return append(diff, removed...)
+}
@@ -1,17 +1,38 @@
 package config
 
 import (
+	"cmp"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"log-monitor/internal/logger"
+
+	"github.com/kevinburke/ssh_config"
+	"golang.org/x/text/encoding/htmlindex"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
+	Version  int            `yaml:"version"`
 	Defaults Defaults       `yaml:"defaults"`
+	Catalog  CatalogConfig  `yaml:"catalog"`
+	Events   EventsConfig   `yaml:"events"`
+	Metrics  []MetricRule   `yaml:"metrics"`
+	Plugins  []PluginConfig `yaml:"plugins"`
 	Servers  []ServerConfig `yaml:"servers"`
+
+	Warnings []string `yaml:"-"` // unknown keys found while loading, with line numbers
+	Path     string   `yaml:"-"` // file it was loaded from; empty without one
+
+	sshConfig *ssh_config.Config // parsed ~/.ssh/config, if any server uses it
 }
 
 type Defaults struct {
@@ -19,25 +40,211 @@ type Defaults struct {
 	SSHPort     int    `yaml:"ssh_port"`
 	TailLines   int    `yaml:"tail_lines"`
 	DownloadDir string `yaml:"download_dir"`
+	GeoIPDB     string `yaml:"geoip_db"` // MaxMind .mmdb file used when looking up IP addresses
+	Keychain    bool   `yaml:"keychain"`
+	KnownHosts  string `yaml:"known_hosts"`
+	StateFile   string `yaml:"state_file"`
+	SSHConfig   string `yaml:"ssh_config"`
+
+	// ControlSocket enables connection sharing between instances, e.g.
+	// "~/.cache/log-monitor/control.sock". Empty disables it.
+	ControlSocket string `yaml:"control_socket"`
+
+	// Viewer display of tabs and other control characters
+	TabWidth     int    `yaml:"tab_width"`     // columns per tab stop
+	ControlChars string `yaml:"control_chars"` // "strip", "symbols" (␛, ␍) or "caret" (^[, ^M)
+
+	// DimAfter dims viewer lines logged longer ago than this, going by
+	// their timestamps, e.g. "30m". 0 leaves them all bright.
+	DimAfter time.Duration `yaml:"dim_after"`
+
+	// SpillHistory keeps the viewer lines beyond its 10000 in a temporary
+	// file instead of dropping them; scrolling above the top pages them
+	// back in.
+	SpillHistory bool `yaml:"spill_history"`
+
+	// Proxy for the SSH TCP connection: socks5://, socks5h:// or http://
+	// URL, with optional user:password, or a ws:// or wss:// tunnel
+	// endpoint, which may take the target as %host% and %port%. Empty
+	// dials directly.
+	Proxy string `yaml:"proxy"`
+
+	ConnectTimeout    time.Duration `yaml:"connect_timeout"`    // SSH dial + handshake, e.g. "15s"
+	CommandTimeout    time.Duration `yaml:"command_timeout"`    // a single remote command (listing, reading)
+	KeepaliveInterval time.Duration `yaml:"keepalive_interval"` // background keepalive for open connections; negative disables
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`       // close connections unused for this long; 0 keeps them open
+	DialRetries       int           `yaml:"dial_retries"`       // extra attempts after a transient dial failure; negative disables
+
+	// EagerConnect dials every server in the background at startup, so the
+	// first selection doesn't wait for the handshake. Servers can opt in or
+	// out with their own eager_connect.
+	EagerConnect bool `yaml:"eager_connect"`
+
+	// KeepaliveCountMax is how many background keepalives in a row may go
+	// unanswered before a connection is given up as dead, like OpenSSH's
+	// ServerAliveCountMax.
+	KeepaliveCountMax int `yaml:"keepalive_count_max"`
+
+	// OpenSingleMatch opens a file as soon as the file filter leaves only
+	// it, without waiting for Enter.
+	OpenSingleMatch bool `yaml:"open_single_match"`
+
+	// DownloadConfirmSize asks before downloading files larger than this,
+	// e.g. "500MB"; negative never asks.
+	DownloadConfirmSize ByteSize `yaml:"download_confirm_size"`
+
+	// ReopenLastFile opens the file last opened in a folder on returning to
+	// it, instead of only putting the cursor on it.
+	ReopenLastFile bool `yaml:"reopen_last_file"`
+
+	// StatusTemplate lays out the left of the status bar, e.g.
+	// "%state% %server% | %file% | %rate% | %context%"; see StatusFields.
+	// Empty keeps the built-in layout.
+	StatusTemplate string `yaml:"status_template"`
+
+	// ShowBanner pops up the SSH banner and message of the day of each
+	// server the first time it is connected to, to be acknowledged.
+	ShowBanner bool `yaml:"show_banner"`
+
+	// ReadOnly refuses to truncate or delete files on the servers. Servers
+	// can opt in or out with their own read_only.
+	ReadOnly bool `yaml:"read_only"`
+
+	// ExitSummary prints what the session did to stdout on quitting:
+	// servers, files tailed, data received, tail losses and downloads.
+	ExitSummary bool `yaml:"exit_summary"`
+
+	// Locale is the language of the UI, e.g. "de"; empty is English.
+	// LocaleFile is a YAML catalog mapping the English messages to
+	// translations, used on top of the built-in ones.
+	Locale     string `yaml:"locale"`
+	LocaleFile string `yaml:"locale_file"`
+}
+
+// StatusFields are the placeholders a status_template can use, each
+// written as %name%.
+var StatusFields = []string{
+	"server", "folder", "file", "filter", "matches", "lines", "rate",
+	"state", "latency", "conns", "note", "context",
 }
 
 type LogFolder struct {
 	Path         string   `yaml:"path"`
 	FilePatterns []string `yaml:"file_patterns"`
+	Encoding     string   `yaml:"encoding"`  // e.g. "latin1", "shift_jis"; empty or "auto" detects non-UTF-8 lines
+	AutoOpen     string   `yaml:"auto_open"` // "newest" opens the most recently modified file once listed
+	Unit         string   `yaml:"unit"`      // systemd unit writing these logs, for Ctrl-U
+
+	// Type is "" for a directory of log files, or FolderKubectl for the
+	// pods of a Kubernetes namespace, listed and followed with kubectl.
+	Type       string `yaml:"type"`
+	Namespace  string `yaml:"namespace"`  // kubectl: namespace of the pods; empty for kubectl's current one
+	Selector   string `yaml:"selector"`   // kubectl: label selector, e.g. "app=api"
+	Kubeconfig string `yaml:"kubeconfig"` // kubectl: run kubectl locally with this kubeconfig instead of on the server
+
+	// Root is the configured folder's path while one of its subdirectories
+	// (Path) is browsed, otherwise "".
+	Root string `yaml:"-"`
+}
+
+// FolderKubectl is the type of a log folder showing Kubernetes pods.
+const FolderKubectl = "kubectl"
+
+// IsKubectl reports whether the folder shows pods rather than files.
+func (f LogFolder) IsKubectl() bool {
+	return f.Type == FolderKubectl
 }
 
 type ServerConfig struct {
-	Name         string      `yaml:"name"`
-	Host         string      `yaml:"host"`
-	Port         int         `yaml:"port"`
-	User         string      `yaml:"user"`
-	Auth       AuthConfig  `yaml:"auth"`
-	LogFolders []LogFolder `yaml:"log_folders"`
-	Sudo         bool        `yaml:"sudo"`
+	Name          string      `yaml:"name"`
+	Group         string      `yaml:"group"`           // servers of a group can be tailed together (Ctrl-G)
+	SSHConfigHost string      `yaml:"ssh_config_host"` // Host alias in ~/.ssh/config to take connection settings from
+	Local         bool        `yaml:"local"`           // files on this machine, read without SSH
+	Host          string      `yaml:"host"`
+	Port          int         `yaml:"port"`
+	User          string      `yaml:"user"`
+	Auth          AuthConfig  `yaml:"auth"`
+	LogFolders    []LogFolder `yaml:"log_folders"`
+	Sudo          bool        `yaml:"sudo"`
+	SudoUser      string      `yaml:"sudo_user"`       // sudo to this user instead of root; implies sudo
+	Escalation    string      `yaml:"escalation"`      // privilege command template instead of sudo, e.g. "pbrun sh -c %cmd%", or a preset name; implies sudo
+	SetupCommand  string      `yaml:"setup_command"`   // wraps every remote command, e.g. "sudo su - appuser"
+	Keychain      *bool       `yaml:"keychain"`        // store sudo password in the OS keychain (defaults.keychain if unset)
+	ProxyJump     string      `yaml:"proxy_jump"`      // comma-separated jump hosts, like ssh -J
+	ProxyChain    []string    `yaml:"proxy_chain"`     // hops reached one through another, each a pooled connection
+	StrictHost    bool        `yaml:"strict_host_key"` // refuse changed host keys instead of asking
+	Proxy         string      `yaml:"proxy"`           // defaults.proxy if unset, "none" for a direct dial
+	FileBackend   string      `yaml:"file_backend"`    // "shell" (ls/stat/cat, default) or "sftp" for listing and downloads; "local" for local servers
+	Compression   bool        `yaml:"compression"`     // gzip command output and downloads on the server
+	Shell         string      `yaml:"shell"`           // login shell on the server: "sh" (default), "bash", "csh" or "fish"
+
+	ConnectTimeout time.Duration `yaml:"connect_timeout"` // defaults.connect_timeout if unset
+	CommandTimeout time.Duration `yaml:"command_timeout"` // defaults.command_timeout if unset
+	DialRetries    int           `yaml:"dial_retries"`    // defaults.dial_retries if unset, negative for none
+
+	// AgentForwarding makes the local SSH agent available to commands run
+	// on the server, so they can reach further hosts with your keys.
+	AgentForwarding bool `yaml:"agent_forwarding"`
+
+	EagerConnect *bool `yaml:"eager_connect"` // dial at startup (defaults.eager_connect if unset)
+	ReadOnly     *bool `yaml:"read_only"`     // refuse to truncate or delete files (defaults.read_only if unset)
+
+	// Keepalives for this server's connection, for sshds or firewalls with
+	// shorter idle timeouts than the rest: defaults.keepalive_interval and
+	// defaults.keepalive_count_max if unset.
+	KeepaliveInterval time.Duration `yaml:"keepalive_interval"`
+	KeepaliveCountMax int           `yaml:"keepalive_count_max"`
+
+	// MaxSessions queues sessions beyond this many at once, for servers
+	// whose sshd MaxSessions is low. 0 learns the limit from the server.
+	MaxSessions int `yaml:"max_sessions"`
+
+	// DefaultFolder is the path of the log folder opened on selecting a
+	// server with several, instead of listing them.
+	DefaultFolder string `yaml:"default_folder"`
+
+	// PasswordCommand prints the password used for auth.method password
+	// and for sudo, e.g. "pass show servers/web1", so neither is prompted
+	// for.
+	PasswordCommand string `yaml:"password_command"`
+
+	Hosts       []string       `yaml:"-"` // every address when host is a list, Host being the first
+	JumpHosts   []ServerConfig `yaml:"-"` // resolved from ProxyJump or ProxyChain, first hop first
+	PooledHops  bool           `yaml:"-"` // JumpHosts come from ProxyChain: each hop is a pooled connection
+	FromCatalog bool           `yaml:"-"` // defined by the shared catalog, not the local config
+}
+
+// ConnectEagerly reports whether the server is dialed at startup. Servers
+// that ask for one-time codes are left until they are selected.
+func (s ServerConfig) ConnectEagerly() bool {
+	return s.EagerConnect != nil && *s.EagerConnect && s.Auth.Method != "keyboard-interactive" && !s.Local
+}
+
+// FolderIndex returns the index of the log folder with the given path, or
+// -1. Trailing slashes are ignored.
+func (s ServerConfig) FolderIndex(path string) int {
+	path = strings.TrimRight(path, "/")
+	for i, f := range s.LogFolders {
+		if strings.TrimRight(f.Path, "/") == path {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsReadOnly reports whether files on this server may not be truncated or
+// deleted.
+func (s ServerConfig) IsReadOnly() bool {
+	return s.ReadOnly != nil && *s.ReadOnly
+}
+
+// UseKeychain reports whether sudo passwords for this server are kept in the OS keychain.
+func (s ServerConfig) UseKeychain() bool {
+	return s.Keychain != nil && *s.Keychain
 }
 
 type AuthConfig struct {
-	Method  string `yaml:"method"`  // "key", "password", or "agent"
+	Method  string `yaml:"method"` // "key", "password", "agent", "keyboard-interactive" or "gssapi"
 	KeyPath string `yaml:"key_path"`
 }
 
@@ -47,20 +254,58 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("reading config: %w", err)
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+
+	version, err := migrate(&doc)
+	if err != nil {
 		return nil, fmt.Errorf("parsing config: %w", err)
 	}
+	if version < CurrentVersion {
+		logger.Log("config", "migrated %s from version %d to %d; consider updating the file", path, version, CurrentVersion)
+	}
+
+	var cfg Config
+	if len(doc.Content) > 0 {
+		if err := doc.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("parsing config: %w", err)
+		}
+	}
+	cfg.Version = CurrentVersion
+	cfg.Path = path
+	cfg.Warnings = unknownKeys(&doc, Config{})
+	for _, w := range cfg.Warnings {
+		logger.Log("config", "%s: %s", path, w)
+	}
+
+	cfg.sshConfig, err = resolveSSHAliases(cfg.Servers, cfg.Defaults)
+	if err != nil {
+		return nil, fmt.Errorf("validating config: %w", locate(err, &doc))
+	}
 
 	applyDefaults(&cfg)
 
 	if err := validate(&cfg); err != nil {
-		return nil, fmt.Errorf("validating config: %w", err)
+		return nil, fmt.Errorf("validating config: %w", locate(err, &doc))
+	}
+
+	if err := resolveJumpHosts(&cfg); err != nil {
+		return nil, fmt.Errorf("validating config: %w", locate(err, &doc))
 	}
 
 	return &cfg, nil
 }
 
+// Default returns the configuration used without a config file, for
+// -stdin: no servers and every default.
+func Default() *Config {
+	cfg := Config{Version: CurrentVersion}
+	applyDefaults(&cfg)
+	return &cfg
+}
+
 func applyDefaults(cfg *Config) {
 	d := &cfg.Defaults
 	if d.SSHPort == 0 {
@@ -69,59 +314,439 @@ func applyDefaults(cfg *Config) {
 	if d.TailLines == 0 {
 		d.TailLines = 100
 	}
+	if d.TabWidth <= 0 {
+		d.TabWidth = 8
+	}
+	if d.ControlChars == "" {
+		d.ControlChars = "strip"
+	}
+	if d.ConnectTimeout <= 0 {
+		d.ConnectTimeout = 15 * time.Second
+	}
+	if d.CommandTimeout <= 0 {
+		d.CommandTimeout = 30 * time.Second
+	}
+	if d.KeepaliveInterval == 0 {
+		d.KeepaliveInterval = 30 * time.Second
+	}
+	if d.KeepaliveCountMax <= 0 {
+		d.KeepaliveCountMax = 3
+	}
+	if d.DialRetries == 0 {
+		d.DialRetries = 2
+	}
+	if d.DownloadConfirmSize == 0 {
+		d.DownloadConfirmSize = 500 << 20
+	}
+	if d.KnownHosts == "" {
+		d.KnownHosts = "~/.ssh/known_hosts"
+	}
 	d.SSHKey = expandTilde(d.SSHKey)
+	d.KnownHosts = expandTilde(d.KnownHosts)
+	d.StateFile = expandTilde(d.StateFile)
+	d.ControlSocket = expandTilde(d.ControlSocket)
+	d.SSHConfig = sshConfigPath(*d)
 	d.DownloadDir = expandTilde(d.DownloadDir)
+	d.GeoIPDB = expandTilde(d.GeoIPDB)
+	d.LocaleFile = expandTilde(d.LocaleFile)
+	cfg.Catalog.Source = expandTilde(cfg.Catalog.Source)
+	if cfg.Events.Interval <= 0 {
+		cfg.Events.Interval = time.Minute
+	}
 
 	for i := range cfg.Servers {
-		s := &cfg.Servers[i]
-		if s.Port == 0 {
-			s.Port = d.SSHPort
-		}
-		if s.Auth.Method == "" {
-			if d.SSHKey != "" {
-				s.Auth.Method = "key"
-			} else {
-				s.Auth.Method = "agent"
-			}
+		applyServerDefaults(&cfg.Servers[i], *d)
+	}
+}
+
+// interactiveConnectTimeout is the minimum connect timeout for servers that
+// ask the user for codes while logging in.
+const interactiveConnectTimeout = 2 * time.Minute
+
+// escalationPresets are the escalation templates of common sudo
+// replacements, by name. doas can't take a password on stdin, so it runs
+// non-interactively and needs a nopass rule.
+var escalationPresets = map[string]string{
+	"doas":  "doas -n sh -c %cmd%",
+	"pbrun": "pbrun sh -c %cmd%",
+}
+
+// applyServerDefaults fills unset server fields from the defaults section.
+func applyServerDefaults(s *ServerConfig, d Defaults) {
+	if s.Local && s.Host == "" {
+		s.Host = "localhost"
+	}
+	if s.Port == 0 {
+		s.Port = d.SSHPort
+	}
+	if s.Auth.Method == "" {
+		if d.SSHKey != "" {
+			s.Auth.Method = "key"
+		} else {
+			s.Auth.Method = "agent"
 		}
-		if s.Auth.Method == "key" && s.Auth.KeyPath == "" {
-			s.Auth.KeyPath = d.SSHKey
+	}
+	if s.Auth.Method == "key" && s.Auth.KeyPath == "" {
+		s.Auth.KeyPath = d.SSHKey
+	}
+	s.Auth.KeyPath = expandTilde(s.Auth.KeyPath)
+	for i := range s.LogFolders {
+		s.LogFolders[i].Kubeconfig = expandTilde(s.LogFolders[i].Kubeconfig)
+		if s.Local {
+			s.LogFolders[i].Path = expandTilde(s.LogFolders[i].Path)
+		}
+	}
+	if s.Local {
+		s.DefaultFolder = expandTilde(s.DefaultFolder)
+	}
+	if s.Keychain == nil {
+		keychain := d.Keychain
+		s.Keychain = &keychain
+	}
+	if s.EagerConnect == nil {
+		eager := d.EagerConnect
+		s.EagerConnect = &eager
+	}
+	if s.ReadOnly == nil {
+		readOnly := d.ReadOnly
+		s.ReadOnly = &readOnly
+	}
+	if preset, ok := escalationPresets[s.Escalation]; ok {
+		s.Escalation = preset
+	}
+	if s.Escalation != "" || s.SudoUser != "" {
+		s.Sudo = true
+	}
+	if s.FileBackend == "" {
+		s.FileBackend = "shell"
+	}
+	switch s.Shell {
+	case "":
+		s.Shell = "sh"
+	case "tcsh":
+		s.Shell = "csh"
+	}
+	switch s.Proxy {
+	case "":
+		s.Proxy = d.Proxy
+	case "none":
+		s.Proxy = ""
+	}
+	if s.ConnectTimeout <= 0 {
+		s.ConnectTimeout = d.ConnectTimeout
+		if s.Auth.Method == "keyboard-interactive" {
+			// The timeout also covers typing a one-time code.
+			s.ConnectTimeout = max(s.ConnectTimeout, interactiveConnectTimeout)
 		}
-		s.Auth.KeyPath = expandTilde(s.Auth.KeyPath)
+	}
+	if s.CommandTimeout <= 0 {
+		s.CommandTimeout = d.CommandTimeout
+	}
+	if s.DialRetries == 0 {
+		s.DialRetries = d.DialRetries
+	}
+	if s.KeepaliveInterval == 0 {
+		s.KeepaliveInterval = d.KeepaliveInterval
+	}
+	if s.KeepaliveCountMax <= 0 {
+		s.KeepaliveCountMax = d.KeepaliveCountMax
 	}
 }
 
 func validate(cfg *Config) error {
-	if len(cfg.Servers) == 0 {
-		return fmt.Errorf("no servers defined")
+	if len(cfg.Servers) == 0 && cfg.Catalog.Source == "" {
+		return fieldErrorf("servers", "no servers defined")
 	}
-	for i, s := range cfg.Servers {
+	switch cfg.Defaults.ControlChars {
+	case "strip", "symbols", "caret":
+	default:
+		return fieldErrorf("defaults.control_chars", "unknown value %q (use strip, symbols or caret)", cfg.Defaults.ControlChars)
+	}
+	if err := validateStatusTemplate(cfg.Defaults.StatusTemplate); err != nil {
+		return fieldErrorf("defaults.status_template", "%v", err)
+	}
+	if cfg.Events.URL != "" && cfg.Events.Command != "" {
+		return fieldErrorf("events.command", "set either events.url or events.command, not both")
+	}
+	if err := validateMetrics(cfg.Metrics); err != nil {
+		return err
+	}
+	if err := validatePlugins(cfg.Plugins); err != nil {
+		return err
+	}
+	return validateServers(cfg.Servers)
+}
+
+// validateServers checks required server fields and fills in default names.
+func validateServers(servers []ServerConfig) error {
+	for i, s := range servers {
+		field := fmt.Sprintf("servers[%d]", i)
 		if s.Host == "" {
-			return fmt.Errorf("server %d: host is required", i)
+			return fieldErrorf(field+".host", "host is required")
 		}
-		if s.User == "" {
-			return fmt.Errorf("server %d (%s): user is required", i, s.Host)
+		for j, h := range s.Hosts {
+			if h == "" {
+				return fieldErrorf(fmt.Sprintf("%s.host[%d]", field, j), "empty address (server %s)", s.Host)
+			}
+		}
+		if s.User == "" && !s.Local {
+			return fieldErrorf(field+".user", "user is required (server %s)", s.Host)
 		}
 		if len(s.LogFolders) == 0 {
-			return fmt.Errorf("server %d (%s): log_folders is required", i, s.Host)
+			return fieldErrorf(field+".log_folders", "log_folders is required (server %s)", s.Host)
 		}
 		for j, f := range s.LogFolders {
+			switch f.Type {
+			case "":
+			case FolderKubectl:
+				if f.Path == "" {
+					// The path only names the folder in the UI
+					f.Path = "kubectl:" + cmp.Or(f.Namespace, "default")
+					servers[i].LogFolders[j].Path = f.Path
+				}
+				if s.Local && f.Kubeconfig == "" {
+					return fieldErrorf(fmt.Sprintf("%s.log_folders[%d].kubeconfig", field, j), "kubeconfig is required for kubectl on a local server (server %s)", s.Host)
+				}
+			default:
+				return fieldErrorf(fmt.Sprintf("%s.log_folders[%d].type", field, j), "unknown type %q (use kubectl) (server %s)", f.Type, s.Host)
+			}
 			if f.Path == "" {
-				return fmt.Errorf("server %d (%s): log_folders[%d]: path is required", i, s.Host, j)
+				return fieldErrorf(fmt.Sprintf("%s.log_folders[%d].path", field, j), "path is required (server %s)", s.Host)
 			}
+			if !validEncoding(f.Encoding) {
+				return fieldErrorf(fmt.Sprintf("%s.log_folders[%d].encoding", field, j), "unknown encoding %q (server %s)", f.Encoding, s.Host)
+			}
+			if f.AutoOpen != "" && f.AutoOpen != "newest" {
+				return fieldErrorf(fmt.Sprintf("%s.log_folders[%d].auto_open", field, j), "unknown auto_open %q (use newest) (server %s)", f.AutoOpen, s.Host)
+			}
+		}
+		if s.DefaultFolder != "" && s.FolderIndex(s.DefaultFolder) < 0 {
+			return fieldErrorf(field+".default_folder", "%q is not one of the log_folders paths (server %s)", s.DefaultFolder, s.Host)
 		}
 		if s.Name == "" {
-			cfg.Servers[i].Name = fmt.Sprintf("%s@%s", s.User, s.Host)
+			servers[i].Name = fmt.Sprintf("%s@%s", s.User, s.Host)
+			if s.Local {
+				servers[i].Name = s.Host
+			}
+		}
+		if s.Local {
+			// Nothing of the SSH settings below applies
+			if s.Sudo {
+				return fieldErrorf(field+".sudo", "sudo isn't available for a local server; run log-monitor as a user who can read the files (server %s)", s.Host)
+			}
+			servers[i].FileBackend = "local"
+			continue
 		}
 		switch s.Auth.Method {
-		case "key", "password", "agent":
+		case "key", "password", "agent", "keyboard-interactive", "gssapi":
+		default:
+			return fieldErrorf(field+".auth.method", "unknown auth method %q (server %s)", s.Auth.Method, s.Host)
+		}
+		if err := validateProxy(s.Proxy); err != nil {
+			return fieldErrorf(field+".proxy", "%v (server %s)", err, s.Host)
+		}
+		if s.Escalation != "" && s.SudoUser != "" {
+			return fieldErrorf(field+".sudo_user", "sudo_user doesn't apply to an escalation template; put the user in the template (server %s)", s.Host)
+		}
+		if s.Escalation != "" && !strings.Contains(s.Escalation, "%cmd%") {
+			return fieldErrorf(field+".escalation", "escalation must contain %%cmd%% or be doas or pbrun (server %s)", s.Host)
+		}
+		switch s.FileBackend {
+		case "shell":
+		case "sftp":
+			if s.Sudo {
+				return fieldErrorf(field+".file_backend", "sftp can't read files as another user; use the shell backend with sudo (server %s)", s.Host)
+			}
+			if s.SetupCommand != "" {
+				return fieldErrorf(field+".file_backend", "sftp can't run through setup_command; use the shell backend (server %s)", s.Host)
+			}
+		default:
+			return fieldErrorf(field+".file_backend", "unknown file backend %q (use shell or sftp) (server %s)", s.FileBackend, s.Host)
+		}
+		switch s.Shell {
+		case "sh", "bash", "csh", "fish":
 		default:
-			return fmt.Errorf("server %d (%s): unknown auth method %q", i, s.Host, s.Auth.Method)
+			return fieldErrorf(field+".shell", "unknown shell %q (use sh, bash, csh or fish) (server %s)", s.Shell, s.Host)
+		}
+		if s.MaxSessions < 0 {
+			return fieldErrorf(field+".max_sessions", "max_sessions can't be negative (server %s)", s.Host)
+		}
+	}
+	return nil
+}
+
+// validateProxy checks a proxy URL; empty means no proxy.
+func validateProxy(proxy string) error {
+	if proxy == "" {
+		return nil
+	}
+	// Tunnel URLs may hold the target's placeholders, which aren't valid
+	// URL escapes
+	u, err := url.Parse(strings.NewReplacer("%host%", "host", "%port%", "22").Replace(proxy))
+	if err != nil {
+		return fmt.Errorf("invalid proxy %q", proxy)
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h", "http":
+	case "ws", "wss":
+		if u.Host == "" {
+			return fmt.Errorf("tunnel %q needs a host", u.Redacted())
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q (use socks5, socks5h, http, ws or wss)", u.Scheme)
+	}
+	if _, port, err := net.SplitHostPort(u.Host); err != nil || port == "" {
+		return fmt.Errorf("proxy %q needs a host:port", u.Redacted())
+	}
+	return nil
+}
+
+// statusFieldRe matches a placeholder of a status template.
+var statusFieldRe = regexp.MustCompile(`%([a-z]+)%`)
+
+// validateStatusTemplate checks that a status template only uses known
+// placeholders.
+func validateStatusTemplate(tmpl string) error {
+	for _, m := range statusFieldRe.FindAllStringSubmatch(tmpl, -1) {
+		if !slices.Contains(StatusFields, m[1]) {
+			return fmt.Errorf("unknown placeholder %%%s%% (use %s)", m[1], "%"+strings.Join(StatusFields, "%, %")+"%")
+		}
+	}
+	return nil
+}
+
+// validEncoding reports whether name is a text encoding log folders can use.
+func validEncoding(name string) bool {
+	switch strings.ToLower(name) {
+	case "", "auto", "utf-8", "utf8":
+		return true
+	}
+	_, err := htmlindex.Get(name)
+	return err == nil
+}
+
+// resolveJumpHosts fills in JumpHosts for every server with a proxy_jump
+// or proxy_chain. Each entry is either the name of another configured
+// server, whose connection settings are reused, or [user@]host[:port], which
+// inherits the user and auth of the server being reached. A named server
+// that is itself reached through jump hosts brings them along, before it.
+func resolveJumpHosts(cfg *Config) error {
+	index := make(map[string]int, len(cfg.Servers))
+	for i, s := range cfg.Servers {
+		index[s.Name] = i
+	}
+	var hops func(i int, via []int) ([]ServerConfig, bool, error)
+	hops = func(i int, via []int) ([]ServerConfig, bool, error) {
+		s := cfg.Servers[i]
+		field := fmt.Sprintf("servers[%d].proxy_jump", i)
+		entries := strings.Split(s.ProxyJump, ",")
+		pooled := false
+		if len(s.ProxyChain) > 0 {
+			if strings.TrimSpace(s.ProxyJump) != "" {
+				return nil, false, fieldErrorf(field, "can't be combined with proxy_chain (server %s)", s.Host)
+			}
+			field = fmt.Sprintf("servers[%d].proxy_chain", i)
+			entries = s.ProxyChain
+			pooled = true
+		}
+		var out []ServerConfig
+		for _, entry := range entries {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			j, ok := index[entry]
+			if !ok {
+				hop, err := parseJumpSpec(entry, s, cfg.Defaults.SSHPort, cfg.sshConfig)
+				if err != nil {
+					return nil, false, fieldErrorf(field, "%v (server %s)", err, s.Host)
+				}
+				out = append(out, hop)
+				continue
+			}
+			if j == i {
+				return nil, false, fieldErrorf(field, "refers to the server itself (server %s)", s.Host)
+			}
+			if slices.Contains(via, j) {
+				return nil, false, fieldErrorf(field, "goes through %s, which is reached through this server: a loop (server %s)", entry, s.Host)
+			}
+			before, _, err := hops(j, append(via, i))
+			if err != nil {
+				return nil, false, err
+			}
+			named := cfg.Servers[j]
+			named.ProxyJump = ""
+			named.ProxyChain = nil
+			named.JumpHosts = nil
+			named.PooledHops = false
+			out = append(append(out, before...), named)
 		}
+		return out, pooled && len(out) > 0, nil
+	}
+
+	resolved := make([][]ServerConfig, len(cfg.Servers))
+	pooled := make([]bool, len(cfg.Servers))
+	for i := range cfg.Servers {
+		var err error
+		if resolved[i], pooled[i], err = hops(i, nil); err != nil {
+			return err
+		}
+	}
+	for i := range cfg.Servers {
+		cfg.Servers[i].JumpHosts = resolved[i]
+		cfg.Servers[i].PooledHops = pooled[i]
 	}
 	return nil
 }
 
+// parseJumpSpec parses a [user@]host[:port] jump host. When an ssh config is
+// loaded, host may also be a Host alias from it. Anything left unset is
+// taken from the target server.
+func parseJumpSpec(spec string, target ServerConfig, defaultPort int, sshCfg *ssh_config.Config) (ServerConfig, error) {
+	var hop ServerConfig
+	hostPort := spec
+	if at := strings.LastIndex(spec, "@"); at >= 0 {
+		hop.User = spec[:at]
+		hostPort = spec[at+1:]
+	}
+	hop.Host = hostPort
+	if host, port, err := net.SplitHostPort(hostPort); err == nil {
+		n, err := strconv.Atoi(port)
+		if err != nil || n <= 0 || n > 65535 {
+			return ServerConfig{}, fmt.Errorf("invalid port in %q", spec)
+		}
+		hop.Host = host
+		hop.Port = n
+	}
+	if hop.Host == "" {
+		return ServerConfig{}, fmt.Errorf("invalid jump host %q", spec)
+	}
+	if sshCfg != nil {
+		hop.SSHConfigHost, hop.Host = hop.Host, ""
+		if err := applySSHAlias(&hop, sshCfg); err != nil {
+			return ServerConfig{}, err
+		}
+	}
+	if hop.User == "" {
+		hop.User = target.User
+	}
+	if hop.Port == 0 {
+		hop.Port = defaultPort
+	}
+	if hop.Auth.KeyPath == "" {
+		hop.Auth = target.Auth
+	}
+	// The first hop is dialed from this machine, like the target would be
+	hop.Proxy = target.Proxy
+	hop.Auth.KeyPath = expandTilde(hop.Auth.KeyPath)
+	if hop.User == "" {
+		return ServerConfig{}, fmt.Errorf("invalid jump host %q", spec)
+	}
+	hop.Name = fmt.Sprintf("%s@%s", hop.User, hop.Host)
+	return hop, nil
+}
+
 func expandTilde(path string) string {
 	if strings.HasPrefix(path, "~/") {
 		home, err := os.UserHomeDir()
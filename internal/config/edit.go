@@ -0,0 +1,146 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SaveFilePatterns writes the file_patterns of one log folder of a server
+// back to the config file it was loaded from, leaving the rest of the file
+// and its comments as they are. No patterns removes the key. Catalog
+// servers are not in the file and can't be saved.
+func (cfg *Config) SaveFilePatterns(server, folder string, patterns []string) error {
+	if cfg.Path == "" {
+		return fmt.Errorf("saving file patterns: no config file")
+	}
+	// Servers from the file come first, in its order
+	index := -1
+	for i, s := range cfg.Servers {
+		if s.FromCatalog {
+			break
+		}
+		if s.Name == server {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("saving file patterns: %s is not defined in %s", server, cfg.Path)
+	}
+
+	// A config file that is a symlink, e.g. into a dotfiles repository, is
+	// written where it points rather than replaced by a plain file
+	path, err := filepath.EvalSymlinks(cfg.Path)
+	if err != nil {
+		return fmt.Errorf("saving file patterns: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("saving file patterns: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("saving file patterns: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("saving file patterns: parsing %s: %w", cfg.Path, err)
+	}
+	version, err := migrate(&doc)
+	if err != nil {
+		return fmt.Errorf("saving file patterns: parsing %s: %w", cfg.Path, err)
+	}
+
+	entry, err := folderEntry(&doc, index, server, folder)
+	if err != nil {
+		return fmt.Errorf("saving file patterns: %s changed since it was loaded: %w", cfg.Path, err)
+	}
+	if len(patterns) == 0 {
+		removeEntry(entry, "file_patterns")
+	} else {
+		seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, p := range patterns {
+			seq.Content = append(seq.Content, scalar(p, 0))
+		}
+		// A shared node, such as one a migration copied to several
+		// folders, is replaced rather than changed
+		if k, v := mappingEntry(entry, "file_patterns"); v != nil {
+			seq.Style = v.Style & yaml.FlowStyle
+			seq.HeadComment, seq.LineComment = v.HeadComment, v.LineComment
+			entry.Content[slices.Index(entry.Content, k)+1] = seq
+		} else {
+			entry.Content = append(entry.Content, scalar("file_patterns", 0), seq)
+		}
+	}
+	if version < CurrentVersion {
+		// The migrated layout is what gets written
+		setVersion(&doc)
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("saving file patterns: encoding: %w", err)
+	}
+	enc.Close()
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out.Bytes(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("saving file patterns: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("saving file patterns: %w", err)
+	}
+	return nil
+}
+
+// folderEntry returns the mapping of the log folder at path of the server
+// at index, checking that it is still the server named name.
+func folderEntry(doc *yaml.Node, index int, name, path string) (*yaml.Node, error) {
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("it is empty")
+	}
+	srv := childNode(doc.Content[0], "servers")
+	if srv != nil {
+		srv = childNode(srv, strconv.Itoa(index))
+	}
+	if srv == nil || srv.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("server %s not found", name)
+	}
+	if _, v := mappingEntry(srv, "name"); v != nil && v.Value != name {
+		return nil, fmt.Errorf("server %s not found", name)
+	}
+	path = strings.TrimRight(path, "/")
+	if folders := childNode(srv, "log_folders"); folders != nil && folders.Kind == yaml.SequenceNode {
+		for _, f := range folders.Content {
+			if f.Kind != yaml.MappingNode {
+				continue
+			}
+			if _, v := mappingEntry(f, "path"); v != nil && strings.TrimRight(expandTilde(v.Value), "/") == path {
+				return f, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("folder %s of %s not found", path, name)
+}
+
+// setVersion sets the version of a document to CurrentVersion.
+func setVersion(doc *yaml.Node) {
+	root := doc.Content[0]
+	v := scalar(strconv.Itoa(CurrentVersion), 0)
+	v.Tag = "!!int"
+	if k, _ := mappingEntry(root, "version"); k != nil {
+		root.Content[slices.Index(root.Content, k)+1] = v
+		return
+	}
+	root.Content = append([]*yaml.Node{scalar("version", 0), v}, root.Content...)
+}
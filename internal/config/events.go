@@ -0,0 +1,17 @@
+package config
+
+import "time"
+
+// EventsConfig points at a feed of deployment (or other) events that are
+// shown as markers in the viewer, so errors can be lined up with releases.
+// Either URL or Command is set.
+type EventsConfig struct {
+	URL      string        `yaml:"url"`      // http(s) URL returning the events, polled with GET
+	Command  string        `yaml:"command"`  // local command printing the events, run with sh -c
+	Interval time.Duration `yaml:"interval"` // how often to poll, e.g. "1m"
+}
+
+// Enabled reports whether an event feed is configured.
+func (e EventsConfig) Enabled() bool {
+	return e.URL != "" || e.Command != ""
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnmarshalYAML also accepts host: as a list of addresses, for HA pairs
+// that share a filesystem. The first address becomes Host, which names the
+// server in the pool, and all of them Hosts.
+func (s *ServerConfig) UnmarshalYAML(node *yaml.Node) error {
+	type plain ServerConfig
+	var hosts []string
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value != "host" || value.Kind != yaml.SequenceNode {
+				continue
+			}
+			if err := value.Decode(&hosts); err != nil {
+				return err
+			}
+			// Decode the rest as usual, with the first address as host
+			first := ""
+			if len(hosts) > 0 {
+				first = hosts[0]
+			}
+			single := *node
+			single.Content = slices.Clone(node.Content)
+			single.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: first, Line: value.Line, Column: value.Column}
+			node = &single
+			break
+		}
+	}
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	s.Hosts = hosts
+	return nil
+}
+
+// Addresses returns the hosts to try connecting to, in order.
+func (s ServerConfig) Addresses() []string {
+	if len(s.Hosts) > 0 {
+		return s.Hosts
+	}
+	return []string{s.Host}
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// MetricRule pulls numeric values out of matching log lines. Every named
+// group in Pattern becomes a metric of that name, e.g.
+// `took (?P<response_time>[\d.]+)ms` yields response_time.
+type MetricRule struct {
+	Pattern string `yaml:"pattern"` // regular expression with at least one named group
+}
+
+func validateMetrics(rules []MetricRule) error {
+	for i, r := range rules {
+		field := fmt.Sprintf("metrics[%d].pattern", i)
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fieldErrorf(field, "invalid regular expression: %v", err)
+		}
+		named := false
+		for _, name := range re.SubexpNames() {
+			if name != "" {
+				named = true
+			}
+		}
+		if !named {
+			return fieldErrorf(field, "pattern needs a named group such as (?P<response_time>[0-9.]+)")
+		}
+	}
+	return nil
+}
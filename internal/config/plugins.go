@@ -0,0 +1,27 @@
+package config
+
+import "fmt"
+
+// PluginConfig turns on a compiled-in plugin pane (see package plugin).
+type PluginConfig struct {
+	Name    string `yaml:"name"`    // name the plugin registered, e.g. "levels"
+	Overlay bool   `yaml:"overlay"` // draw over the viewer while focused instead of below it
+	Height  int    `yaml:"height"`  // rows below the viewer, borders included; 0 means 10
+}
+
+func validatePlugins(plugins []PluginConfig) error {
+	seen := make(map[string]bool)
+	for i, p := range plugins {
+		field := fmt.Sprintf("plugins[%d]", i)
+		switch {
+		case p.Name == "":
+			return fieldErrorf(field+".name", "name is required")
+		case seen[p.Name]:
+			return fieldErrorf(field+".name", "plugin %s is listed twice", p.Name)
+		case p.Height < 0 || p.Height > 0 && p.Height < 3:
+			return fieldErrorf(field+".height", "height must be at least 3")
+		}
+		seen[p.Name] = true
+	}
+	return nil
+}
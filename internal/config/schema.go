@@ -0,0 +1,189 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the config layout this build understands. Files without
+// a version: field are treated as version 1.
+//
+//	1: servers have a single log_path (or log_paths) and server-level
+//	   file_patterns / key_path
+//	2: servers have log_folders, each with its own file_patterns, and the
+//	   key path lives under auth
+const CurrentVersion = 2
+
+// FieldError is a validation error tied to a field of the config file.
+type FieldError struct {
+	Field string // dotted path, e.g. servers[2].log_folders[0].path
+	Line  int    // line in the YAML file, 0 if unknown
+	Msg   string
+}
+
+func (e *FieldError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s: %s", e.Line, e.Field, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Msg)
+}
+
+// fieldErrorf builds a FieldError; the line is filled in by locate.
+func fieldErrorf(field, format string, args ...any) *FieldError {
+	return &FieldError{Field: field, Msg: fmt.Sprintf(format, args...)}
+}
+
+// locate sets the line of a FieldError from the parsed document. Missing
+// fields point at their closest existing parent.
+func locate(err error, doc *yaml.Node) error {
+	fe, ok := err.(*FieldError)
+	if !ok || doc == nil || len(doc.Content) == 0 {
+		return err
+	}
+	node := doc.Content[0]
+	line := node.Line
+	for _, seg := range splitFieldPath(fe.Field) {
+		next := childNode(node, seg)
+		if next == nil {
+			break
+		}
+		node = next
+		line = node.Line
+	}
+	fe.Line = line
+	return fe
+}
+
+// splitFieldPath turns "servers[2].auth.method" into
+// ["servers", "2", "auth", "method"].
+func splitFieldPath(path string) []string {
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+	return strings.Split(path, ".")
+}
+
+// childNode returns the value for a mapping key or sequence index, or nil.
+func childNode(node *yaml.Node, seg string) *yaml.Node {
+	switch node.Kind {
+	case yaml.MappingNode:
+		if _, v := mappingEntry(node, seg); v != nil {
+			return v
+		}
+	case yaml.SequenceNode:
+		if i, err := strconv.Atoi(seg); err == nil && i >= 0 && i < len(node.Content) {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+// mappingEntry returns the key and value nodes for key, or nils.
+func mappingEntry(m *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i], m.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// removeEntry deletes key from a mapping node, returning its value.
+func removeEntry(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			v := m.Content[i+1]
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return v
+		}
+	}
+	return nil
+}
+
+// migrate upgrades an older config layout in place to CurrentVersion.
+// It returns the version the document was written for.
+func migrate(doc *yaml.Node) (int, error) {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return CurrentVersion, nil
+	}
+	root := doc.Content[0]
+
+	version := 1
+	if k, v := mappingEntry(root, "version"); v != nil {
+		n, err := strconv.Atoi(v.Value)
+		if err != nil || n < 1 {
+			return 0, &FieldError{Field: "version", Line: k.Line, Msg: fmt.Sprintf("invalid version %q", v.Value)}
+		}
+		if n > CurrentVersion {
+			return 0, &FieldError{Field: "version", Line: k.Line,
+				Msg: fmt.Sprintf("config version %d is newer than this build supports (%d); please upgrade log-monitor", n, CurrentVersion)}
+		}
+		version = n
+	}
+
+	if version < 2 {
+		if _, servers := mappingEntry(root, "servers"); servers != nil && servers.Kind == yaml.SequenceNode {
+			for _, srv := range servers.Content {
+				if srv.Kind == yaml.MappingNode {
+					migrateServerV1(srv)
+				}
+			}
+		}
+	}
+	return version, nil
+}
+
+// migrateServerV1 rewrites a version 1 server entry:
+//
+//	log_path: /var/log/app       log_folders:
+//	file_patterns: ["*.log"]  →    - path: /var/log/app
+//	key_path: ~/.ssh/id            file_patterns: ["*.log"]
+//	                             auth:
+//	                               method: key
+//	                               key_path: ~/.ssh/id
+func migrateServerV1(srv *yaml.Node) {
+	patterns := removeEntry(srv, "file_patterns")
+
+	var paths []*yaml.Node
+	if v := removeEntry(srv, "log_path"); v != nil {
+		paths = append(paths, v)
+	}
+	if v := removeEntry(srv, "log_paths"); v != nil && v.Kind == yaml.SequenceNode {
+		paths = append(paths, v.Content...)
+	}
+	if len(paths) > 0 {
+		if _, existing := mappingEntry(srv, "log_folders"); existing == nil {
+			folders := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: paths[0].Line}
+			for _, p := range paths {
+				folder := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: p.Line}
+				folder.Content = append(folder.Content, scalar("path", p.Line), p)
+				if patterns != nil {
+					folder.Content = append(folder.Content, scalar("file_patterns", patterns.Line), patterns)
+				}
+				folders.Content = append(folders.Content, folder)
+			}
+			srv.Content = append(srv.Content, scalar("log_folders", paths[0].Line), folders)
+		}
+	}
+
+	if keyPath := removeEntry(srv, "key_path"); keyPath != nil {
+		_, auth := mappingEntry(srv, "auth")
+		if auth == nil {
+			auth = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: keyPath.Line}
+			srv.Content = append(srv.Content, scalar("auth", keyPath.Line), auth)
+		}
+		if _, existing := mappingEntry(auth, "key_path"); existing == nil {
+			auth.Content = append(auth.Content, scalar("key_path", keyPath.Line), keyPath)
+		}
+		// A server-level key implied key auth.
+		if _, method := mappingEntry(auth, "method"); method == nil {
+			auth.Content = append(auth.Content, scalar("method", keyPath.Line), scalar("key", keyPath.Line))
+		}
+	}
+}
+
+func scalar(value string, line int) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Line: line}
+}
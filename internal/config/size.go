@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ByteSize is a size in bytes, written in the config as a number with an
+// optional unit: "500MB", "2G", "64k" or "1048576". Units are powers of
+// 1024, as ls -lh shows them.
+type ByteSize int64
+
+var sizeUnits = []struct {
+	suffix string
+	mult   int64
+}{
+	{"tb", 1 << 40}, {"t", 1 << 40},
+	{"gb", 1 << 30}, {"g", 1 << 30},
+	{"mb", 1 << 20}, {"m", 1 << 20},
+	{"kb", 1 << 10}, {"k", 1 << 10},
+	{"b", 1},
+}
+
+// UnmarshalYAML parses a size such as "500MB".
+func (b *ByteSize) UnmarshalYAML(node *yaml.Node) error {
+	n, err := parseByteSize(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	*b = n
+	return nil
+}
+
+func parseByteSize(s string) (ByteSize, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	mult := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(v, u.suffix) {
+			v, mult = strings.TrimSpace(strings.TrimSuffix(v, u.suffix)), u.mult
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q (e.g. 500MB, 2G)", s)
+	}
+	return ByteSize(f * float64(mult)), nil
+}
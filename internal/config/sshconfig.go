@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/kevinburke/ssh_config"
+)
+
+// loadSSHConfig parses an OpenSSH client config file.
+func loadSSHConfig(path string) (*ssh_config.Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading ssh config: %w", err)
+	}
+	defer f.Close()
+	cfg, err := ssh_config.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ssh config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// sshConfigPath returns the OpenSSH client config file to read aliases from.
+func sshConfigPath(d Defaults) string {
+	if d.SSHConfig == "" {
+		return expandTilde("~/.ssh/config")
+	}
+	return expandTilde(d.SSHConfig)
+}
+
+// resolveSSHAliases applies ssh config settings to every server with an
+// ssh_config_host. It returns the parsed file, or nil if no server uses one.
+func resolveSSHAliases(servers []ServerConfig, d Defaults) (*ssh_config.Config, error) {
+	var sshCfg *ssh_config.Config
+	for i := range servers {
+		if servers[i].SSHConfigHost == "" {
+			continue
+		}
+		field := fmt.Sprintf("servers[%d]", i)
+		if sshCfg == nil {
+			var err error
+			if sshCfg, err = loadSSHConfig(sshConfigPath(d)); err != nil {
+				return nil, fieldErrorf(field+".ssh_config_host", "%v", err)
+			}
+		}
+		if err := applySSHAlias(&servers[i], sshCfg); err != nil {
+			return nil, fieldErrorf(field+".ssh_config_host", "%v", err)
+		}
+	}
+	return sshCfg, nil
+}
+
+// applySSHAlias fills connection settings of a server that references an
+// OpenSSH Host alias. Values set explicitly in config.yaml take precedence.
+// Must run before applyServerDefaults so defaults don't mask the alias.
+func applySSHAlias(s *ServerConfig, sshCfg *ssh_config.Config) error {
+	alias := s.SSHConfigHost
+	get := func(key string) string {
+		v, _ := sshCfg.Get(alias, key)
+		return v
+	}
+
+	if s.Host == "" {
+		s.Host = get("HostName")
+		if s.Host == "" {
+			s.Host = alias
+		}
+	}
+	if s.User == "" {
+		s.User = get("User")
+	}
+	if s.Port == 0 {
+		if p := get("Port"); p != "" {
+			n, err := strconv.Atoi(p)
+			if err != nil {
+				return fmt.Errorf("invalid Port %q for Host %s in ssh config", p, alias)
+			}
+			s.Port = n
+		}
+	}
+	if s.Auth.KeyPath == "" && (s.Auth.Method == "" || s.Auth.Method == "key") {
+		if ids, _ := sshCfg.GetAll(alias, "IdentityFile"); len(ids) > 0 {
+			s.Auth.Method = "key"
+			s.Auth.KeyPath = ids[0]
+		}
+	}
+	if s.ProxyJump == "" && len(s.ProxyChain) == 0 {
+		if pj := get("ProxyJump"); pj != "" && pj != "none" {
+			s.ProxyJump = pj
+		}
+	}
+	if s.Name == "" {
+		s.Name = alias
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// unknownKeys walks a parsed document alongside the struct it decodes into
+// and reports keys that no field accepts, e.g. a misspelled file_pattern.
+// Merge keys (<<) and aliases are followed, and top-level keys starting with
+// "x-" are left alone so they can hold anchors.
+func unknownKeys(doc *yaml.Node, into any) []string {
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	var warnings []string
+	walkKeys(doc.Content[0], reflect.TypeOf(into), "", &warnings)
+	return warnings
+}
+
+func walkKeys(node *yaml.Node, t reflect.Type, path string, warnings *[]string) {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "<<" {
+				walkMerge(value, t, path, warnings)
+				continue
+			}
+			if path == "" && strings.HasPrefix(key.Value, "x-") {
+				continue
+			}
+			field, ok := fields[key.Value]
+			if !ok {
+				msg := fmt.Sprintf("line %d: %s: unknown key", key.Line, joinField(path, key.Value))
+				if guess := closestField(key.Value, fields); guess != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", guess)
+				}
+				*warnings = append(*warnings, msg)
+				continue
+			}
+			walkKeys(value, field, joinField(path, key.Value), warnings)
+		}
+
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for i, item := range node.Content {
+			walkKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), warnings)
+		}
+	}
+}
+
+// walkMerge checks the mapping(s) pulled in by a << merge key.
+func walkMerge(value *yaml.Node, t reflect.Type, path string, warnings *[]string) {
+	if value.Kind == yaml.SequenceNode {
+		for _, item := range value.Content {
+			walkKeys(item, t, path, warnings)
+		}
+		return
+	}
+	walkKeys(value, t, path, warnings)
+}
+
+// yamlFields maps the yaml key of every decodable field to its type.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func joinField(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// closestField suggests a known key within two edits of key, if any.
+func closestField(key string, fields map[string]reflect.Type) string {
+	best, bestDist := "", 3
+	for name := range fields {
+		if d := editDistance(key, name); d < bestDist || (d == bestDist && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
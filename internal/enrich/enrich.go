@@ -0,0 +1,174 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"log-monitor/internal/logger"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// ipCandidateRe matches text that may be an IPv4 or IPv6 address; each match
+// is checked with netip.ParseAddr.
+var ipCandidateRe = regexp.MustCompile(`\d{1,3}(?:\.\d{1,3}){3}|[0-9A-Fa-f]{0,4}(?::[0-9A-Fa-f]{0,4}){2,7}(?:\.\d{1,3}){0,3}(?:%[0-9A-Za-z]+)?`)
+
+// FindIPs returns the distinct IP addresses in text, in order of appearance.
+// Unspecified addresses such as "::" are skipped.
+func FindIPs(text string) []netip.Addr {
+	var ips []netip.Addr
+	seen := make(map[netip.Addr]bool)
+	for _, loc := range ipCandidateRe.FindAllStringIndex(text, -1) {
+		if !standalone(text, loc[0], loc[1]) {
+			continue
+		}
+		addr, err := netip.ParseAddr(text[loc[0]:loc[1]])
+		if err != nil || addr.IsUnspecified() || seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		ips = append(ips, addr)
+	}
+	return ips
+}
+
+// standalone reports whether text[start:end] isn't part of a longer run of
+// digits, dots or colons, e.g. a version number like 1.2.3.4.5. An IPv4
+// address may be followed by a colon and a port, and any address by a full
+// stop.
+func standalone(text string, start, end int) bool {
+	edge := func(b byte) bool {
+		return b == '.' || b == ':' || b >= '0' && b <= '9'
+	}
+	if start > 0 && edge(text[start-1]) {
+		return false
+	}
+	if end == len(text) || !edge(text[end]) {
+		return true
+	}
+	switch text[end] {
+	case ':':
+		return !strings.Contains(text[start:end], ":")
+	case '.':
+		return end+1 == len(text) || !edge(text[end+1])
+	}
+	return false
+}
+
+// Info is what could be found out about an address.
+type Info struct {
+	Addr    netip.Addr
+	Names   []string // reverse DNS names, without the trailing dot
+	DNSErr  error    // reverse lookup failure; a missing PTR record is not one
+	City    string
+	Country string // English country name
+	ISOCode string // two-letter country code
+	ASN     uint   // autonomous system number, 0 if unknown
+	ASOrg   string // autonomous system organization
+}
+
+// Label is a short description for annotating the address in the logs,
+// e.g. "host.example.com · DE", or "" if nothing is known.
+func (i Info) Label() string {
+	var parts []string
+	if len(i.Names) > 0 {
+		parts = append(parts, i.Names[0])
+	}
+	switch {
+	case i.ISOCode != "":
+		parts = append(parts, i.ISOCode)
+	case i.ASOrg != "":
+		parts = append(parts, i.ASOrg)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// Location describes where the address is, e.g. "Frankfurt, Germany (DE)".
+func (i Info) Location() string {
+	var parts []string
+	if i.City != "" {
+		parts = append(parts, i.City)
+	}
+	if i.Country != "" {
+		parts = append(parts, i.Country)
+	}
+	loc := strings.Join(parts, ", ")
+	if i.ISOCode != "" {
+		loc = strings.TrimSpace(fmt.Sprintf("%s (%s)", loc, i.ISOCode))
+	}
+	return loc
+}
+
+// Lookup resolves the address's reverse DNS names and, when geo is not
+// nil, its location and network.
+func Lookup(ctx context.Context, addr netip.Addr, geo *GeoDB) Info {
+	info := Info{Addr: addr}
+	names, err := net.DefaultResolver.LookupAddr(ctx, addr.String())
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil:
+		for _, n := range names {
+			info.Names = append(info.Names, strings.TrimSuffix(n, "."))
+		}
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+	default:
+		info.DNSErr = err
+		logger.Log("enrich", "reverse lookup of %s: %v", addr, err)
+	}
+	if geo != nil {
+		geo.fill(&info)
+	}
+	return info
+}
+
+// GeoDB is a local MaxMind database (GeoLite2 / GeoIP2 City, Country or
+// ASN, or a compatible one).
+type GeoDB struct {
+	r *maxminddb.Reader
+}
+
+// OpenGeoDB opens the .mmdb file at path.
+func OpenGeoDB(path string) (*GeoDB, error) {
+	r, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening GeoIP database: %w", err)
+	}
+	logger.Log("enrich", "GeoIP database %s (%s, built %d)", path, r.Metadata.DatabaseType, r.Metadata.BuildEpoch)
+	return &GeoDB{r: r}, nil
+}
+
+// Close releases the database.
+func (g *GeoDB) Close() error {
+	return g.r.Close()
+}
+
+// geoRecord holds the fields used from City, Country and ASN databases.
+type geoRecord struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	ASN   uint   `maxminddb:"autonomous_system_number"`
+	ASOrg string `maxminddb:"autonomous_system_organization"`
+}
+
+func (g *GeoDB) fill(info *Info) {
+	var rec geoRecord
+	if err := g.r.Lookup(net.IP(info.Addr.Unmap().AsSlice()), &rec); err != nil {
+		logger.Log("enrich", "GeoIP lookup of %s: %v", info.Addr, err)
+		return
+	}
+	info.City = rec.City.Names["en"]
+	info.Country = rec.Country.Names["en"]
+	info.ISOCode = rec.Country.ISOCode
+	info.ASN = rec.ASN
+	info.ASOrg = rec.ASOrg
+}
@@ -0,0 +1,123 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/logger"
+)
+
+// maxFeedSize caps how much is read from an event feed.
+const maxFeedSize = 1 << 20
+
+// Event is something that happened outside the logs, typically a deployment.
+type Event struct {
+	Time   time.Time `json:"time"`
+	Text   string    `json:"text"`
+	Server string    `json:"server"` // server name it applies to; empty for all servers
+}
+
+// Key identifies an event across polls of the feed.
+func (e Event) Key() string {
+	return e.Time.UTC().Format(time.RFC3339Nano) + "\x00" + e.Server + "\x00" + e.Text
+}
+
+// AppliesTo reports whether the event belongs on the named server's logs.
+func (e Event) AppliesTo(server string) bool {
+	return e.Server == "" || e.Server == server
+}
+
+// Fetch reads the event feed.
+func Fetch(ctx context.Context, c config.EventsConfig) ([]Event, error) {
+	var data []byte
+	var err error
+	if c.URL != "" {
+		data, err = readURL(ctx, c.URL)
+	} else {
+		data, err = runCommand(ctx, c.Command)
+	}
+	if err != nil {
+		return nil, err
+	}
+	evs, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	logger.Log("events", "loaded %d events", len(evs))
+	return evs, nil
+}
+
+// Parse decodes a feed: a JSON array of events, or one JSON event per line.
+// Events must have a time and a text.
+func Parse(data []byte) ([]Event, error) {
+	data = bytes.TrimSpace(data)
+	var evs []Event
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &evs); err != nil {
+			return nil, fmt.Errorf("parsing events: %w", err)
+		}
+	} else {
+		for i, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			var ev Event
+			if err := json.Unmarshal([]byte(line), &ev); err != nil {
+				return nil, fmt.Errorf("parsing events line %d: %w", i+1, err)
+			}
+			evs = append(evs, ev)
+		}
+	}
+	for i, ev := range evs {
+		if ev.Time.IsZero() || ev.Text == "" {
+			return nil, fmt.Errorf("parsing events: event %d needs a time and a text", i+1)
+		}
+	}
+	return evs, nil
+}
+
+func runCommand(ctx context.Context, command string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running events command: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if len(out) > maxFeedSize {
+		return nil, fmt.Errorf("events command printed more than %d bytes", maxFeedSize)
+	}
+	return out, nil
+}
+
+func readURL(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetching events: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching events: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching events %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetching events: %w", err)
+	}
+	if len(data) > maxFeedSize {
+		return nil, fmt.Errorf("events feed %s is larger than %d bytes", url, maxFeedSize)
+	}
+	return data, nil
+}
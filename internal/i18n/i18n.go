@@ -0,0 +1,67 @@
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"sync"
+
+	"log-monitor/internal/logger"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Catalogs map the English text of a message, as written in the code, to
+// its translation. Messages missing from the catalog stay in English, so a
+// catalog can be partial.
+
+//go:embed locales/*.yaml
+var builtin embed.FS
+
+var (
+	mu      sync.RWMutex
+	catalog map[string]string
+)
+
+// Load selects the language of the UI. locale is a language code such as
+// "de"; when empty the UI stays in English. A catalog file, if given, adds
+// to or overrides the built-in translations of that language.
+func Load(locale, file string) error {
+	if locale == "" {
+		locale = "en"
+	}
+	msgs := make(map[string]string)
+	if raw, err := builtin.ReadFile("locales/" + locale + ".yaml"); err == nil {
+		if err := yaml.Unmarshal(raw, &msgs); err != nil {
+			return fmt.Errorf("parsing built-in %s messages: %w", locale, err)
+		}
+	}
+	if file != "" {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("reading messages: %w", err)
+		}
+		if err := yaml.Unmarshal(raw, &msgs); err != nil {
+			return fmt.Errorf("parsing messages %s: %w", file, err)
+		}
+	}
+	logger.Log("i18n", "locale %q: %d translated message(s)", locale, len(msgs))
+
+	mu.Lock()
+	defer mu.Unlock()
+	catalog = msgs
+	return nil
+}
+
+// T translates a message and, with args, formats it like fmt.Sprintf.
+func T(msg string, args ...any) string {
+	mu.RLock()
+	if tr, ok := catalog[msg]; ok && tr != "" {
+		msg = tr
+	}
+	mu.RUnlock()
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
@@ -0,0 +1,28 @@
+package keychain
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CommandPassword runs a password manager command such as
+// `pass show servers/web1` through sh and returns the first line it prints,
+// which is where pass and op put the secret.
+func CommandPassword(ctx context.Context, command string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("running password command: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	pw, _, _ := strings.Cut(string(out), "\n")
+	pw = strings.TrimSuffix(pw, "\r")
+	if pw == "" {
+		return "", fmt.Errorf("password command printed no password")
+	}
+	return pw, nil
+}
@@ -0,0 +1,46 @@
+package keychain
+
+import (
+	"errors"
+	"fmt"
+
+	"log-monitor/internal/logger"
+
+	"github.com/zalando/go-keyring"
+)
+
+// service is the name entries are stored under in the OS keychain
+// (macOS Keychain, Secret Service on Linux, Windows Credential Manager).
+const service = "log-monitor"
+
+// GetSudoPassword returns the sudo password stored for the given server key,
+// or "" if there is none or the keychain is unavailable.
+func GetSudoPassword(serverKey string) string {
+	pw, err := keyring.Get(service, sudoAccount(serverKey))
+	if err != nil {
+		if !errors.Is(err, keyring.ErrNotFound) {
+			logger.Log("keychain", "get %s: %v", serverKey, err)
+		}
+		return ""
+	}
+	return pw
+}
+
+// SetSudoPassword stores the sudo password for the given server key.
+func SetSudoPassword(serverKey, password string) error {
+	if err := keyring.Set(service, sudoAccount(serverKey), password); err != nil {
+		return fmt.Errorf("storing sudo password in keychain: %w", err)
+	}
+	return nil
+}
+
+// DeleteSudoPassword removes the stored sudo password for the given server key.
+func DeleteSudoPassword(serverKey string) {
+	if err := keyring.Delete(service, sudoAccount(serverKey)); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		logger.Log("keychain", "delete %s: %v", serverKey, err)
+	}
+}
+
+func sudoAccount(serverKey string) string {
+	return "sudo:" + serverKey
+}
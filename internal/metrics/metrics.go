@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"log-monitor/internal/config"
+)
+
+// maxSamples caps each series. Beyond it the oldest tenth is dropped.
+const maxSamples = 100_000
+
+// Sample is one value pulled from a log line.
+type Sample struct {
+	At    time.Time
+	Value float64
+}
+
+type rule struct {
+	re    *regexp.Regexp
+	names []string // metric name per subexpression, "" for unnamed groups
+}
+
+// Set extracts metrics from log lines and keeps them as series, one per
+// named group.
+type Set struct {
+	rules  []rule
+	series map[string][]Sample
+	order  []string // metric names in order of first appearance
+}
+
+// NewSet compiles the rules. Patterns are checked when the config is
+// loaded, so an error here means the config wasn't validated.
+func NewSet(rules []config.MetricRule) (*Set, error) {
+	s := &Set{series: make(map[string][]Sample)}
+	for _, r := range rules {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("metric pattern %q: %w", r.Pattern, err)
+		}
+		s.rules = append(s.rules, rule{re: re, names: re.SubexpNames()})
+	}
+	return s, nil
+}
+
+// Enabled reports whether any rules are configured.
+func (s *Set) Enabled() bool {
+	return len(s.rules) > 0
+}
+
+// Add extracts the values in line, recorded at the given time. Groups that
+// don't hold a number are skipped.
+func (s *Set) Add(line string, at time.Time) {
+	for _, r := range s.rules {
+		m := r.re.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		for i, name := range r.names {
+			if name == "" || m[i] == "" {
+				continue
+			}
+			v, err := strconv.ParseFloat(m[i], 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			s.add(name, Sample{At: at, Value: v})
+		}
+	}
+}
+
+func (s *Set) add(name string, sample Sample) {
+	series, ok := s.series[name]
+	if !ok {
+		s.order = append(s.order, name)
+	}
+	if len(series) >= maxSamples {
+		series = append(series[:0], series[maxSamples/10:]...)
+	}
+	s.series[name] = append(series, sample)
+}
+
+// Reset drops all samples, e.g. when another file is opened.
+func (s *Set) Reset() {
+	s.series = make(map[string][]Sample)
+	s.order = nil
+}
+
+// Names returns the metrics seen so far, in order of first appearance.
+func (s *Set) Names() []string {
+	return s.order
+}
+
+// Series returns the samples of a metric, oldest first.
+func (s *Set) Series(name string) []Sample {
+	return s.series[name]
+}
+
+// Summary describes the values of a series.
+type Summary struct {
+	Count int
+	Min   float64
+	Avg   float64
+	Max   float64
+	P95   float64 // 95th percentile, nearest rank
+}
+
+// Summarize computes a summary of samples. The zero Summary is returned
+// for an empty series.
+func Summarize(samples []Sample) Summary {
+	if len(samples) == 0 {
+		return Summary{}
+	}
+	values := make([]float64, len(samples))
+	var sum float64
+	for i, sm := range samples {
+		values[i] = sm.Value
+		sum += sm.Value
+	}
+	slices.Sort(values)
+	rank := int(math.Ceil(0.95*float64(len(values)))) - 1
+	return Summary{
+		Count: len(values),
+		Min:   values[0],
+		Avg:   sum / float64(len(values)),
+		Max:   values[len(values)-1],
+		P95:   values[max(rank, 0)],
+	}
+}
+
+// WriteCSV writes every sample as a "time,metric,value" row, metric by
+// metric, with a header line.
+func (s *Set) WriteCSV(w io.Writer) (rows int, err error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"time", "metric", "value"}); err != nil {
+		return 0, err
+	}
+	for _, name := range s.order {
+		for _, sm := range s.series[name] {
+			rec := []string{
+				sm.At.Format(time.RFC3339),
+				name,
+				strconv.FormatFloat(sm.Value, 'f', -1, 64),
+			}
+			if err := cw.Write(rec); err != nil {
+				return rows, err
+			}
+			rows++
+		}
+	}
+	cw.Flush()
+	return rows, cw.Error()
+}
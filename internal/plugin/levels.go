@@ -0,0 +1,79 @@
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+func init() {
+	Register("levels", func() Pane { return &levels{counts: make(map[string]int)} })
+}
+
+// levelRe finds the severity of a log line.
+var levelRe = regexp.MustCompile(`\b(?i:(FATAL|CRIT(?:ICAL)?|ERROR|ERR|WARN(?:ING)?|NOTICE|INFO|DEBUG|TRACE))\b`)
+
+// levelOrder lists the severities from most to least severe, as shown.
+var levelOrder = []string{"FATAL", "ERROR", "WARN", "NOTICE", "INFO", "DEBUG", "TRACE"}
+
+// levels is a built-in plugin, and an example for writing others: it
+// counts the lines of each severity in the open file.
+type levels struct {
+	counts map[string]int
+	total  int
+}
+
+func (l *levels) Title() string { return "Levels" }
+
+func (l *levels) Reset() {
+	clear(l.counts)
+	l.total = 0
+}
+
+func (l *levels) Feed(lines []string) {
+	for _, line := range lines {
+		l.total++
+		m := levelRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		level := strings.ToUpper(m[1])
+		switch {
+		case strings.HasPrefix(level, "CRIT"):
+			level = "FATAL"
+		case level == "ERR":
+			level = "ERROR"
+		case strings.HasPrefix(level, "WARN"):
+			level = "WARN"
+		}
+		l.counts[level]++
+	}
+}
+
+func (l *levels) HandleKey(key string) bool {
+	if key == "x" {
+		l.Reset()
+		return true
+	}
+	return false
+}
+
+func (l *levels) View(width, height int) string {
+	if l.total == 0 {
+		return "No lines yet"
+	}
+	var rows []string
+	for _, level := range levelOrder {
+		n := l.counts[level]
+		if n == 0 {
+			continue
+		}
+		bar := max(width-16, 0) * n / l.total
+		rows = append(rows, fmt.Sprintf("%-6s %7d %s", level, n, strings.Repeat("▇", bar)))
+	}
+	rows = append(rows, fmt.Sprintf("%-6s %7d   (x: reset)", "lines", l.total))
+	if len(rows) > height {
+		rows = rows[len(rows)-height:]
+	}
+	return strings.Join(rows, "\n")
+}
@@ -0,0 +1,76 @@
+// Package plugin lets compiled-in extensions add panes to the UI that are
+// fed the lines of the open file, e.g. a summary of request traces. A
+// plugin registers a constructor from an init function:
+//
+//	func init() {
+//		plugin.Register("traces", func() plugin.Pane { return &traceSummary{} })
+//	}
+//
+// is linked in with a blank import in main, and is turned on by listing
+// its name under plugins: in the config.
+package plugin
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Pane is an extra pane driven by the UI. Its methods are called from the
+// UI goroutine only.
+type Pane interface {
+	// Title is shown in the pane's top border.
+	Title() string
+	// Reset forgets everything fed so far; another file was opened.
+	Reset()
+	// Feed hands over lines of the open file as they arrive, without
+	// colors.
+	Feed(lines []string)
+	// HandleKey is given the keys typed while the pane has the focus,
+	// e.g. "j" or "enter", and reports whether it used the key.
+	HandleKey(key string) bool
+	// View renders the pane's content in width×height cells.
+	View(width, height int) string
+}
+
+// Factory creates a pane; each use of a plugin gets its own.
+type Factory func() Pane
+
+var (
+	mu       sync.Mutex
+	registry = make(map[string]Factory)
+)
+
+// Register makes a plugin available under name. It panics if the name is
+// taken, as two plugins claiming one name is a build mistake.
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := registry[name]; dup {
+		panic("plugin: Register called twice for " + name)
+	}
+	registry[name] = f
+}
+
+// New creates a pane of the named plugin.
+func New(name string) (Pane, error) {
+	mu.Lock()
+	f, ok := registry[name]
+	mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown plugin %q (available: %v)", name, Names())
+	}
+	return f(), nil
+}
+
+// Names returns the registered plugins, sorted.
+func Names() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
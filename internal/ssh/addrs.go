@@ -0,0 +1,151 @@
+package ssh
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/logger"
+)
+
+// fallbackDelay is how long a connection attempt runs alone before the
+// next address is tried alongside it, as in Happy Eyeballs (RFC 8305).
+const fallbackDelay = 250 * time.Millisecond
+
+// dialTarget is one address a server may be reached at.
+type dialTarget struct {
+	host string // the host as configured, which its host key is checked for
+	addr string // host:port to connect to
+}
+
+// dialTargets lists where srv can be reached: each of its hosts, and every
+// address a host name resolves to, IPv6 and IPv4 alternating. Through a
+// proxy, names are left for the proxy to resolve.
+func dialTargets(ctx context.Context, srv config.ServerConfig) ([]dialTarget, error) {
+	port := strconv.Itoa(srv.Port)
+	var targets []dialTarget
+	var lookupErr error
+	for _, host := range srv.Addresses() {
+		if _, err := netip.ParseAddr(host); err == nil || srv.Proxy != "" {
+			targets = append(targets, dialTarget{host: host, addr: net.JoinHostPort(host, port)})
+			continue
+		}
+		ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			logger.Log("ssh", "resolving %s: %v", host, err)
+			if lookupErr == nil {
+				lookupErr = err
+			}
+			continue
+		}
+		for _, ip := range interleaveFamilies(ips) {
+			targets = append(targets, dialTarget{host: host, addr: net.JoinHostPort(ip.Unmap().String(), port)})
+		}
+	}
+	if len(targets) == 0 {
+		return nil, lookupErr
+	}
+	return targets, nil
+}
+
+// interleaveFamilies orders addresses IPv6, IPv4, IPv6, ... keeping the
+// resolver's order within each family, so a broken family costs one
+// fallbackDelay rather than one per address.
+func interleaveFamilies(ips []netip.Addr) []netip.Addr {
+	var v6, v4 []netip.Addr
+	for _, ip := range ips {
+		if ip.Unmap().Is4() {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+	out := make([]netip.Addr, 0, len(ips))
+	for i := range max(len(v6), len(v4)) {
+		if i < len(v6) {
+			out = append(out, v6[i])
+		}
+		if i < len(v4) {
+			out = append(out, v4[i])
+		}
+	}
+	return out
+}
+
+// dialAny connects to one of the targets. Each attempt gets fallbackDelay
+// before the next one starts alongside it, or less if it fails sooner. The
+// first connection wins and the others are closed. It returns the winner's
+// index, or the first error if every attempt failed.
+func dialAny(ctx context.Context, proxy string, targets []dialTarget) (net.Conn, int, error) {
+	if len(targets) == 1 {
+		conn, err := dialTCP(ctx, proxy, targets[0].addr)
+		return conn, 0, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn net.Conn
+		i    int
+		err  error
+	}
+	results := make(chan result, len(targets))
+	start := func(i int) {
+		logger.Log("ssh", "TCP dialing %s (%s) ...", targets[i].addr, targets[i].host)
+		go func() {
+			conn, err := dialTCP(ctx, proxy, targets[i].addr)
+			results <- result{conn, i, err}
+		}()
+	}
+
+	next, running := 0, 0
+	var firstErr error
+	// drain closes the connections of attempts still running
+	drain := func() {
+		cancel()
+		go func(running int) {
+			for range running {
+				if late := <-results; late.conn != nil {
+					late.conn.Close()
+				}
+			}
+		}(running)
+	}
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		if next < len(targets) && running == 0 {
+			// Nothing left in flight: don't wait for the timer
+			timer.Reset(0)
+		}
+		select {
+		case <-timer.C:
+			if next < len(targets) {
+				start(next)
+				next++
+				running++
+				timer.Reset(fallbackDelay)
+			}
+		case r := <-results:
+			running--
+			if r.err == nil {
+				drain()
+				return r.conn, r.i, nil
+			}
+			logger.Log("ssh", "TCP dial %s failed: %v", targets[r.i].addr, r.err)
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if running == 0 && next == len(targets) {
+				return nil, -1, firstErr
+			}
+		case <-ctx.Done():
+			drain()
+			return nil, -1, ctx.Err()
+		}
+	}
+}
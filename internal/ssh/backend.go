@@ -0,0 +1,43 @@
+package ssh
+
+import (
+	"context"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// FileBackend lists, stats and downloads files on a server. Reading and
+// tailing always go through shell commands.
+type FileBackend interface {
+	ListFiles(ctx context.Context, client *gossh.Client, dir string, patterns []string, opts CommandOpts) ([]FileInfo, error)
+	StatFile(ctx context.Context, client *gossh.Client, path string, opts CommandOpts) (*FileInfo, error)
+	DownloadFile(client *gossh.Client, remotePath, localPath string, opts CommandOpts, ctx context.Context, progressCh chan<- int64) error
+}
+
+// Backend returns the file backend configured for a server: "sftp",
+// "local" for a local server, or "shell" (ls, stat and cat, which also work
+// under sudo).
+func Backend(name string) FileBackend {
+	switch name {
+	case "sftp":
+		return sftpBackend{}
+	case "local":
+		return localBackend{}
+	}
+	return shellBackend{}
+}
+
+// shellBackend runs ls, stat and cat over exec sessions.
+type shellBackend struct{}
+
+func (shellBackend) ListFiles(ctx context.Context, client *gossh.Client, dir string, patterns []string, opts CommandOpts) ([]FileInfo, error) {
+	return ListFiles(ctx, client, dir, patterns, opts)
+}
+
+func (shellBackend) StatFile(ctx context.Context, client *gossh.Client, path string, opts CommandOpts) (*FileInfo, error) {
+	return StatFile(ctx, client, path, opts)
+}
+
+func (shellBackend) DownloadFile(client *gossh.Client, remotePath, localPath string, opts CommandOpts, ctx context.Context, progressCh chan<- int64) error {
+	return DownloadFile(client, remotePath, localPath, opts, ctx, progressCh)
+}
@@ -2,13 +2,17 @@ package ssh
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"log-monitor/internal/config"
+	"log-monitor/internal/keychain"
 	"log-monitor/internal/logger"
 
 	"golang.org/x/crypto/ssh"
@@ -20,12 +24,59 @@ type Pool struct {
 	mu         sync.Mutex
 	clients    map[string]*ssh.Client
 	sudoPasswd map[string]string
+	sudoNoPass map[string]bool
+	hostInfo   map[string]HostInfo
+	banners    map[string]string     // last pre-auth banner per server key
+	signers    map[string]ssh.Signer // unlocked passphrase-protected keys by path
+	challenges chan<- Challenge      // keyboard-interactive prompts for the UI
+	knownHosts string                // known_hosts file used to verify host keys
+	onState    func(StateChange)     // connection state callback for the UI
+
+	alive map[string]time.Time // last successful background keepalive per client
+
+	latency map[string]time.Duration   // round trip of the last keepalive per client
+	lost    map[string]error           // why each server's last connection ended
+	traffic map[string]*serverCounters // dials and bytes per server key, for Stats
+
+	lastUsed    map[string]time.Time // when each client was last handed out or released
+	busy        map[string]int       // long-running operations (tails, downloads) per client
+	idleTimeout time.Duration        // close clients unused for this long, 0 if off
+	idleStop    chan struct{}        // closed to stop the idle janitor
+
+	hops        map[string]*ssh.Client // open jump host connections, shareable over the control socket
+	dialing     map[string]*dialCall   // dials in progress, shared by concurrent GetClient calls
+	controlPath string                 // control socket for connection sharing, "" if off
+	controlLn   net.Listener           // set when this pool owns the control socket
 }
 
-func NewPool() *Pool {
+// HostInfo describes the machine a pooled connection actually reached.
+type HostInfo struct {
+	Hostname    string // output of `hostname -f` on the remote side
+	KeyType     string // host key algorithm, e.g. ssh-ed25519
+	Fingerprint string // SHA256 host key fingerprint
+	Banner      string // pre-authentication banner sent by the server
+	MOTD        string // message of the day, from /run/motd.dynamic and /etc/motd
+}
+
+// NewPool creates a pool that verifies host keys against the given
+// known_hosts file.
+func NewPool(knownHosts string) *Pool {
 	return &Pool{
+		knownHosts: knownHosts,
 		clients:    make(map[string]*ssh.Client),
 		sudoPasswd: make(map[string]string),
+		sudoNoPass: make(map[string]bool),
+		hostInfo:   make(map[string]HostInfo),
+		banners:    make(map[string]string),
+		signers:    make(map[string]ssh.Signer),
+		hops:       make(map[string]*ssh.Client),
+		dialing:    make(map[string]*dialCall),
+		alive:      make(map[string]time.Time),
+		latency:    make(map[string]time.Duration),
+		lost:       make(map[string]error),
+		traffic:    make(map[string]*serverCounters),
+		lastUsed:   make(map[string]time.Time),
+		busy:       make(map[string]int),
 	}
 }
 
@@ -50,26 +101,81 @@ func (p *Pool) GetSudoPassword(srv config.ServerConfig) string {
 	return p.sudoPasswd[key]
 }
 
-// ClearSudoPassword removes the stored sudo password for a server.
+// ClearSudoPassword removes the stored sudo password and NOPASSWD mark for a server.
 func (p *Pool) ClearSudoPassword(srv config.ServerConfig) {
 	key := ServerKey(srv)
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	delete(p.sudoPasswd, key)
+	delete(p.sudoNoPass, key)
+}
+
+// SetSudoNoPasswd marks a server as allowing sudo without a password.
+func (p *Pool) SetSudoNoPasswd(srv config.ServerConfig) {
+	key := ServerKey(srv)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sudoNoPass[key] = true
+}
+
+// IsSudoNoPasswd returns true if the server was found to allow sudo without a password.
+func (p *Pool) IsSudoNoPasswd(srv config.ServerConfig) bool {
+	key := ServerKey(srv)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sudoNoPass[key]
+}
+
+// HostInfo returns what is known about the remote host behind a server config.
+func (p *Pool) HostInfo(srv config.ServerConfig) HostInfo {
+	key := ServerKey(srv)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.hostInfo[key]
+}
+
+// SetRemoteMOTD records the message of the day read from the server.
+func (p *Pool) SetRemoteMOTD(srv config.ServerConfig, motd string) {
+	key := ServerKey(srv)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	info := p.hostInfo[key]
+	info.MOTD = motd
+	p.hostInfo[key] = info
+}
+
+// SetRemoteHostname records the hostname reported by the remote server.
+func (p *Pool) SetRemoteHostname(srv config.ServerConfig, hostname string) {
+	key := ServerKey(srv)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	info := p.hostInfo[key]
+	info.Hostname = hostname
+	p.hostInfo[key] = info
 }
 
 // GetClient returns a cached or new SSH connection for the given server config.
-// The context allows callers to cancel/timeout the connection attempt.
+// The context allows callers to cancel/timeout the connection attempt. A
+// local server has none: it fails with ErrLocal.
 func (p *Pool) GetClient(ctx context.Context, srv config.ServerConfig) (*ssh.Client, error) {
+	if srv.Local {
+		return nil, ErrLocal
+	}
 	key := fmt.Sprintf("%s@%s:%d", srv.User, srv.Host, srv.Port)
 	logger.Log("ssh", "GetClient start: %s", key)
 
 	p.mu.Lock()
 	if c, ok := p.clients[key]; ok {
+		p.lastUsed[key] = time.Now()
 		p.mu.Unlock()
+		if p.recentlyAlive(key, srv) {
+			logger.Log("ssh", "found cached client for %s, alive per background keepalive", key)
+			return c, nil
+		}
 		logger.Log("ssh", "found cached client for %s, sending keepalive", key)
 		// Check if connection is still alive — outside the lock so a slow
 		// SendRequest doesn't block the entire pool.
+		start := time.Now()
 		done := make(chan error, 1)
 		go func() {
 			_, _, err := c.SendRequest("keepalive@openssh.com", true, nil)
@@ -79,6 +185,10 @@ func (p *Pool) GetClient(ctx context.Context, srv config.ServerConfig) (*ssh.Cli
 		case err := <-done:
 			if err == nil {
 				logger.Log("ssh", "keepalive OK for %s", key)
+				p.mu.Lock()
+				p.alive[key] = time.Now()
+				p.mu.Unlock()
+				p.setLatency(key, c, time.Since(start))
 				return c, nil
 			}
 			logger.Log("ssh", "keepalive failed for %s: %v", key, err)
@@ -93,6 +203,7 @@ func (p *Pool) GetClient(ctx context.Context, srv config.ServerConfig) (*ssh.Cli
 		p.mu.Lock()
 		if p.clients[key] == c {
 			delete(p.clients, key)
+			delete(p.latency, key)
 		}
 		p.mu.Unlock()
 	} else {
@@ -100,73 +211,296 @@ func (p *Pool) GetClient(ctx context.Context, srv config.ServerConfig) (*ssh.Cli
 		logger.Log("ssh", "no cached client for %s, dialing", key)
 	}
 
-	client, err := dial(ctx, srv)
+	// Share a dial already in progress, e.g. the listing's when a tail
+	// starts before the connection is up
+	p.mu.Lock()
+	if c, ok := p.clients[key]; ok {
+		// Dialed by someone else since the check above
+		p.lastUsed[key] = time.Now()
+		p.mu.Unlock()
+		return c, nil
+	}
+	if call, ok := p.dialing[key]; ok {
+		p.mu.Unlock()
+		logger.Log("ssh", "waiting for the dial to %s in progress", key)
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if call.err != nil && ctx.Err() == nil && (errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded)) {
+			// Only the caller that dialed gave up; try again on our time
+			return p.GetClient(ctx, srv)
+		}
+		return call.client, call.err
+	}
+	call := &dialCall{done: make(chan struct{})}
+	p.dialing[key] = call
+	p.mu.Unlock()
+
+	call.client, call.err = p.connect(ctx, key, srv)
+	p.mu.Lock()
+	delete(p.dialing, key)
+	p.mu.Unlock()
+	close(call.done)
+	return call.client, call.err
+}
+
+// dialCall is a dial in progress, shared by concurrent GetClient calls for
+// the same server.
+type dialCall struct {
+	done   chan struct{} // closed once client and err are set
+	client *ssh.Client
+	err    error
+}
+
+// connect dials a server and adds the connection to the pool.
+func (p *Pool) connect(ctx context.Context, key string, srv config.ServerConfig) (*ssh.Client, error) {
+	p.notify(key, StateConnecting, nil)
+	client, hostKey, err := p.dial(ctx, srv)
 	if err != nil {
 		logger.Log("ssh", "dial failed for %s: %v", key, err)
+		p.notify(key, StateError, err)
 		return nil, err
 	}
 
 	logger.Log("ssh", "dial succeeded for %s", key)
+	if srv.AgentForwarding {
+		if err := forwardAgent(client); err != nil {
+			client.Close()
+			p.notify(key, StateError, err)
+			return nil, err
+		}
+	}
+	setSessionLimit(client, srv.MaxSessions)
 	p.mu.Lock()
 	p.clients[key] = client
+	delete(p.lost, key)
+	p.alive[key] = time.Now()
+	p.lastUsed[key] = time.Now()
+	p.hostInfo[key] = HostInfo{
+		KeyType:     hostKey.Type(),
+		Fingerprint: ssh.FingerprintSHA256(hostKey),
+		Banner:      p.banners[key],
+	}
 	p.mu.Unlock()
+	p.notify(key, StateConnected, nil)
+	go p.watch(key, client)
+	go p.keepalive(key, client, srv)
+	go p.measureLatency(key, client)
 
 	return client, nil
 }
 
-func dial(ctx context.Context, srv config.ServerConfig) (*ssh.Client, error) {
-	logger.Log("ssh", "buildAuth method=%s", srv.Auth.Method)
-	authMethods, agentConn, err := buildAuth(srv.Auth)
+// dialOnce connects to a server and returns the client along with the host
+// key presented during the handshake. A server with several addresses (a
+// host list, or a name with several A/AAAA records) is reached at whichever
+// accepts first; if its SSH handshake breaks off, the others are tried.
+func (p *Pool) dialOnce(ctx context.Context, srv config.ServerConfig) (*ssh.Client, ssh.PublicKey, error) {
+	if len(srv.JumpHosts) > 0 && srv.PooledHops {
+		return p.dialViaChain(ctx, srv)
+	}
+	if len(srv.JumpHosts) > 0 {
+		return p.dialViaJumpHosts(ctx, srv)
+	}
+
+	targets, err := dialTargets(ctx, srv)
 	if err != nil {
-		logger.Log("ssh", "buildAuth failed: %v", err)
-		return nil, fmt.Errorf("auth setup for %s: %w", srv.Host, err)
+		return nil, nil, fmt.Errorf("resolving %s: %w", srv.Host, err)
 	}
-	logger.Log("ssh", "buildAuth succeeded")
+	for {
+		// Dial TCP with the context so callers can cancel/timeout the attempt;
+		// its deadline is the server's connect_timeout.
+		tcpConn, i, err := dialAny(ctx, srv.Proxy, targets)
+		if err != nil {
+			addr := fmt.Sprintf("%s:%d", srv.Host, srv.Port)
+			logger.Log("ssh", "TCP dial failed %s: %v", addr, err)
+			return nil, nil, fmt.Errorf("TCP dial %s: %w", addr, err)
+		}
+		logger.Log("ssh", "TCP connected to %s", targets[i].addr)
 
-	cfg := &ssh.ClientConfig{
-		User:            srv.User,
-		Auth:            authMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		// The host key is checked for the host as configured
+		at := srv
+		at.Host = targets[i].host
+		client, hostKey, err := p.handshake(ctx, p.countedConn(srv, tcpConn), at, ServerKey(srv))
+		if err == nil || len(targets) == 1 || ctx.Err() != nil || !transientDialError(err) {
+			return client, hostKey, err
+		}
+		logger.Log("ssh", "trying the other addresses of %s", srv.Host)
+		targets = slices.Delete(targets, i, i+1)
+	}
+}
+
+// dialViaJumpHosts connects to the first jump host directly, then tunnels
+// through each following hop to reach the target, like `ssh -J`. When
+// another instance shares its connection to the first jump host, that is
+// used instead of dialing it. The jump connections are closed once the
+// target connection closes.
+func (p *Pool) dialViaJumpHosts(ctx context.Context, srv config.ServerConfig) (*ssh.Client, ssh.PublicKey, error) {
+	var hops []*ssh.Client
+	closeHops := func() {
+		for i := len(hops) - 1; i >= 0; i-- {
+			p.untrackHop(hops[i])
+			hops[i].Close()
+		}
+	}
+
+	// Everything reached through the first jump host, target last.
+	first := srv.JumpHosts[0]
+	chain := append(append([]config.ServerConfig{}, srv.JumpHosts[1:]...), srv)
+
+	conn, err := p.controlDial(ctx, first, chain[0])
+	if err == nil {
+		logger.Log("ssh", "using shared connection to jump host %s", first.Name)
+	} else {
+		if err != errNotShared {
+			logger.Log("ssh", "shared connection to %s unavailable: %v", first.Name, err)
+		}
+		logger.Log("ssh", "dialing jump host %s", first.Name)
+		client, _, err := p.dialOnce(ctx, first)
+		if err != nil {
+			return nil, nil, fmt.Errorf("jump host %s: %w", first.Name, err)
+		}
+		hops = append(hops, client)
+		p.trackHop(first, client)
+		if conn, err = tunnel(ctx, client, chain[0]); err != nil {
+			closeHops()
+			return nil, nil, err
+		}
+	}
+
+	for i, hop := range chain[:len(chain)-1] {
+		c, _, err := p.handshake(ctx, conn, hop, ServerKey(hop))
+		if err != nil {
+			closeHops()
+			return nil, nil, err
+		}
+		hops = append(hops, c)
+		p.trackHop(hop, c)
+		if conn, err = tunnel(ctx, c, chain[i+1]); err != nil {
+			closeHops()
+			return nil, nil, err
+		}
+	}
+
+	c, hostKey, err := p.handshake(ctx, p.countedConn(srv, conn), srv, ServerKey(srv))
+	if err != nil {
+		closeHops()
+		return nil, nil, err
+	}
+	// Tear down the jump chain once the target connection goes away.
+	go func() {
+		c.Wait()
+		closeHops()
+	}()
+	return c, hostKey, nil
+}
+
+// dialViaChain reaches srv through its proxy_chain. Unlike proxy_jump hops,
+// each hop is a pooled client, itself reached through the hops before it:
+// servers behind the same bastions share one connection to each, and a hop
+// that is also a configured server reuses the connection to it. The last
+// hop is held while srv's connection is open, so the idle timeout leaves it.
+func (p *Pool) dialViaChain(ctx context.Context, srv config.ServerConfig) (*ssh.Client, ssh.PublicKey, error) {
+	last := len(srv.JumpHosts) - 1
+	hop := srv.JumpHosts[last]
+	hop.JumpHosts = srv.JumpHosts[:last]
+	hop.PooledHops = last > 0
+
+	via, err := p.GetClient(ctx, hop)
+	if err != nil {
+		return nil, nil, fmt.Errorf("proxy chain hop %s: %w", hop.Name, err)
+	}
+	conn, err := tunnel(ctx, via, srv)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, hostKey, err := p.handshake(ctx, p.countedConn(srv, conn), srv, ServerKey(srv))
+	if err != nil {
+		return nil, nil, err
+	}
+	release := p.Hold(hop)
+	go func() {
+		c.Wait()
+		release()
+	}()
+	return c, hostKey, nil
+}
+
+// tunnel opens a TCP connection to srv's SSH port through an existing
+// connection.
+func tunnel(ctx context.Context, via *ssh.Client, srv config.ServerConfig) (net.Conn, error) {
+	addr := fmt.Sprintf("%s:%d", srv.Host, srv.Port)
+	logger.Log("ssh", "tunneling to %s via %s", addr, via.RemoteAddr())
+	conn, err := via.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		logger.Log("ssh", "tunnel to %s failed: %v", addr, err)
+		return nil, fmt.Errorf("tunnel to %s: %w", addr, err)
 	}
+	return conn, nil
+}
 
+// handshake runs the SSH handshake for srv over an established connection.
+// The server's banner is kept under key, the pool key of the server dialed:
+// srv may have one of its hosts in place of the configured ones. conn is
+// closed on failure.
+func (p *Pool) handshake(ctx context.Context, conn net.Conn, srv config.ServerConfig, key string) (*ssh.Client, ssh.PublicKey, error) {
 	addr := fmt.Sprintf("%s:%d", srv.Host, srv.Port)
 
-	// Dial TCP with the context so callers can cancel/timeout the attempt.
-	logger.Log("ssh", "TCP dialing %s ...", addr)
-	d := net.Dialer{Timeout: 10 * time.Second}
-	tcpConn, err := d.DialContext(ctx, "tcp", addr)
+	logger.Log("ssh", "buildAuth method=%s", srv.Auth.Method)
+	authMethods, agentConn, err := p.buildAuth(ctx, srv)
 	if err != nil {
+		conn.Close()
+		logger.Log("ssh", "buildAuth failed: %v", err)
+		return nil, nil, fmt.Errorf("auth setup for %s: %w", srv.Host, err)
+	}
+	logger.Log("ssh", "buildAuth succeeded")
+
+	check, err := loadKnownHosts(p.knownHosts)
+	if err != nil {
+		conn.Close()
 		if agentConn != nil {
 			agentConn.Close()
 		}
-		logger.Log("ssh", "TCP dial failed %s: %v", addr, err)
-		return nil, fmt.Errorf("TCP dial %s: %w", addr, err)
+		return nil, nil, err
 	}
-	logger.Log("ssh", "TCP connected to %s", addr)
 
-	// Close the TCP connection if the context is cancelled during the SSH
+	var hostKey ssh.PublicKey
+	var banner strings.Builder
+	cfg := &ssh.ClientConfig{
+		User:              srv.User,
+		Auth:              authMethods,
+		HostKeyCallback:   p.hostKeyCallback(srv, check, &hostKey),
+		HostKeyAlgorithms: knownHostAlgorithms(check, addr),
+		BannerCallback: func(msg string) error {
+			banner.WriteString(msg)
+			return nil
+		},
+	}
+
+	// Close the connection if the context is cancelled during the SSH
 	// handshake. ssh.NewClientConn does not accept a context, so this is the
 	// only way to interrupt it.
 	handshakeDone := make(chan struct{})
 	go func() {
 		select {
 		case <-ctx.Done():
-			logger.Log("ssh", "context cancelled during handshake, closing TCP to %s", addr)
-			tcpConn.Close()
+			logger.Log("ssh", "context cancelled during handshake, closing connection to %s", addr)
+			conn.Close()
 		case <-handshakeDone:
 		}
 	}()
 
 	logger.Log("ssh", "SSH handshake starting with %s ...", addr)
-	sshConn, chans, reqs, err := ssh.NewClientConn(tcpConn, addr, cfg)
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, cfg)
 	close(handshakeDone)
 	if err != nil {
-		tcpConn.Close()
+		conn.Close()
 		if agentConn != nil {
 			agentConn.Close()
 		}
 		logger.Log("ssh", "SSH handshake failed %s: %v", addr, err)
-		return nil, fmt.Errorf("SSH handshake %s: %w", addr, err)
+		return nil, nil, fmt.Errorf("SSH handshake %s: %w", addr, err)
 	}
 	logger.Log("ssh", "SSH handshake succeeded with %s", addr)
 
@@ -178,24 +512,25 @@ func dial(ctx context.Context, srv config.ServerConfig) (*ssh.Client, error) {
 			agentConn.Close()
 		}
 		logger.Log("ssh", "context expired after handshake for %s", addr)
-		return nil, fmt.Errorf("SSH connect %s: %w", addr, ctx.Err())
+		return nil, nil, fmt.Errorf("SSH connect %s: %w", addr, ctx.Err())
 	}
 
-	return ssh.NewClient(sshConn, chans, reqs), nil
+	p.mu.Lock()
+	p.banners[key] = banner.String()
+	p.mu.Unlock()
+	return ssh.NewClient(sshConn, chans, reqs), hostKey, nil
 }
 
 // buildAuth returns auth methods and, if agent auth is used, the agent socket
-// connection (caller must close it on dial failure).
-func buildAuth(auth config.AuthConfig) ([]ssh.AuthMethod, net.Conn, error) {
+// connection (caller must close it on dial failure). An encrypted key that
+// hasn't been unlocked yields a *PassphraseError.
+func (p *Pool) buildAuth(ctx context.Context, srv config.ServerConfig) ([]ssh.AuthMethod, net.Conn, error) {
+	auth := srv.Auth
 	switch auth.Method {
 	case "key":
-		keyData, err := os.ReadFile(auth.KeyPath)
+		signer, err := p.keySigner(srv)
 		if err != nil {
-			return nil, nil, fmt.Errorf("reading key %s: %w", auth.KeyPath, err)
-		}
-		signer, err := ssh.ParsePrivateKey(keyData)
-		if err != nil {
-			return nil, nil, fmt.Errorf("parsing key %s: %w", auth.KeyPath, err)
+			return nil, nil, err
 		}
 		return []ssh.AuthMethod{ssh.PublicKeys(signer)}, nil, nil
 
@@ -213,25 +548,92 @@ func buildAuth(auth config.AuthConfig) ([]ssh.AuthMethod, net.Conn, error) {
 		agentClient := agent.NewClient(conn)
 		return []ssh.AuthMethod{ssh.PublicKeysCallback(agentClient.Signers)}, conn, nil
 
+	case "keyboard-interactive":
+		// Servers behind MFA often want a key first, then the code.
+		var methods []ssh.AuthMethod
+		if auth.KeyPath != "" {
+			signer, err := p.keySigner(srv)
+			if err != nil {
+				return nil, nil, err
+			}
+			methods = append(methods, ssh.PublicKeys(signer))
+		}
+		methods = append(methods, ssh.KeyboardInteractive(p.keyboardInteractive(ctx, srv)))
+		return methods, nil, nil
+
+	case "gssapi":
+		return []ssh.AuthMethod{ssh.GSSAPIWithMICAuthMethod(&gssapiClient{}, srv.Host)}, nil, nil
+
 	case "password":
-		return nil, nil, fmt.Errorf("password auth requires interactive input; use key or agent instead")
+		if srv.PasswordCommand == "" {
+			return nil, nil, fmt.Errorf("password auth requires interactive input; set password_command or use key or agent instead")
+		}
+		return []ssh.AuthMethod{ssh.PasswordCallback(func() (string, error) {
+			return keychain.CommandPassword(ctx, srv.PasswordCommand)
+		})}, nil, nil
 
 	default:
 		return nil, nil, fmt.Errorf("unknown auth method: %s", auth.Method)
 	}
 }
 
-// CloseAll closes all cached SSH connections and clears stored sudo passwords.
+// forwardAgent relays the agent channels the server opens on c to the local
+// agent at SSH_AUTH_SOCK. Sessions still have to ask for them (newSession).
+func forwardAgent(c *ssh.Client) error {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return fmt.Errorf("agent forwarding: SSH_AUTH_SOCK not set")
+	}
+	if err := agent.ForwardToRemote(c, sock); err != nil {
+		return fmt.Errorf("agent forwarding: %w", err)
+	}
+	return nil
+}
+
+// CloseAll closes all cached SSH connections and jump host connections,
+// clears stored sudo state and unlocked keys, and stops serving the control
+// socket. It is meant for shutdown: the idle timeout stays off afterwards.
+// KillAll drops the connections and keeps the pool usable.
 func (p *Pool) CloseAll() {
 	logger.Log("ssh", "CloseAll start")
+	var closed []string
+	defer func() {
+		for _, key := range closed {
+			p.notify(key, StateDisconnected, nil)
+		}
+	}()
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	for key, c := range p.clients {
-		c.Close()
 		delete(p.clients, key)
+		c.Close()
+		closed = append(closed, key)
+	}
+	p.closeHops()
+	for key := range p.alive {
+		delete(p.alive, key)
+	}
+	clear(p.latency)
+	clear(p.lost)
+	for key := range p.lastUsed {
+		delete(p.lastUsed, key)
+	}
+	if p.idleStop != nil {
+		close(p.idleStop)
+		p.idleStop = nil
 	}
 	for key := range p.sudoPasswd {
 		delete(p.sudoPasswd, key)
 	}
+	for key := range p.sudoNoPass {
+		delete(p.sudoNoPass, key)
+	}
+	for path := range p.signers {
+		delete(p.signers, path)
+	}
+	if p.controlLn != nil {
+		p.controlLn.Close()
+		p.controlLn = nil
+	}
 	logger.Log("ssh", "CloseAll done")
 }
@@ -0,0 +1,37 @@
+package ssh
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// decompressors are the commands that write a compressed log to stdout, by
+// file extension, for rotated logs such as app.log.3.gz.
+var decompressors = map[string]string{
+	".gz":  "zcat",
+	".bz2": "bzcat",
+	".xz":  "xzcat",
+}
+
+// Decompressor returns the command that decompresses a remote file to
+// stdout, or "" if the file isn't a compressed log. Compressed tar archives
+// are not logs.
+func Decompressor(path string) string {
+	name := strings.ToLower(filepath.Base(path))
+	ext := filepath.Ext(name)
+	if strings.HasSuffix(strings.TrimSuffix(name, ext), ".tar") {
+		return ""
+	}
+	return decompressors[ext]
+}
+
+// compressedReadScript returns a script printing "LINES:<n>" and then the
+// last lines of the compressed file $1, decompressing it once. Errors of
+// the decompressor, such as a truncated archive, end up after the lines
+// that could be read.
+func compressedReadScript(cat string, lines int) string {
+	return fmt.Sprintf(`%s -- "$1" 2>&1 | awk -v n=%d '{ l[NR %% n] = $0 }
+END { print "LINES:" NR; for (i = (NR > n ? NR - n + 1 : 1); i <= NR; i++) print l[i %% n] }'`,
+		cat, max(lines, 1))
+}
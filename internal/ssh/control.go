@@ -0,0 +1,270 @@
+package ssh
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/logger"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Connection sharing: the first instance with a control socket configured
+// listens on it. Later instances ask it for TCP tunnels through the jump
+// host connections it already holds, so a bastion is dialed (and
+// authenticated against) once per machine rather than once per instance.
+//
+// The protocol is one request line, "DIAL <server key> <host:port>", answered
+// with "OK" or "ERR <reason>". After OK the socket carries the raw tunnel.
+
+// errNotShared means no other instance can provide the connection.
+var errNotShared = errors.New("connection not shared")
+
+// errNoPeerCred means the system can't tell who is at the other end of a
+// unix socket.
+var errNoPeerCred = errors.New("peer credentials not available")
+
+// ShareConnections enables connection sharing over a unix socket at path.
+// If another instance already owns the socket, this pool becomes a user of
+// it and owner is false; otherwise this pool serves it until CloseAll.
+//
+// The socket's directory must be private to the user (0700): the socket is
+// created with the default umask, and only the directory keeps other users
+// from reaching it before its mode is set.
+func (p *Pool) ShareConnections(path string) (owner bool, err error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return false, fmt.Errorf("control socket dir: %w", err)
+	}
+	if err := checkControlDir(dir); err != nil {
+		return false, fmt.Errorf("control socket dir %s: %w", dir, err)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		c, dialErr := net.DialTimeout("unix", path, time.Second)
+		if dialErr == nil {
+			c.Close()
+			p.mu.Lock()
+			p.controlPath = path
+			p.mu.Unlock()
+			logger.Log("ssh", "control socket %s owned by another instance", path)
+			return false, nil
+		}
+		// Left behind by an instance that didn't exit cleanly, if nothing
+		// listens on it; an owner slow to answer keeps its socket.
+		info, statErr := os.Lstat(path)
+		if !errors.Is(dialErr, syscall.ECONNREFUSED) || statErr != nil || info.Mode().Type() != os.ModeSocket {
+			return false, fmt.Errorf("control socket %s: %w", path, err)
+		}
+		logger.Log("ssh", "removing stale control socket %s", path)
+		os.Remove(path)
+		if ln, err = net.Listen("unix", path); err != nil {
+			return false, fmt.Errorf("control socket %s: %w", path, err)
+		}
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return false, fmt.Errorf("control socket %s: %w", path, err)
+	}
+
+	p.mu.Lock()
+	p.controlPath = path
+	p.controlLn = ln
+	p.mu.Unlock()
+	logger.Log("ssh", "serving control socket %s", path)
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go p.serveControlConn(conn)
+		}
+	}()
+	return true, nil
+}
+
+// serveControlConn handles one tunnel request from another instance. Only
+// the same user's instances are served, and only through jump host
+// connections: the servers themselves are not a way into their networks.
+func (p *Pool) serveControlConn(conn net.Conn) {
+	if err := checkPeer(conn); err != nil {
+		logger.Log("ssh", "control: refused a connection: %v", err)
+		conn.Close()
+		return
+	}
+	r := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	line, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	fields := strings.Fields(line)
+	if len(fields) != 3 || fields[0] != "DIAL" {
+		fmt.Fprintf(conn, "ERR bad request\n")
+		conn.Close()
+		return
+	}
+	key, addr := fields[1], fields[2]
+
+	p.mu.Lock()
+	via, ok := p.hops[key]
+	p.mu.Unlock()
+	if !ok {
+		fmt.Fprintf(conn, "ERR not connected to %s\n", key)
+		conn.Close()
+		return
+	}
+
+	remote, err := via.Dial("tcp", addr)
+	if err != nil {
+		fmt.Fprintf(conn, "ERR %v\n", err)
+		conn.Close()
+		return
+	}
+	logger.Log("ssh", "control: tunneling to %s via %s for another instance", addr, key)
+	fmt.Fprintf(conn, "OK\n")
+	relay(&bufferedConn{Conn: conn, r: r}, remote)
+}
+
+// controlDial asks the instance owning the control socket for a tunnel to
+// dest through its connection to via. It returns errNotShared when sharing
+// is off, this pool owns the socket, or the owner isn't connected to via.
+func (p *Pool) controlDial(ctx context.Context, via, dest config.ServerConfig) (net.Conn, error) {
+	p.mu.Lock()
+	path, owner := p.controlPath, p.controlLn != nil
+	p.mu.Unlock()
+	if path == "" || owner {
+		return nil, errNotShared
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNotShared, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	addr := fmt.Sprintf("%s:%d", dest.Host, dest.Port)
+	if _, err := fmt.Fprintf(conn, "DIAL %s %s\n", ServerKey(via), addr); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %v", errNotShared, err)
+	}
+	r := bufio.NewReader(conn)
+	reply, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %v", errNotShared, err)
+	}
+	reply = strings.TrimSpace(reply)
+	if reply != "OK" {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %s", errNotShared, strings.TrimPrefix(reply, "ERR "))
+	}
+	conn.SetDeadline(time.Time{})
+	return sharedConn{&bufferedConn{Conn: conn, r: r}}, nil
+}
+
+// checkPeer refuses a control connection from another user, where the
+// system can tell who is at the other end of a unix socket.
+func checkPeer(conn net.Conn) error {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return fmt.Errorf("not a unix socket connection")
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	uid, err := peerUID(raw)
+	if errors.Is(err, errNoPeerCred) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading peer credentials: %w", err)
+	}
+	if uid != os.Getuid() {
+		return fmt.Errorf("peer runs as uid %d", uid)
+	}
+	return nil
+}
+
+// trackHop records a jump host connection so it can be shared.
+func (p *Pool) trackHop(srv config.ServerConfig, c *ssh.Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.hops[ServerKey(srv)] = c
+}
+
+// untrackHop forgets a jump host connection that is being closed.
+func (p *Pool) untrackHop(c *ssh.Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, hop := range p.hops {
+		if hop == c {
+			delete(p.hops, key)
+		}
+	}
+}
+
+// closeHops closes and forgets every jump host connection. p.mu must be
+// held.
+func (p *Pool) closeHops() {
+	for key, hop := range p.hops {
+		delete(p.hops, key)
+		hop.Close()
+	}
+}
+
+// bufferedConn is a net.Conn whose reads go through a bufio.Reader that may
+// already hold data read past the request/reply line.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// sharedConn is a tunnel received over the control socket. Like tunnels
+// opened with ssh.Client.Dial it reports zero TCP addresses; known_hosts
+// checks can't make sense of a unix socket address.
+type sharedConn struct {
+	*bufferedConn
+}
+
+func (sharedConn) LocalAddr() net.Addr  { return &net.TCPAddr{} }
+func (sharedConn) RemoteAddr() net.Addr { return &net.TCPAddr{} }
+
+// relay copies between a and b until either side closes.
+func relay(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+	a.Close()
+	b.Close()
+}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkControlDir checks that the control socket's directory is a
+// directory of this user that other users can't enter.
+func checkControlDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory")
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("owned by uid %d, not you", st.Uid)
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		return fmt.Errorf("mode is %#o; it must be 0700", perm)
+	}
+	return nil
+}
@@ -1,12 +1,16 @@
 package ssh
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -40,9 +44,57 @@ func (pw *progressWriter) Write(p []byte) (int, error) {
 	return n, err
 }
 
+// errDownloadLimit stops a download that reached opts.DownloadLimit.
+var errDownloadLimit = errors.New("download size limit reached")
+
+// limitWriter passes on at most n more bytes, then fails with
+// errDownloadLimit.
+type limitWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) <= lw.n {
+		n, err := lw.w.Write(p)
+		lw.n -= int64(n)
+		return n, err
+	}
+	n, err := lw.w.Write(p[:lw.n])
+	lw.n -= int64(n)
+	if err == nil {
+		err = errDownloadLimit
+	}
+	return n, err
+}
+
+// downloadWriter wraps a download's local file with the size limit and
+// progress reporting asked for.
+func downloadWriter(ctx context.Context, f io.Writer, opts CommandOpts, progressCh chan<- int64) io.Writer {
+	dst := f
+	if opts.DownloadLimit > 0 {
+		dst = &limitWriter{w: dst, n: opts.DownloadLimit}
+	}
+	if progressCh != nil {
+		dst = &progressWriter{w: dst, ch: progressCh, ctx: ctx}
+	}
+	return dst
+}
+
 // CommandOpts holds optional parameters for remote command execution.
 type CommandOpts struct {
-	SudoPassword string
+	Sudo         bool   // run the command through sudo
+	SudoPassword string // written to `sudo -S`; empty means NOPASSWD (`sudo -n`)
+	Escalation   string // command template used instead of sudo; %cmd% is replaced by the quoted command
+	SudoUser     string // runs sudo as this user (sudo -u) instead of root
+	Setup        string // wraps every command to switch user context first; see withSetup
+	Compress     bool   // gzip command output on the server (when gzip is installed there)
+	ForwardAgent bool   // make the local SSH agent available to the command
+	Shell        string // login shell on the server, see ShellCsh; "" for a POSIX sh
+
+	// DownloadLimit stops a download after this many bytes, so a file
+	// growing faster than it downloads can't keep it going; 0 for none.
+	DownloadLimit int64
 }
 
 // FileInfo holds metadata about a remote file.
@@ -53,34 +105,54 @@ type FileInfo struct {
 	IsDir   bool
 }
 
-// ListFiles returns files in the given directory, optionally filtered by glob patterns.
-func ListFiles(client *gossh.Client, dir string, patterns []string, opts CommandOpts) ([]FileInfo, error) {
+// PartialListError reports a listing that came back incomplete because ls
+// was denied access to some entries. ListFiles returns the entries it
+// could read along with it.
+type PartialListError struct {
+	Dir    string
+	Denied int   // entries ls could not read
+	Err    error // the failure of ls
+}
+
+func (e *PartialListError) Error() string {
+	return fmt.Sprintf("listing %s: %d entries unreadable: %v", e.Dir, e.Denied, e.Err)
+}
+
+func (e *PartialListError) Unwrap() error {
+	return e.Err
+}
+
+// ListFiles returns files in the given directory, optionally filtered by
+// glob patterns. If ls was denied access to some entries but listed
+// others, those are returned with a *PartialListError.
+func ListFiles(ctx context.Context, client *gossh.Client, dir string, patterns []string, opts CommandOpts) ([]FileInfo, error) {
 	cmd := fmt.Sprintf("ls -la --time-style=full-iso %s", shellescape.Quote(dir))
-	output, err := runCommand(client, cmd, opts)
+	output, err := runCommand(ctx, client, cmd, opts)
+	files := parseLsOutput(output)
+	var partial error
 	if err != nil {
-		return nil, fmt.Errorf("listing %s: %w", dir, err)
+		denied := strings.Count(err.Error(), "Permission denied")
+		if denied == 0 || len(files) == 0 || ctx.Err() != nil {
+			return nil, fmt.Errorf("listing %s: %w", dir, err)
+		}
+		logger.Log("ssh", "listing %s: %d entries denied, keeping %d", dir, denied, len(files))
+		partial = &PartialListError{Dir: dir, Denied: denied, Err: err}
 	}
 
-	files := parseLsOutput(output)
-
 	if len(patterns) > 0 {
-		files = filterByPatterns(files, patterns)
+		files = FilterByPatterns(files, patterns)
 	}
 
-	// Sort by filename
-	sort.Slice(files, func(i, j int) bool {
-		return files[i].Name < files[j].Name
-	})
-
-	return files, nil
+	sortListing(files)
+	return files, partial
 }
 
 // CountLines returns the total number of lines in a remote file via `wc -l`.
-func CountLines(client *gossh.Client, path string, opts CommandOpts) (int, error) {
+func CountLines(ctx context.Context, client *gossh.Client, path string, opts CommandOpts) (int, error) {
 	// Use `wc -l file` instead of `wc -l < file` to avoid stdin redirection
 	// conflicting with sudo -S which reads the password from stdin.
 	cmd := fmt.Sprintf("wc -l %s", shellescape.Quote(path))
-	output, err := runCommand(client, cmd, opts)
+	output, err := runCommand(ctx, client, cmd, opts)
 	if err != nil {
 		return 0, fmt.Errorf("counting lines %s: %w", path, err)
 	}
@@ -97,9 +169,24 @@ func CountLines(client *gossh.Client, path string, opts CommandOpts) (int, error
 }
 
 // ReadFileContent reads the last N lines of a remote file.
-func ReadFileContent(client *gossh.Client, path string, lines int, opts CommandOpts) (string, error) {
+func ReadFileContent(ctx context.Context, client *gossh.Client, path string, lines int, opts CommandOpts) (string, error) {
 	cmd := fmt.Sprintf("tail -n %d %s", lines, shellescape.Quote(path))
-	output, err := runCommand(client, cmd, opts)
+	output, err := runCommand(ctx, client, cmd, opts)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", path, err)
+	}
+	return output, nil
+}
+
+// ReadFileLines reads lines from through to of a remote file, decompressing
+// compressed files (see Decompressor) on the server.
+func ReadFileLines(ctx context.Context, client *gossh.Client, path string, from, to int, opts CommandOpts) (string, error) {
+	script := fmt.Sprintf(`sed -n '%d,%dp;%dq' "$1"`, from, to, to)
+	if cat := Decompressor(path); cat != "" {
+		script = fmt.Sprintf(`%s -- "$1" | sed -n '%d,%dp;%dq'`, cat, from, to, to)
+	}
+	cmd := fmt.Sprintf("sh -c %s _ %s", shellescape.Quote(script), shellescape.Quote(path))
+	output, err := runCommand(ctx, client, cmd, opts)
 	if err != nil {
 		return "", fmt.Errorf("reading %s: %w", path, err)
 	}
@@ -108,21 +195,36 @@ func ReadFileContent(client *gossh.Client, path string, lines int, opts CommandO
 
 // CountAndReadFileContent counts total lines and reads the last N lines in a
 // single command. This avoids a second sudo authentication round when sudo is
-// required, significantly reducing latency.
-func CountAndReadFileContent(client *gossh.Client, path string, lines int, opts CommandOpts) (totalLines int, content string, err error) {
-	script := fmt.Sprintf(
-		`lines=$(wc -l < "$1" 2>/dev/null); echo "LINES:${lines:-0}"; tail -n %d "$1"`,
-		lines)
-	cmd := fmt.Sprintf("sh -c %s _ %s", shellescape.Quote(script), shellescape.Quote(path))
-	output, err := runCommand(client, cmd, opts)
+// required, significantly reducing latency. Compressed files (see
+// Decompressor) are decompressed on the server.
+func CountAndReadFileContent(ctx context.Context, client *gossh.Client, path string, lines int, opts CommandOpts) (totalLines int, content string, err error) {
+	cmd := fmt.Sprintf("sh -c %s _ %s", shellescape.Quote(countAndReadScript(path, lines)), shellescape.Quote(path))
+	output, err := runCommand(ctx, client, cmd, opts)
 	if err != nil {
 		return 0, "", fmt.Errorf("reading %s: %w", path, err)
 	}
+	totalLines, content = parseCountedContent(output)
+	return totalLines, content, nil
+}
+
+// countAndReadScript is the sh script CountAndReadFileContent runs with the
+// file as $1: it prints "LINES:<count>", then the file's last lines.
+func countAndReadScript(path string, lines int) string {
+	if cat := Decompressor(path); cat != "" {
+		return compressedReadScript(cat, lines)
+	}
+	return fmt.Sprintf(
+		`lines=$(wc -l < "$1" 2>/dev/null); echo "LINES:${lines:-0}"; tail -n %d "$1"`,
+		lines)
+}
 
+// parseCountedContent splits the output of countAndReadScript into the
+// line count and the content.
+func parseCountedContent(output string) (totalLines int, content string) {
 	// First line is "LINES:  123", rest is file content
 	idx := strings.Index(output, "\n")
 	if idx == -1 {
-		return 0, output, nil
+		return 0, output
 	}
 
 	header := output[:idx]
@@ -134,14 +236,13 @@ func CountAndReadFileContent(client *gossh.Client, path string, lines int, opts
 			totalLines = n
 		}
 	}
-
-	return totalLines, content, nil
+	return totalLines, content
 }
 
 // StatFile returns metadata for a single remote file.
-func StatFile(client *gossh.Client, path string, opts CommandOpts) (*FileInfo, error) {
+func StatFile(ctx context.Context, client *gossh.Client, path string, opts CommandOpts) (*FileInfo, error) {
 	cmd := fmt.Sprintf("stat --format='%%n %%s %%Y %%F' %s", shellescape.Quote(path))
-	output, err := runCommand(client, cmd, opts)
+	output, err := runCommand(ctx, client, cmd, opts)
 	if err != nil {
 		return nil, fmt.Errorf("stat %s: %w", path, err)
 	}
@@ -162,6 +263,24 @@ func StatFile(client *gossh.Client, path string, opts CommandOpts) (*FileInfo, e
 	}, nil
 }
 
+// TruncateFile empties a remote file with truncate -s 0. The file itself
+// stays, so processes writing it carry on into the empty file.
+func TruncateFile(ctx context.Context, client *gossh.Client, path string, opts CommandOpts) error {
+	if _, err := runCommand(ctx, client, "truncate -s 0 -- "+shellescape.Quote(path), opts); err != nil {
+		return fmt.Errorf("truncating %s: %w", path, err)
+	}
+	return nil
+}
+
+// DeleteFile removes a remote file with rm. A process still writing it
+// keeps its disk space until it closes the file.
+func DeleteFile(ctx context.Context, client *gossh.Client, path string, opts CommandOpts) error {
+	if _, err := runCommand(ctx, client, "rm -- "+shellescape.Quote(path), opts); err != nil {
+		return fmt.Errorf("deleting %s: %w", path, err)
+	}
+	return nil
+}
+
 // DownloadFile streams a remote file to a local path via cat over SSH.
 // If ctx is non-nil, the download can be cancelled. If progressCh is non-nil,
 // cumulative bytes written are reported through it and the channel is closed on return.
@@ -177,13 +296,16 @@ func DownloadFile(client *gossh.Client, remotePath, localPath string, opts Comma
 		return fmt.Errorf("creating local directory: %w", err)
 	}
 
-	sess, err := client.NewSession()
+	sess, err := newSession(ctx, client, opts)
 	if err != nil {
-		return fmt.Errorf("creating session: %w", err)
+		return err
 	}
 	defer sess.Close()
 
 	cmd := fmt.Sprintf("cat %s", shellescape.Quote(remotePath))
+	if opts.Compress {
+		cmd = compressed(cmd)
+	}
 
 	f, err := os.Create(localPath)
 	if err != nil {
@@ -191,24 +313,31 @@ func DownloadFile(client *gossh.Client, remotePath, localPath string, opts Comma
 	}
 	defer f.Close()
 
-	// Build the destination writer, optionally wrapping with progress reporting
-	var dst io.Writer = f
-	if progressCh != nil {
-		dst = &progressWriter{w: f, ch: progressCh, ctx: ctx}
-	}
+	dst := downloadWriter(ctx, f, opts, progressCh)
 
-	copyAndCleanup := func(stdout io.Reader) error {
-		if _, err := io.Copy(dst, stdout); err != nil {
+	// copyAndCleanup reports limited when the download stopped at
+	// opts.DownloadLimit; the remote cat is then killed, not waited for.
+	copyAndCleanup := func(stdout io.Reader) (limited bool, err error) {
+		src, err := maybeGunzip(stdout)
+		if err == nil {
+			_, err = io.Copy(dst, src)
+		}
+		if errors.Is(err, errDownloadLimit) {
+			logger.Log("ssh", "DownloadFile: %s stopped at %d bytes, it grew during the download", remotePath, opts.DownloadLimit)
+			sess.Signal(gossh.SIGKILL)
+			sess.Close()
+			return true, nil
+		}
+		if err != nil {
 			// On cancel/error, remove partial file
 			f.Close()
 			os.Remove(localPath)
-			return fmt.Errorf("downloading file: %w", err)
+			return false, fmt.Errorf("downloading file: %w", err)
 		}
-		return nil
+		return false, nil
 	}
 
-	if opts.SudoPassword != "" {
-		sudoCmd := fmt.Sprintf("sudo -S %s", cmd)
+	if opts.Sudo {
 		logger.Log("ssh", "DownloadFile (sudo): %s → %s", remotePath, localPath)
 
 		var stderr bytes.Buffer
@@ -219,115 +348,344 @@ func DownloadFile(client *gossh.Client, remotePath, localPath string, opts Comma
 			return fmt.Errorf("stdout pipe: %w", err)
 		}
 
-		stdin, err := sess.StdinPipe()
-		if err != nil {
-			return fmt.Errorf("stdin pipe: %w", err)
-		}
-
-		if err := sess.Start(sudoCmd); err != nil {
-			return fmt.Errorf("starting %q: %w", sudoCmd, err)
-		}
-
-		if _, err := fmt.Fprintf(stdin, "%s\n", opts.SudoPassword); err != nil {
-			return fmt.Errorf("writing sudo password: %w", err)
+		if err := startSudo(sess.Session, cmd, opts); err != nil {
+			return err
 		}
-		stdin.Close()
 
-		if err := copyAndCleanup(stdout); err != nil {
+		if limited, err := copyAndCleanup(stdout); limited || err != nil {
 			return err
 		}
 
-		if err := sess.Wait(); err != nil {
-			stderrStr := stderr.String()
-			if strings.Contains(stderrStr, "Sorry, try again") || strings.Contains(stderrStr, "incorrect password") {
+		err = sess.Wait()
+		stderrStr, status := compressedStatus(stderr.String())
+		if err != nil {
+			if isSudoAuthFailure(stderrStr) {
 				return fmt.Errorf("sudo authentication failed")
 			}
 			return fmt.Errorf("running %q: %w: %s", cmd, err, stderrStr)
 		}
+		if status != 0 {
+			return fmt.Errorf("running %q: exit status %d: %s", cmd, status, stderrStr)
+		}
 		return nil
 	}
 
 	logger.Log("ssh", "DownloadFile: %s → %s", remotePath, localPath)
 
+	var stderr bytes.Buffer
+	sess.Stderr = &stderr
+
 	stdout, err := sess.StdoutPipe()
 	if err != nil {
 		return fmt.Errorf("stdout pipe: %w", err)
 	}
 
-	if err := sess.Start(cmd); err != nil {
+	if err := sess.Start(commandLine(cmd, opts)); err != nil {
 		return fmt.Errorf("starting %q: %w", cmd, err)
 	}
 
-	if err := copyAndCleanup(stdout); err != nil {
+	if limited, err := copyAndCleanup(stdout); limited || err != nil {
 		return err
 	}
 
-	if err := sess.Wait(); err != nil {
-		return fmt.Errorf("running %q: %w", cmd, err)
+	err = sess.Wait()
+	stderrStr, status := compressedStatus(stderr.String())
+	if err != nil {
+		return fmt.Errorf("running %q: %w: %s", cmd, err, stderrStr)
+	}
+	if status != 0 {
+		return fmt.Errorf("running %q: exit status %d: %s", cmd, status, stderrStr)
 	}
 
 	return nil
 }
 
-func runCommand(client *gossh.Client, cmd string, opts CommandOpts) (string, error) {
-	sess, err := client.NewSession()
+// runCommand runs cmd in a new session and returns its output. If ctx is
+// cancelled or its deadline passes, the remote process is killed and the
+// session closed, so a hung command (e.g. `ls` on a dying NFS mount) can't
+// block the caller until the SSH connection itself dies. When the command
+// fails, whatever it printed is returned along with the error.
+func runCommand(ctx context.Context, client *gossh.Client, cmd string, opts CommandOpts) (string, error) {
+	sess, err := newSession(ctx, client, opts)
 	if err != nil {
-		return "", fmt.Errorf("creating session: %w", err)
+		return "", err
 	}
 	defer sess.Close()
 
-	if opts.SudoPassword != "" {
-		sudoCmd := fmt.Sprintf("sudo -S %s", cmd)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			logger.Log("ssh", "runCommand cancelled: %s", cmd)
+			sess.Signal(gossh.SIGKILL)
+			sess.Close()
+		case <-done:
+		}
+	}()
+
+	if opts.Compress {
+		return runCompressed(ctx, sess.Session, cmd, opts)
+	}
+
+	if opts.Sudo {
 		logger.Log("ssh", "runCommand (sudo): %s", cmd)
 
 		var stdout, stderr bytes.Buffer
 		sess.Stdout = &stdout
 		sess.Stderr = &stderr
 
-		stdin, err := sess.StdinPipe()
-		if err != nil {
-			return "", fmt.Errorf("stdin pipe: %w", err)
-		}
-
-		if err := sess.Start(sudoCmd); err != nil {
-			return "", fmt.Errorf("starting %q: %w", sudoCmd, err)
-		}
-
-		_, err = fmt.Fprintf(stdin, "%s\n", opts.SudoPassword)
-		if err != nil {
-			return "", fmt.Errorf("writing sudo password: %w", err)
+		if err := startSudo(sess.Session, cmd, opts); err != nil {
+			return "", err
 		}
-		stdin.Close()
 
 		err = sess.Wait()
 		stderrStr := stderr.String()
 		if err != nil {
-			if strings.Contains(stderrStr, "Sorry, try again") || strings.Contains(stderrStr, "incorrect password") {
+			if ctx.Err() != nil {
+				return "", fmt.Errorf("running %q: %w", cmd, ctx.Err())
+			}
+			if isSudoAuthFailure(stderrStr) {
 				return "", fmt.Errorf("sudo authentication failed")
 			}
-			return "", fmt.Errorf("running %q: %w: %s", cmd, err, stderrStr)
+			return stdout.String(), fmt.Errorf("running %q: %w: %s", cmd, err, stderrStr)
 		}
 		return stdout.String(), nil
 	}
 
-	out, err := sess.CombinedOutput(cmd)
+	out, err := sess.CombinedOutput(commandLine(cmd, opts))
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("running %q: %w", cmd, ctx.Err())
+		}
+		return string(out), fmt.Errorf("running %q: %w: %s", cmd, err, string(out))
+	}
+	return string(out), nil
+}
+
+// runCompressed runs cmd with its output gzipped on the server and returns
+// the decompressed output. stdout and stderr are kept apart so error
+// messages can't corrupt the compressed stream.
+func runCompressed(ctx context.Context, sess *gossh.Session, cmd string, opts CommandOpts) (string, error) {
+	wrapped := compressed(cmd)
+	logger.Log("ssh", "runCommand (compressed): %s", cmd)
+
+	var stdout, stderr bytes.Buffer
+	sess.Stdout = &stdout
+	sess.Stderr = &stderr
+
+	var err error
+	if opts.Sudo {
+		err = startSudo(sess, wrapped, opts)
+	} else {
+		err = sess.Start(commandLine(wrapped, opts))
+	}
+	if err == nil {
+		err = sess.Wait()
+	}
+	stderrStr, status := compressedStatus(stderr.String())
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("running %q: %w", cmd, ctx.Err())
+		}
+		if opts.Sudo && isSudoAuthFailure(stderrStr) {
+			return "", fmt.Errorf("sudo authentication failed")
+		}
+		return "", fmt.Errorf("running %q: %w: %s", cmd, err, stderrStr)
+	}
+
+	r, err := maybeGunzip(&stdout)
+	if err != nil {
+		return "", fmt.Errorf("running %q: %w", cmd, err)
+	}
+	out, err := io.ReadAll(r)
 	if err != nil {
-		return "", fmt.Errorf("running %q: %w: %s", cmd, err, string(out))
+		return "", fmt.Errorf("running %q: decompressing output: %w", cmd, err)
+	}
+	if status != 0 {
+		return string(out), fmt.Errorf("running %q: exit status %d: %s%s", cmd, status, out, stderrStr)
 	}
 	return string(out), nil
 }
 
+// compressedExitPrefix starts the line compressed() adds to stderr with the
+// command's own exit status; the pipeline's status is gzip's.
+const compressedExitPrefix = "log-monitor-exit:"
+
+// compressed wraps cmd so its stdout is gzipped on the server. Servers
+// without gzip run cmd as is; readers tell the two apart by the gzip magic.
+func compressed(cmd string) string {
+	script := fmt.Sprintf(
+		`if command -v gzip >/dev/null 2>&1; then { %s; echo "%s$?" >&2; } | gzip -1 -c; else %s; fi`,
+		cmd, compressedExitPrefix, cmd)
+	return fmt.Sprintf("sh -c %s", shellescape.Quote(script))
+}
+
+// compressedStatus strips the exit status line written by compressed()
+// from stderr and returns it; status is 0 if there is none.
+func compressedStatus(stderr string) (rest string, status int) {
+	trimmed := strings.TrimRight(stderr, "\n")
+	idx := strings.LastIndex(trimmed, compressedExitPrefix)
+	if idx < 0 || (idx > 0 && trimmed[idx-1] != '\n') {
+		return stderr, 0
+	}
+	status, err := strconv.Atoi(trimmed[idx+len(compressedExitPrefix):])
+	if err != nil {
+		return stderr, 0
+	}
+	return trimmed[:idx], status
+}
+
+// maybeGunzip returns a reader of r's decompressed content if r starts
+// with a gzip header, and of r as is otherwise.
+func maybeGunzip(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		return br, nil
+	}
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing output: %w", err)
+	}
+	return zr, nil
+}
+
+// startSudo starts cmd under sudo on the session. With a password it runs
+// `sudo -S` and writes the password to stdin; without one it runs `sudo -n`
+// so a missing NOPASSWD rule fails fast instead of waiting for input.
+// A custom escalation template replaces sudo entirely, getting cmd quoted
+// as one word ("pbrun sh -c %cmd%"); the password, if any, is still
+// written to stdin for tools that read it there.
+func startSudo(sess *gossh.Session, cmd string, opts CommandOpts) error {
+	var sudoCmd string
+	switch {
+	case opts.Escalation != "":
+		sudoCmd = strings.ReplaceAll(opts.Escalation, "%cmd%", shellescape.Quote(cmd))
+	case opts.SudoPassword == "":
+		sudoCmd = fmt.Sprintf("sudo -n %s%s", sudoUserArg(opts), cmd)
+	default:
+		sudoCmd = fmt.Sprintf("sudo -S %s%s", sudoUserArg(opts), cmd)
+	}
+
+	sudoCmd = commandLine(sudoCmd, opts)
+	if opts.SudoPassword == "" {
+		if err := sess.Start(sudoCmd); err != nil {
+			return fmt.Errorf("starting %q: %w", sudoCmd, err)
+		}
+		return nil
+	}
+
+	stdin, err := sess.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("stdin pipe: %w", err)
+	}
+	if err := sess.Start(sudoCmd); err != nil {
+		return fmt.Errorf("starting %q: %w", sudoCmd, err)
+	}
+	if _, err := fmt.Fprintf(stdin, "%s\n", opts.SudoPassword); err != nil {
+		return fmt.Errorf("writing sudo password: %w", err)
+	}
+	stdin.Close()
+	return nil
+}
+
+// sudoUserArg returns the sudo option selecting the target user, with a
+// trailing space, or "" to run as root.
+func sudoUserArg(opts CommandOpts) string {
+	if opts.SudoUser == "" {
+		return ""
+	}
+	return "-u " + shellescape.Quote(opts.SudoUser) + " "
+}
+
+// withSetup runs cmd inside the server's setup command, such as
+// "sudo su - app" for logs only that user can read. A setup command with
+// %cmd% gets the quoted command there ("sudo -u app sh -c %cmd%");
+// otherwise cmd is fed to it as a script on stdin, as if typed after it.
+func withSetup(cmd string, opts CommandOpts) string {
+	switch {
+	case opts.Setup == "":
+		return cmd
+	case strings.Contains(opts.Setup, "%cmd%"):
+		return strings.ReplaceAll(opts.Setup, "%cmd%", shellescape.Quote(cmd))
+	default:
+		return fmt.Sprintf("printf '%%s\\n' %s | %s", shellescape.Quote(cmd), opts.Setup)
+	}
+}
+
+// isSudoAuthFailure reports whether sudo's stderr indicates a rejected or
+// missing password.
+func isSudoAuthFailure(stderr string) bool {
+	return strings.Contains(stderr, "Sorry, try again") ||
+		strings.Contains(stderr, "incorrect password") ||
+		strings.Contains(stderr, "a password is required")
+}
+
+// escalationProbeTimeout bounds the NOPASSWD probe through an escalation
+// template that has no -n flag.
+const escalationProbeTimeout = 5 * time.Second
+
+// ProbeSudoNoPasswd reports whether the remote user can run sudo without a
+// password (NOPASSWD), by running `sudo -n true` as the target user of
+// opts. With an escalation template, `true` is run through it with no
+// password on stdin, for at most escalationProbeTimeout unless the
+// template has -n: the tool may wait for a password that never comes.
+// Both run inside the setup command, if any.
+func ProbeSudoNoPasswd(ctx context.Context, client *gossh.Client, opts CommandOpts) bool {
+	if opts.Escalation != "" && !slices.Contains(strings.Fields(opts.Escalation), "-n") {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, escalationProbeTimeout)
+		defer cancel()
+	}
+	probe := CommandOpts{Sudo: true, Escalation: opts.Escalation, SudoUser: opts.SudoUser, Setup: opts.Setup, Shell: opts.Shell}
+	_, err := runCommand(ctx, client, "true", probe)
+	if err != nil {
+		logger.Log("ssh", "sudo -n probe failed: %v", err)
+		return false
+	}
+	return true
+}
+
+// RemoteHostname returns the fully qualified hostname reported by the server.
+// Only the login shell of opts is used.
+func RemoteHostname(ctx context.Context, client *gossh.Client, opts CommandOpts) (string, error) {
+	output, err := runCommand(ctx, client, "hostname -f 2>/dev/null || hostname", CommandOpts{Shell: opts.Shell})
+	if err != nil {
+		return "", fmt.Errorf("hostname: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// RemoteMOTD returns the message of the day login shells would print:
+// the dynamic one of Debian and Ubuntu followed by /etc/motd. Only the login
+// shell of opts is used.
+func RemoteMOTD(ctx context.Context, client *gossh.Client, opts CommandOpts) (string, error) {
+	output, err := runCommand(ctx, client, "cat /run/motd.dynamic /etc/motd 2>/dev/null; true", CommandOpts{Shell: opts.Shell})
+	if err != nil {
+		return "", fmt.Errorf("motd: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
 // parseLsOutput parses `ls -la --time-style=full-iso` output into FileInfo entries.
 // Format: permissions links owner group size date time timezone name
 func parseLsOutput(output string) []FileInfo {
 	var files []FileInfo
 	for _, line := range strings.Split(output, "\n") {
 		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "total") {
+		if line == "" || strings.HasPrefix(line, "total") || strings.HasPrefix(line, "ls:") {
 			continue
 		}
 
 		fields := strings.Fields(line)
+		// Entries ls could not stat show as "-????????? ? ? ? ? ? name"
+		if len(fields) >= 7 && strings.Contains(fields[0], "?") && fields[5] == "?" {
+			if name := strings.Join(fields[6:], " "); name != "." && name != ".." {
+				files = append(files, FileInfo{Name: name, IsDir: fields[0][0] == 'd'})
+			}
+			continue
+		}
 		if len(fields) < 9 {
 			continue
 		}
@@ -359,9 +717,16 @@ func parseLsOutput(output string) []FileInfo {
 	return files
 }
 
-func filterByPatterns(files []FileInfo, patterns []string) []FileInfo {
+// FilterByPatterns keeps the files whose name matches one of the glob
+// patterns, and all directories.
+func FilterByPatterns(files []FileInfo, patterns []string) []FileInfo {
 	var filtered []FileInfo
 	for _, f := range files {
+		if f.IsDir {
+			// Kept to browse into
+			filtered = append(filtered, f)
+			continue
+		}
 		for _, p := range patterns {
 			matched, err := filepath.Match(p, f.Name)
 			if err == nil && matched {
@@ -373,6 +738,16 @@ func filterByPatterns(files []FileInfo, patterns []string) []FileInfo {
 	return filtered
 }
 
+// sortListing sorts a listing by name, directories first.
+func sortListing(files []FileInfo) {
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].IsDir != files[j].IsDir {
+			return files[i].IsDir
+		}
+		return files[i].Name < files[j].Name
+	})
+}
+
 // FormatSize returns a human-readable file size.
 func FormatSize(bytes int64) string {
 	const (
@@ -0,0 +1,106 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"al.essio.dev/pkg/shellescape"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// grepScript searches the files given after the folder and the query with
+// grep, or zgrep, bzgrep and xzgrep for compressed logs, one file after the
+// other so hits arrive as they are found. Each hit is "file\0line:text":
+// names may hold colons, and as only GNU grep can end them with a NUL, the
+// loop puts the name before grep's own output. head stops the search after
+// the limit.
+const grepScript = `cd -- "$1" || exit 2
+q=$2
+shift 2
+for f do
+	case $f in
+	*.gz) zgrep -n -E -e "$q" -- "$f" ;;
+	*.bz2) bzgrep -n -E -e "$q" -- "$f" ;;
+	*.xz) xzgrep -n -E -e "$q" -- "$f" ;;
+	*) grep -nI -E -e "$q" -- "$f" ;;
+	esac | while IFS= read -r l; do printf '%%s\000%%s\n' "$f" "$l"; done
+done 2>/dev/null | head -n %d`
+
+// Grep searches files of the remote folder dir for the extended regular
+// expression query and writes the hits to w as they are found, at most
+// maxHits of them, one per line for ParseGrepHit. It returns once the
+// search is done or ctx is cancelled.
+func Grep(ctx context.Context, client *gossh.Client, dir string, files []string, query string, maxHits int, w io.Writer, opts CommandOpts) error {
+	sess, err := newSession(ctx, client, opts)
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+
+	stdout, err := sess.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("stdout pipe: %w", err)
+	}
+	// The greps' own errors are dropped; what's left says why the search
+	// could not run, such as a folder that's gone or sudo refusing.
+	var stderr bytes.Buffer
+	sess.Stderr = &stderr
+
+	args := make([]string, 0, len(files)+2)
+	for _, a := range append([]string{dir, query}, files...) {
+		args = append(args, shellescape.Quote(a))
+	}
+	cmd := fmt.Sprintf("sh -c %s _ %s", shellescape.Quote(fmt.Sprintf(grepScript, maxHits)), strings.Join(args, " "))
+	if opts.Sudo {
+		err = startSudo(sess.Session, cmd, opts)
+	} else {
+		err = sess.Start(commandLine(cmd, opts))
+	}
+	if err != nil {
+		return fmt.Errorf("starting grep: %w", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			sess.Signal(gossh.SIGTERM)
+			sess.Close()
+		case <-stop:
+		}
+	}()
+
+	if _, err := io.Copy(w, stdout); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("reading grep output: %w", err)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := sess.Wait(); err != nil {
+		if msg, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n"); msg != "" {
+			return fmt.Errorf("searching %s: %s", dir, msg)
+		}
+		return fmt.Errorf("searching %s: %w", dir, err)
+	}
+	return nil
+}
+
+// ParseGrepHit splits a hit written by Grep into the file name, the line
+// number and the text of the line.
+func ParseGrepHit(hit string) (name string, line int, text string, ok bool) {
+	name, rest, found := strings.Cut(hit, "\x00")
+	if !found {
+		return "", 0, "", false
+	}
+	num, text, _ := strings.Cut(rest, ":")
+	n, err := strconv.Atoi(num)
+	if err != nil {
+		return "", 0, "", false
+	}
+	return name, n, text, true
+}
@@ -0,0 +1,114 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"log-monitor/internal/logger"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	krbconfig "github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// gssapiClient authenticates with the Kerberos tickets of the local user
+// (kinit), like ssh -K, through gssapi-with-mic. Only file credential
+// caches are supported.
+type gssapiClient struct {
+	krb    *client.Client
+	key    types.EncryptionKey // session key of the service ticket
+	seqNum int64               // initiator sequence number of the context
+}
+
+// InitSecContext sends an AP-REQ for the host/<server> service ticket. No
+// mutual authentication is asked for, so a single token completes the
+// context.
+func (g *gssapiClient) InitSecContext(target string, token []byte, isGSSDelegCreds bool) ([]byte, bool, error) {
+	if token != nil {
+		return nil, false, fmt.Errorf("kerberos: unexpected token from server")
+	}
+	krb, err := loadKerberos()
+	if err != nil {
+		return nil, false, err
+	}
+	g.krb = krb
+
+	// The SSH layer passes host@<server>; Kerberos names it host/<server>
+	spn := strings.Replace(target, "@", "/", 1)
+	tkt, key, err := krb.GetServiceTicket(spn)
+	if err != nil {
+		return nil, false, fmt.Errorf("kerberos: service ticket for %s: %w", spn, err)
+	}
+	logger.Log("ssh", "kerberos: got service ticket for %s as %s", spn, krb.Credentials.UserName())
+
+	tok, err := spnego.NewKRB5TokenAPREQ(krb, tkt, key, []int{gssapi.ContextFlagInteg}, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("kerberos: building AP-REQ: %w", err)
+	}
+	// The MIC carries the sequence number the authenticator was given
+	if err := tok.APReq.DecryptAuthenticator(key); err != nil {
+		return nil, false, fmt.Errorf("kerberos: %w", err)
+	}
+	out, err := tok.Marshal()
+	if err != nil {
+		return nil, false, fmt.Errorf("kerberos: %w", err)
+	}
+	g.key, g.seqNum = key, tok.APReq.Authenticator.SeqNumber
+	return out, false, nil
+}
+
+// GetMIC signs the SSH session binding with the ticket's session key.
+func (g *gssapiClient) GetMIC(micField []byte) ([]byte, error) {
+	mic := gssapi.MICToken{SndSeqNum: uint64(g.seqNum), Payload: micField}
+	if err := mic.SetChecksum(g.key, keyusage.GSSAPI_INITIATOR_SIGN); err != nil {
+		return nil, fmt.Errorf("kerberos: signing: %w", err)
+	}
+	return mic.Marshal()
+}
+
+// DeleteSecContext forgets the context and the loaded credentials.
+func (g *gssapiClient) DeleteSecContext() error {
+	if g.krb != nil {
+		g.krb.Destroy()
+		g.krb = nil
+	}
+	g.key = types.EncryptionKey{}
+	return nil
+}
+
+// loadKerberos reads krb5.conf and the credential cache, from $KRB5_CONFIG
+// and $KRB5CCNAME when set, as the MIT tools do.
+func loadKerberos() (*client.Client, error) {
+	confPath := os.Getenv("KRB5_CONFIG")
+	if confPath == "" {
+		confPath = "/etc/krb5.conf"
+	}
+	cfg, err := krbconfig.Load(confPath)
+	if err != nil {
+		return nil, fmt.Errorf("kerberos: loading %s: %w", confPath, err)
+	}
+
+	ccPath := os.Getenv("KRB5CCNAME")
+	switch {
+	case ccPath == "":
+		ccPath = fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid())
+	case strings.HasPrefix(ccPath, "FILE:"):
+		ccPath = strings.TrimPrefix(ccPath, "FILE:")
+	case strings.Contains(ccPath, ":") && !strings.HasPrefix(ccPath, "/"):
+		return nil, fmt.Errorf("kerberos: credential cache %s not supported, only FILE: caches (KRB5CCNAME)", ccPath)
+	}
+	cc, err := credentials.LoadCCache(ccPath)
+	if err != nil {
+		return nil, fmt.Errorf("kerberos: no tickets in %s (run kinit): %w", ccPath, err)
+	}
+	krb, err := client.NewFromCCache(cc, cfg, client.DisablePAFXFAST(true))
+	if err != nil {
+		return nil, fmt.Errorf("kerberos: %w", err)
+	}
+	return krb, nil
+}
@@ -0,0 +1,132 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/logger"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyError reports a host key that is not in known_hosts yet, or that
+// differs from the key recorded there. The user may choose to accept it.
+type HostKeyError struct {
+	Server   config.ServerConfig // server that presented the key (may be a jump host)
+	Address  string              // host:port as dialed
+	Key      ssh.PublicKey
+	Mismatch bool // known_hosts holds a different key for this host
+}
+
+func (e *HostKeyError) Error() string {
+	if e.Mismatch {
+		return fmt.Sprintf("host key for %s has changed (%s %s)", e.Address, e.Key.Type(), e.Fingerprint())
+	}
+	return fmt.Sprintf("unknown host key for %s (%s %s)", e.Address, e.Key.Type(), e.Fingerprint())
+}
+
+// Fingerprint returns the SHA256 fingerprint of the presented key.
+func (e *HostKeyError) Fingerprint() string {
+	return ssh.FingerprintSHA256(e.Key)
+}
+
+// loadKnownHosts parses the known_hosts file. A missing file is treated as
+// empty, so every host is unknown until accepted.
+func loadKnownHosts(path string) (ssh.HostKeyCallback, error) {
+	cb, err := knownhosts.New(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return func(string, net.Addr, ssh.PublicKey) error {
+				return &knownhosts.KeyError{}
+			}, nil
+		}
+		return nil, fmt.Errorf("reading known_hosts %s: %w", path, err)
+	}
+	return cb, nil
+}
+
+// hostKeyCallback verifies host keys against known_hosts. Unknown keys, and
+// changed keys unless the server is in strict mode, come back as a
+// *HostKeyError so the UI can ask the user. seen receives the presented key.
+func (p *Pool) hostKeyCallback(srv config.ServerConfig, check ssh.HostKeyCallback, seen *ssh.PublicKey) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		*seen = key
+		err := check(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+		hkErr := &HostKeyError{Server: srv, Address: hostname, Key: key, Mismatch: len(keyErr.Want) > 0}
+		logger.Log("ssh", "host key check for %s: %v", hostname, hkErr)
+		if hkErr.Mismatch && srv.StrictHost {
+			return fmt.Errorf("%s; refusing to connect (strict_host_key)", hkErr.Error())
+		}
+		return hkErr
+	}
+}
+
+// knownHostAlgorithms returns the host key algorithms recorded for addr, so
+// the server is asked for a key type we can verify rather than whichever it
+// prefers. Returns nil if the host is not in known_hosts.
+func knownHostAlgorithms(check ssh.HostKeyCallback, addr string) []string {
+	// Probe with a key that can't be in the file; the resulting KeyError
+	// lists the keys that are.
+	probe, err := ssh.NewPublicKey(ed25519.PublicKey(make([]byte, ed25519.PublicKeySize)))
+	if err != nil {
+		return nil
+	}
+	var keyErr *knownhosts.KeyError
+	if !errors.As(check(addr, &net.TCPAddr{IP: net.IPv4zero}, probe), &keyErr) {
+		return nil
+	}
+	var algos []string
+	seen := make(map[string]bool)
+	for _, k := range keyErr.Want {
+		typ := k.Key.Type()
+		if seen[typ] {
+			continue
+		}
+		seen[typ] = true
+		if typ == ssh.KeyAlgoRSA {
+			algos = append(algos, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256)
+		}
+		algos = append(algos, typ)
+	}
+	return algos
+}
+
+// AcceptHostKey records the key from a HostKeyError in known_hosts so future
+// connections trust it.
+func (p *Pool) AcceptHostKey(e *HostKeyError) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(p.knownHosts), 0o700); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(p.knownHosts), err)
+	}
+	f, err := os.OpenFile(p.knownHosts, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening known_hosts: %w", err)
+	}
+	defer f.Close()
+
+	line := knownhosts.Line([]string{knownhosts.Normalize(e.Address)}, e.Key) + "\n"
+	// Don't glue the new entry onto a last line that lacks its newline.
+	if st, err := f.Stat(); err == nil && st.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, st.Size()-1); err == nil && last[0] != '\n' {
+			line = "\n" + line
+		}
+	}
+	if _, err := f.WriteString(line); err != nil {
+		return fmt.Errorf("writing known_hosts: %w", err)
+	}
+	logger.Log("ssh", "added %s %s to %s", e.Address, e.Fingerprint(), p.knownHosts)
+	return nil
+}
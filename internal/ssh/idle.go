@@ -0,0 +1,85 @@
+package ssh
+
+import (
+	"time"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/logger"
+)
+
+// Hold marks the server's connection as in use by a long-running operation
+// such as a tail or a download, so the idle timeout doesn't close it. The
+// returned func releases it and must be called exactly once.
+func (p *Pool) Hold(srv config.ServerConfig) (release func()) {
+	key := ServerKey(srv)
+	p.mu.Lock()
+	p.busy[key]++
+	p.mu.Unlock()
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.busy[key]--; p.busy[key] <= 0 {
+			delete(p.busy, key)
+		}
+		p.lastUsed[key] = time.Now()
+	}
+}
+
+// SetIdleTimeout closes connections that haven't been used for d, checking
+// in the background. Connections held with Hold are kept. A d of 0 or less
+// turns it off. It runs until CloseAll, which doesn't restart it.
+func (p *Pool) SetIdleTimeout(d time.Duration) {
+	p.mu.Lock()
+	if p.idleStop != nil {
+		close(p.idleStop)
+		p.idleStop = nil
+	}
+	p.idleTimeout = d
+	if d <= 0 {
+		p.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	p.idleStop = stop
+	p.mu.Unlock()
+
+	// Check often enough that a connection outlives the timeout by at most
+	// a quarter of it, within reason.
+	every := min(max(d/4, time.Second), time.Minute)
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				p.closeIdle()
+			}
+		}
+	}()
+}
+
+// closeIdle closes every connection that is not held and has been unused
+// for longer than the idle timeout.
+func (p *Pool) closeIdle() {
+	var closed []string
+	p.mu.Lock()
+	for key, c := range p.clients {
+		if p.busy[key] > 0 || time.Since(p.lastUsed[key]) < p.idleTimeout {
+			continue
+		}
+		delete(p.clients, key)
+		delete(p.alive, key)
+		delete(p.latency, key)
+		delete(p.lastUsed, key)
+		c.Close()
+		closed = append(closed, key)
+	}
+	p.mu.Unlock()
+
+	for _, key := range closed {
+		logger.Log("ssh", "closed idle connection to %s", key)
+		p.notify(key, StateDisconnected, nil)
+	}
+}
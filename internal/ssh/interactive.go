@@ -0,0 +1,78 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/logger"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Challenge is a keyboard-interactive prompt from a server, such as a
+// request for a one-time code. Answer by sending one answer per question on
+// Reply, or nil to abort the login. A challenge without questions carries
+// only text to show (e.g. "Duo push sent") and expects no reply. Done is
+// closed once the login stops waiting for the answers, e.g. on its timeout.
+type Challenge struct {
+	Server      config.ServerConfig
+	Name        string
+	Instruction string
+	Questions   []string
+	Echos       []bool // whether each answer may be shown while typed
+	Reply       chan<- []string
+	Done        <-chan struct{}
+}
+
+// SetChallenges sets the channel keyboard-interactive challenges are sent
+// to. Without it, keyboard-interactive logins fail.
+func (p *Pool) SetChallenges(ch chan<- Challenge) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.challenges = ch
+}
+
+// keyboardInteractive answers a server's challenges by forwarding them to
+// the UI and waiting for the user, for as long as ctx allows.
+func (p *Pool) keyboardInteractive(ctx context.Context, srv config.ServerConfig) ssh.KeyboardInteractiveChallenge {
+	return func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		p.mu.Lock()
+		challenges := p.challenges
+		p.mu.Unlock()
+		if challenges == nil {
+			return nil, fmt.Errorf("keyboard-interactive login needs a terminal")
+		}
+		logger.Log("ssh", "keyboard-interactive challenge from %s: %d question(s)", srv.Name, len(questions))
+
+		reply := make(chan []string, 1)
+		c := Challenge{
+			Server:      srv,
+			Name:        name,
+			Instruction: instruction,
+			Questions:   questions,
+			Echos:       echos,
+			Reply:       reply,
+			Done:        ctx.Done(),
+		}
+		select {
+		case challenges <- c:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if len(questions) == 0 {
+			return nil, nil
+		}
+
+		select {
+		case answers := <-reply:
+			if answers == nil {
+				return nil, errors.New("login cancelled")
+			}
+			return answers, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/logger"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// keepaliveTimeout is how long a keepalive may take before the connection
+// is considered dead.
+const keepaliveTimeout = 5 * time.Second
+
+// aliveTrust is how long an answered background keepalive stands in for
+// GetClient's own check: a connection that died since is handed out until
+// then, and fails the command run on it.
+const aliveTrust = 5 * time.Second
+
+var errKeepaliveTimeout = errors.New("keepalive timed out")
+
+// keepalive pings the connection for key each interval until it closes,
+// like OpenSSH's ServerAliveInterval. A connection that stops answering
+// ServerAliveCountMax-style, or fails outright, is evicted and reported as
+// StateError: sessions on it end, so a tail on a connection silently
+// dropped by a firewall or sshd's idle timeout resumes on a new one instead
+// of waiting forever. The server's keepalive_interval and
+// keepalive_count_max, defaulted from the config defaults, set how often and
+// how many; a zero or negative interval turns it off. GetClient can skip its
+// own check for connections that answered just before (see aliveTrust).
+func (p *Pool) keepalive(key string, c *ssh.Client, srv config.ServerConfig) {
+	interval, countMax := srv.KeepaliveInterval, srv.KeepaliveCountMax
+	if interval <= 0 {
+		return
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		c.Wait()
+		close(closed)
+	}()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	missed := 0
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+		}
+		rtt, err := ping(c)
+		if err == nil {
+			missed = 0
+			p.mu.Lock()
+			if p.clients[key] == c {
+				p.alive[key] = time.Now()
+			}
+			p.mu.Unlock()
+			p.setLatency(key, c, rtt)
+			continue
+		}
+		timedOut := errors.Is(err, errKeepaliveTimeout)
+		if timedOut {
+			missed++
+			err = fmt.Errorf("no answer to %d keepalive(s) in a row", missed)
+		}
+		logger.Log("ssh", "background keepalive failed for %s: %v", key, err)
+		p.mu.Lock()
+		delete(p.alive, key)
+		p.mu.Unlock()
+		if !timedOut || missed >= max(countMax, 1) {
+			p.evict(key, c, err)
+			return
+		}
+	}
+}
+
+// ping sends one keepalive request and waits up to keepaliveTimeout. It
+// returns the round trip time.
+func ping(c *ssh.Client) (time.Duration, error) {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := c.SendRequest("keepalive@openssh.com", true, nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return time.Since(start), err
+	case <-time.After(keepaliveTimeout):
+		return 0, errKeepaliveTimeout
+	}
+}
+
+// measureLatency times one keepalive on a new connection, so the round
+// trip is known before the first background keepalive.
+func (p *Pool) measureLatency(key string, c *ssh.Client) {
+	rtt, err := ping(c)
+	if err != nil {
+		logger.Log("ssh", "measuring latency of %s: %v", key, err)
+		return
+	}
+	p.setLatency(key, c, rtt)
+}
+
+// setLatency records the round trip time of the connection for key and
+// reports it, unless the connection has been replaced in the meantime.
+func (p *Pool) setLatency(key string, c *ssh.Client, rtt time.Duration) {
+	p.mu.Lock()
+	current := p.clients[key] == c
+	if current {
+		p.latency[key] = rtt
+	}
+	fn := p.onState
+	p.mu.Unlock()
+	if current && fn != nil {
+		fn(StateChange{Key: key, State: StateConnected, Latency: rtt})
+	}
+}
+
+// Latency returns the round trip time of the last keepalive on the
+// server's pooled connection, or 0 if it isn't connected or not measured
+// yet.
+func (p *Pool) Latency(srv config.ServerConfig) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.latency[ServerKey(srv)]
+}
+
+// recentlyAlive reports whether the connection for key answered a
+// background keepalive within aliveTrust, and the last interval of srv.
+func (p *Pool) recentlyAlive(key string, srv config.ServerConfig) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	at, ok := p.alive[key]
+	return ok && srv.KeepaliveInterval > 0 && time.Since(at) < min(srv.KeepaliveInterval, aliveTrust)
+}
+
+// evict closes a dead connection and drops it from the pool, unless it has
+// been replaced in the meantime.
+func (p *Pool) evict(key string, c *ssh.Client, err error) {
+	p.mu.Lock()
+	current := p.clients[key] == c
+	if current {
+		delete(p.clients, key)
+		delete(p.alive, key)
+		delete(p.latency, key)
+		delete(p.lastUsed, key)
+		p.lost[key] = fmt.Errorf("server stopped answering: %w", err)
+	}
+	p.mu.Unlock()
+	c.Close()
+	if current {
+		p.notify(key, StateError, err)
+	}
+}
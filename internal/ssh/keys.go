@@ -0,0 +1,65 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"log-monitor/internal/config"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// PassphraseError reports an encrypted private key. The connection can be
+// retried once the key has been unlocked with UnlockKey.
+type PassphraseError struct {
+	Server  config.ServerConfig // server whose auth uses the key (may be a jump host)
+	KeyPath string
+}
+
+func (e *PassphraseError) Error() string {
+	return fmt.Sprintf("key %s is protected by a passphrase", e.KeyPath)
+}
+
+// keySigner returns the signer for a server's private key, using the
+// decrypted copy if the key was unlocked earlier in this session.
+func (p *Pool) keySigner(srv config.ServerConfig) (ssh.Signer, error) {
+	path := srv.Auth.KeyPath
+	p.mu.Lock()
+	signer, ok := p.signers[path]
+	p.mu.Unlock()
+	if ok {
+		return signer, nil
+	}
+
+	keyData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key %s: %w", path, err)
+	}
+	signer, err = ssh.ParsePrivateKey(keyData)
+	if err != nil {
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return nil, &PassphraseError{Server: srv, KeyPath: path}
+		}
+		return nil, fmt.Errorf("parsing key %s: %w", path, err)
+	}
+	return signer, nil
+}
+
+// UnlockKey decrypts a passphrase-protected private key and keeps the
+// signer in memory until CloseAll, so the passphrase is asked only once.
+func (p *Pool) UnlockKey(path, passphrase string) error {
+	keyData, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading key %s: %w", path, err)
+	}
+	signer, err := ssh.ParsePrivateKeyWithPassphrase(keyData, []byte(passphrase))
+	if err != nil {
+		return fmt.Errorf("unlocking key %s: %w", path, err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.signers[path] = signer
+	return nil
+}
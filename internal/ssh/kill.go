@@ -0,0 +1,31 @@
+package ssh
+
+import "log-monitor/internal/logger"
+
+// KillAll is the kill switch: it closes every open session and connection
+// at once, stopping whatever runs on them (a tail, a runaway cat, a
+// download). Unlike CloseAll the pool stays usable, with its passwords and
+// unlocked keys: servers reconnect on their next use. It returns how many
+// sessions and connections were closed.
+func (p *Pool) KillAll() (sessions, conns int) {
+	sessions = closeSessions()
+
+	var closed []string
+	p.mu.Lock()
+	for key, c := range p.clients {
+		delete(p.clients, key)
+		delete(p.alive, key)
+		delete(p.latency, key)
+		delete(p.lastUsed, key)
+		c.Close()
+		closed = append(closed, key)
+	}
+	p.closeHops()
+	p.mu.Unlock()
+
+	for _, key := range closed {
+		p.notify(key, StateDisconnected, nil)
+	}
+	logger.Log("ssh", "kill switch: closed %d session(s) on %d connection(s)", sessions, len(closed))
+	return sessions, len(closed)
+}
@@ -0,0 +1,131 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"al.essio.dev/pkg/shellescape"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// Kube says which pods a kubectl folder shows and where kubectl runs: on
+// the server, or on this machine with a kubeconfig.
+type Kube struct {
+	Namespace  string // empty for kubectl's current namespace
+	Selector   string // label selector, e.g. "app=api"
+	Kubeconfig string // runs kubectl locally with this kubeconfig; empty runs it on the server
+}
+
+// Local reports whether kubectl runs on this machine rather than the server.
+func (k Kube) Local() bool {
+	return k.Kubeconfig != ""
+}
+
+// podsJSONPath prints a line per pod: its name, start time and containers.
+const podsJSONPath = `{range .items[*]}{.metadata.name}{"\t"}{.status.startTime}{"\t"}{range .spec.containers[*]}{.name}{" "}{end}{"\n"}{end}`
+
+// ListPods lists the pods of a kubectl folder by name. A pod with
+// several containers is listed once per container, as "pod/container".
+func ListPods(ctx context.Context, client *gossh.Client, k Kube, opts CommandOpts) ([]FileInfo, error) {
+	args := []string{"get", "pods", "-o", "jsonpath=" + podsJSONPath}
+	if k.Selector != "" {
+		args = append(args, "-l", k.Selector)
+	}
+	out, err := k.run(ctx, client, args, opts)
+	if err != nil {
+		return nil, fmt.Errorf("listing pods: %w", err)
+	}
+	var pods []FileInfo
+	for _, line := range strings.Split(out, "\n") {
+		name, rest, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		started, containers, _ := strings.Cut(rest, "\t")
+		at, _ := time.Parse(time.RFC3339, started)
+		names := strings.Fields(containers)
+		if len(names) <= 1 {
+			pods = append(pods, FileInfo{Name: name, ModTime: at})
+			continue
+		}
+		for _, c := range names {
+			pods = append(pods, FileInfo{Name: name + "/" + c, ModTime: at})
+		}
+	}
+	sortListing(pods)
+	return pods, nil
+}
+
+// StartPodLogs follows the log of a pod listed by ListPods, like
+// `kubectl logs -f`, starting with its last lines.
+func StartPodLogs(ctx context.Context, client *gossh.Client, k Kube, name string, lines int, w io.Writer, opts CommandOpts) (*Tailer, error) {
+	pod, container, _ := strings.Cut(name, "/")
+	args := []string{"logs", "-f", "--tail=" + strconv.Itoa(lines), pod}
+	if container != "" {
+		args = append(args, "-c", container)
+	}
+	if k.Local() {
+		return k.startLocal(ctx, args, w)
+	}
+	opts.Sudo = false
+	return startStream(ctx, client, k.commandLine(args), w, opts)
+}
+
+// commandLine returns the kubectl command line for args, to run on the
+// server.
+func (k Kube) commandLine(args []string) string {
+	parts := []string{"kubectl"}
+	for _, a := range k.args(args) {
+		parts = append(parts, shellescape.Quote(a))
+	}
+	return strings.Join(parts, " ")
+}
+
+// args puts the namespace in front of args.
+func (k Kube) args(args []string) []string {
+	if k.Namespace == "" {
+		return args
+	}
+	return append([]string{"-n", k.Namespace}, args...)
+}
+
+// command returns kubectl with args, to run on this machine.
+func (k Kube) command(ctx context.Context, args []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "kubectl", k.args(args)...)
+	cmd.Env = append(os.Environ(), "KUBECONFIG="+k.Kubeconfig)
+	return cmd
+}
+
+// run runs kubectl with args and returns its output. kubectl runs as the
+// login user on the server, never with sudo.
+func (k Kube) run(ctx context.Context, client *gossh.Client, args []string, opts CommandOpts) (string, error) {
+	if !k.Local() {
+		opts.Sudo = false
+		return runCommand(ctx, client, k.commandLine(args), opts)
+	}
+	var stderr bytes.Buffer
+	cmd := k.command(ctx, args)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n"); msg != "" {
+			return "", fmt.Errorf("kubectl: %s", msg)
+		}
+		return "", fmt.Errorf("kubectl: %w", err)
+	}
+	return string(out), nil
+}
+
+// startLocal runs kubectl with args on this machine and copies its output
+// to w until it is stopped or ends.
+func (k Kube) startLocal(ctx context.Context, args []string, w io.Writer) (*Tailer, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	return startLocalStream(ctx, cancel, k.command(ctx, args), w)
+}
@@ -0,0 +1,219 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"log-monitor/internal/logger"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// ErrLocal is returned for a local server by what only works over SSH.
+var ErrLocal = errors.New("not available for a local server")
+
+// localBackend reads the files of a local server from the file system. The
+// client it is passed is nil and ignored.
+type localBackend struct{}
+
+func (localBackend) ListFiles(ctx context.Context, _ *gossh.Client, dir string, patterns []string, _ CommandOpts) ([]FileInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", dir, err)
+	}
+	var files []FileInfo
+	for _, e := range entries {
+		// Show what a link points at, like a rotated log's "current" link
+		info, err := os.Stat(filepath.Join(dir, e.Name()))
+		if err != nil {
+			if info, err = e.Info(); err != nil {
+				continue
+			}
+		}
+		files = append(files, FileInfo{
+			Name:    e.Name(),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			IsDir:   info.IsDir(),
+		})
+	}
+
+	if len(patterns) > 0 {
+		files = FilterByPatterns(files, patterns)
+	}
+
+	sortListing(files)
+	return files, nil
+}
+
+func (localBackend) StatFile(_ context.Context, _ *gossh.Client, path string, _ CommandOpts) (*FileInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	return &FileInfo{
+		Name:    filepath.Base(path),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		IsDir:   info.IsDir(),
+	}, nil
+}
+
+func (localBackend) DownloadFile(_ *gossh.Client, remotePath, localPath string, opts CommandOpts, ctx context.Context, progressCh chan<- int64) error {
+	if progressCh != nil {
+		defer close(progressCh)
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
+		return fmt.Errorf("creating local directory: %w", err)
+	}
+
+	logger.Log("ssh", "DownloadFile (local): %s → %s", remotePath, localPath)
+
+	src, err := os.Open(remotePath)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", remotePath, err)
+	}
+	defer src.Close()
+
+	// Creating the destination would empty the source before it is read
+	if srcInfo, err := src.Stat(); err == nil {
+		if dstInfo, err := os.Stat(localPath); err == nil && os.SameFile(srcInfo, dstInfo) {
+			return fmt.Errorf("%s is the file being downloaded; choose another destination", localPath)
+		}
+	}
+
+	f, err := os.Create(localPath)
+	if err != nil {
+		return fmt.Errorf("creating local file: %w", err)
+	}
+	defer f.Close()
+
+	_, err = io.Copy(downloadWriter(ctx, f, opts, progressCh), ctxReader{ctx: ctx, r: src})
+	if errors.Is(err, errDownloadLimit) {
+		logger.Log("ssh", "DownloadFile (local): %s stopped at %d bytes, it grew during the copy", remotePath, opts.DownloadLimit)
+		return nil
+	}
+	if err != nil {
+		// On cancel/error, remove partial file
+		f.Close()
+		os.Remove(localPath)
+		return fmt.Errorf("copying file: %w", err)
+	}
+	return nil
+}
+
+// ctxReader stops a copy once its context is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr ctxReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// TruncateLocalFile is TruncateFile for a file on this machine.
+func TruncateLocalFile(path string) error {
+	if err := os.Truncate(path, 0); err != nil {
+		return fmt.Errorf("truncating %s: %w", path, err)
+	}
+	return nil
+}
+
+// DeleteLocalFile is DeleteFile for a file on this machine.
+func DeleteLocalFile(path string) error {
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("deleting %s: %w", path, err)
+	}
+	return nil
+}
+
+// startLocalStream runs a command on this machine that keeps printing, such
+// as kubectl logs -f, and copies its output to w until it is stopped or ends.
+// cancel stops it: cmd must have been made with its context.
+func startLocalStream(ctx context.Context, cancel context.CancelFunc, cmd *exec.Cmd, w io.Writer) (*Tailer, error) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("stdout pipe: %w", err)
+	}
+	stderr := newTailStderr()
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("starting %s: %w", filepath.Base(cmd.Path), err)
+	}
+
+	t := &Tailer{
+		cancel:    cancel,
+		rotations: stderr.rotations,
+		done:      make(chan struct{}),
+	}
+	go func() {
+		defer close(t.done)
+
+		copyDone := make(chan error, 1)
+		go func() {
+			_, err := io.Copy(w, stdout)
+			copyDone <- err
+		}()
+
+		select {
+		case <-ctx.Done():
+			// The context kills the command; Wait reaps it
+			cmd.Wait()
+		case err := <-copyDone:
+			waitErr := cmd.Wait()
+			if err == nil {
+				err = classifyLocalExit(waitErr, stderr.String())
+			}
+			t.mu.Lock()
+			t.err = err
+			cb := t.errCallback
+			t.mu.Unlock()
+			if cb != nil {
+				cb(err)
+			}
+		}
+	}()
+	return t, nil
+}
+
+// classifyLocalExit works out why a local command ended, as classifyTailExit
+// does for a remote one.
+func classifyLocalExit(waitErr error, stderr string) *TailExitError {
+	e := &TailExitError{Reason: TailExited, Err: waitErr}
+	stderr = strings.TrimSpace(stderr)
+	if first, _, _ := strings.Cut(stderr, "\n"); first != "" {
+		e.Stderr = first
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(waitErr, &exitErr) {
+		return e
+	}
+	e.Status = exitErr.ExitCode()
+	switch status, ok := exitErr.Sys().(syscall.WaitStatus); {
+	case ok && status.Signaled():
+		e.Reason = TailKilled
+		e.Signal = strings.TrimPrefix(strings.ToUpper(status.Signal().String()), "SIG")
+	case strings.Contains(stderr, "No such file"), strings.Contains(stderr, "has become inaccessible"):
+		e.Reason = TailFileGone
+	case strings.Contains(stderr, "Permission denied"), strings.Contains(stderr, "Operation not permitted"):
+		e.Reason = TailPermissionLost
+	}
+	return e
+}
@@ -0,0 +1,464 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// localPollInterval is how often a local tail looks at its files besides
+// the file system's notifications, which some file systems, such as network
+// mounts, don't send.
+const localPollInterval = time.Second
+
+// localReadChunk is how much a local tail reads at a time, and how far
+// back it reads at a time when looking for the last lines.
+const localReadChunk = 64 * 1024
+
+// openLocalLog opens a file on this machine to read its lines, through a
+// decompressor for compressed logs (see Decompressor).
+func openLocalLog(ctx context.Context, path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	switch Decompressor(path) {
+	case "zcat":
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{zr, f}, nil
+	case "bzcat":
+		return struct {
+			io.Reader
+			io.Closer
+		}{bzip2.NewReader(f), f}, nil
+	case "xzcat":
+		// The standard library has no xz reader
+		f.Close()
+		cmd := exec.CommandContext(ctx, "xzcat", "--", path)
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{out, closerFunc(func() error { return cmd.Wait() })}, nil
+	}
+	return f, nil
+}
+
+type closerFunc func() error
+
+func (c closerFunc) Close() error { return c() }
+
+// CountAndReadLocalFile is CountAndReadFileContent for a file on this
+// machine.
+func CountAndReadLocalFile(ctx context.Context, path string, lines int) (totalLines int, content string, err error) {
+	if Decompressor(path) != "" {
+		totalLines, content, err = readCompressedLocal(ctx, path, lines)
+	} else {
+		totalLines, content, err = readPlainLocal(ctx, path, lines)
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("reading %s: %w", path, err)
+	}
+	return totalLines, content, nil
+}
+
+// readPlainLocal counts the lines of a file and reads its last ones, found
+// by reading back from its end.
+func readPlainLocal(ctx context.Context, path string, lines int) (int, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, "", err
+	}
+	size := info.Size()
+
+	total := 0
+	buf := make([]byte, localReadChunk)
+	for off := int64(0); off < size; {
+		if err := ctx.Err(); err != nil {
+			return 0, "", err
+		}
+		n, err := f.ReadAt(buf[:min(int64(len(buf)), size-off)], off)
+		total += bytes.Count(buf[:n], []byte("\n"))
+		off += int64(n)
+		if err != nil {
+			if err == io.EOF {
+				// Truncated while being counted
+				size = off
+				break
+			}
+			return 0, "", err
+		}
+	}
+
+	start, err := lastLinesStart(f, size, lines)
+	if err != nil {
+		return 0, "", err
+	}
+	content := make([]byte, size-start)
+	n, err := f.ReadAt(content, start)
+	if err != nil && err != io.EOF {
+		return 0, "", err
+	}
+	return total, string(content[:n]), nil
+}
+
+// readCompressedLocal decompresses a file once, counting its lines and
+// keeping the last ones, as compressedReadScript does on a server.
+func readCompressedLocal(ctx context.Context, path string, lines int) (int, string, error) {
+	r, err := openLocalLog(ctx, path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer r.Close()
+
+	lines = max(lines, 1)
+	last := make([]string, lines)
+	total := 0
+	br := bufio.NewReaderSize(ctxReader{ctx: ctx, r: r}, localReadChunk)
+	for {
+		line, rerr := br.ReadString('\n')
+		if line != "" {
+			last[total%lines] = strings.TrimSuffix(line, "\n")
+			total++
+		}
+		if rerr != nil {
+			if rerr != io.EOF {
+				err = rerr
+			}
+			break
+		}
+	}
+	var b bytes.Buffer
+	for i := max(total-lines, 0); i < total; i++ {
+		b.WriteString(last[i%lines])
+		b.WriteByte('\n')
+	}
+	if err != nil {
+		// What could be read comes first, like the decompressor's error
+		// after the lines on a server
+		if b.Len() == 0 {
+			return 0, "", err
+		}
+		fmt.Fprintf(&b, "%v\n", err)
+	}
+	return total, b.String(), nil
+}
+
+// lastLinesStart returns where the last n lines of the first size bytes of
+// f start, reading back from the end. A last line without a newline counts.
+func lastLinesStart(f *os.File, size int64, n int) (int64, error) {
+	if n <= 0 {
+		return size, nil
+	}
+	buf := make([]byte, localReadChunk)
+	end := size
+	// The newline ending the last line doesn't start one
+	skip := true
+	for end > 0 {
+		from := max(end-localReadChunk, 0)
+		chunk := buf[:end-from]
+		if _, err := f.ReadAt(chunk, from); err != nil && err != io.EOF {
+			return 0, err
+		}
+		for i := len(chunk) - 1; i >= 0; i-- {
+			if chunk[i] != '\n' {
+				continue
+			}
+			if skip && from+int64(i) == size-1 {
+				continue
+			}
+			if n--; n == 0 {
+				return from + int64(i) + 1, nil
+			}
+		}
+		end = from
+	}
+	return 0, nil
+}
+
+// localFollow is one file a local tail follows by name.
+type localFollow struct {
+	path string
+	f    *os.File
+	info os.FileInfo // of the open file, to notice another taking its name
+	off  int64       // how far it has been read; f is nil while it's gone
+}
+
+// localTail follows files on this machine the way tail -F does: by name,
+// carrying on with the file that takes the name after a rotation and from
+// the start after a truncation, and telling of both.
+type localTail struct {
+	w         io.Writer
+	files     []*localFollow
+	current   *localFollow // the file of the last lines written
+	rotations chan TailRotation
+	buf       []byte
+}
+
+// StartLocalTail is StartTailFiles for files on this machine: it prints
+// their last lines and follows them by name, woken by the file system's
+// notifications where it sends them and polling otherwise. Several files
+// get tail's "==> path <==" headers.
+func StartLocalTail(ctx context.Context, paths []string, lines int, w io.Writer) (*Tailer, error) {
+	lt := &localTail{
+		w:         w,
+		rotations: make(chan TailRotation, 8),
+		buf:       make([]byte, localReadChunk),
+	}
+	for _, p := range paths {
+		lt.files = append(lt.files, &localFollow{path: filepath.Clean(p)})
+	}
+
+	// Watching the folders sees a file replaced as well as written; without
+	// a watcher, polling alone follows the files
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		watcher = nil
+	} else {
+		watched := make(map[string]bool)
+		for _, lf := range lt.files {
+			dir := filepath.Dir(lf.path)
+			if !watched[dir] {
+				watched[dir] = true
+				watcher.Add(dir)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	t := &Tailer{
+		cancel:    cancel,
+		rotations: lt.rotations,
+		done:      make(chan struct{}),
+	}
+	go func() {
+		defer close(t.done)
+		err := lt.run(ctx, watcher, lines)
+		lt.close()
+		if watcher != nil {
+			watcher.Close()
+		}
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		t.mu.Lock()
+		t.err = err
+		cb := t.errCallback
+		t.mu.Unlock()
+		if cb != nil {
+			cb(err)
+		}
+	}()
+	return t, nil
+}
+
+// run writes the last lines of the files, then what is added to them,
+// until ctx is done or writing fails.
+func (lt *localTail) run(ctx context.Context, watcher *fsnotify.Watcher, lines int) error {
+	for _, lf := range lt.files {
+		if err := lt.open(lf, lines); err != nil {
+			return err
+		}
+	}
+
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	if watcher != nil {
+		events, errs = watcher.Events, watcher.Errors
+	}
+	tick := time.NewTicker(localPollInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			for _, lf := range lt.files {
+				if filepath.Clean(ev.Name) == lf.path {
+					if err := lt.check(lf); err != nil {
+						return err
+					}
+				}
+			}
+		case <-errs:
+			// An overflow loses events; the next poll catches up
+		case <-tick.C:
+			for _, lf := range lt.files {
+				if err := lt.check(lf); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// open opens a file to follow and writes its last lines. A file that
+// can't be opened is waited for.
+func (lt *localTail) open(lf *localFollow, lines int) error {
+	f, err := os.Open(lf.path)
+	if err != nil {
+		lt.rotated(RotationGone)
+		return nil
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		lt.rotated(RotationGone)
+		return nil
+	}
+	lf.f, lf.info = f, info
+	start, err := lastLinesStart(f, info.Size(), lines)
+	if err != nil {
+		start = info.Size()
+	}
+	lf.off = start
+	if len(lt.files) > 1 {
+		// tail heads every file, even one without lines yet
+		if err := lt.header(lf); err != nil {
+			return err
+		}
+	}
+	return lt.read(lf)
+}
+
+// check looks at the file under a followed name: another file taking the
+// name, the file shrinking or going away, or lines added to it.
+func (lt *localTail) check(lf *localFollow) error {
+	info, err := os.Stat(lf.path)
+	switch {
+	case err != nil:
+		if lf.f != nil {
+			// Lines written before it went are still there to read
+			if err := lt.read(lf); err != nil {
+				return err
+			}
+			lf.f.Close()
+			lf.f = nil
+			lt.rotated(RotationGone)
+		}
+		return nil
+	case lf.f == nil:
+		if !lt.reopen(lf) {
+			return nil
+		}
+		lt.rotated(RotationAppeared)
+	case !os.SameFile(lf.info, info):
+		if err := lt.read(lf); err != nil {
+			return err
+		}
+		lf.f.Close()
+		lf.f = nil
+		if !lt.reopen(lf) {
+			lt.rotated(RotationGone)
+			return nil
+		}
+		lt.rotated(RotationReplaced)
+	case info.Size() < lf.off:
+		lf.off = 0
+		lt.rotated(RotationTruncated)
+	}
+	return lt.read(lf)
+}
+
+// reopen opens the file that now has the name, to read it from its start.
+func (lt *localTail) reopen(lf *localFollow) bool {
+	f, err := os.Open(lf.path)
+	if err != nil {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return false
+	}
+	lf.f, lf.info, lf.off = f, info, 0
+	return true
+}
+
+// read writes what was added to a file since it was last read.
+func (lt *localTail) read(lf *localFollow) error {
+	if lf.f == nil {
+		return nil
+	}
+	for {
+		n, err := lf.f.ReadAt(lt.buf, lf.off)
+		if n > 0 {
+			if lt.current != lf && len(lt.files) > 1 {
+				if err := lt.header(lf); err != nil {
+					return err
+				}
+			}
+			if _, err := lt.w.Write(lt.buf[:n]); err != nil {
+				return err
+			}
+			lf.off += int64(n)
+		}
+		if err != nil {
+			// At its end, or unreadable for now, e.g. as its permissions
+			// changed: the next check tries again
+			return nil
+		}
+	}
+}
+
+// header writes tail's "==> path <==" header before the lines of a file,
+// with a blank line when it follows those of another.
+func (lt *localTail) header(lf *localFollow) error {
+	h := fmt.Sprintf("==> %s <==\n", lf.path)
+	if lt.current != nil {
+		h = "\n" + h
+	}
+	lt.current = lf
+	_, err := io.WriteString(lt.w, h)
+	return err
+}
+
+// rotated tells of a rotation, unless nobody is reading them.
+func (lt *localTail) rotated(r TailRotation) {
+	select {
+	case lt.rotations <- r:
+	default:
+	}
+}
+
+// close closes the files being followed.
+func (lt *localTail) close() {
+	for _, lf := range lt.files {
+		if lf.f != nil {
+			lf.f.Close()
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"al.essio.dev/pkg/shellescape"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// FileProcess is a process that has a remote file open.
+type FileProcess struct {
+	PID     int
+	Command string
+	User    string
+	Writing bool // open for writing (or reading and writing)
+}
+
+// FileProcesses lists the processes that have a remote file open, with
+// lsof or, where it isn't installed, fuser. Without sudo only the login
+// user's own processes are visible on most systems.
+func FileProcesses(ctx context.Context, client *gossh.Client, path string, opts CommandOpts) ([]FileProcess, error) {
+	// Both exit non-zero when nothing has the file open; the header line
+	// tells the output formats apart. sbin is often missing from PATH.
+	script := `PATH=$PATH:/usr/sbin:/sbin
+if command -v lsof >/dev/null 2>&1; then echo '#lsof'; lsof -F pcLfa -- "$1" 2>/dev/null; true
+elif command -v fuser >/dev/null 2>&1; then echo '#fuser'; fuser -v "$1" 2>&1; true
+else echo 'neither lsof nor fuser is installed' >&2; false; fi`
+	cmd := fmt.Sprintf("sh -c %s _ %s", shellescape.Quote(script), shellescape.Quote(path))
+	out, err := runCommand(ctx, client, cmd, opts)
+	if err != nil {
+		return nil, fmt.Errorf("listing processes using %s: %w", path, err)
+	}
+	header, rest, _ := strings.Cut(out, "\n")
+	if strings.TrimSpace(header) == "#fuser" {
+		return parseFuserOutput(rest), nil
+	}
+	return parseLsofOutput(rest), nil
+}
+
+// parseLsofOutput parses lsof -F pcLfa output: a p line starts each
+// process, followed by its c and L lines, then f and a lines for each of
+// its descriptors on the file.
+func parseLsofOutput(output string) []FileProcess {
+	var procs []FileProcess
+	for _, line := range strings.Split(output, "\n") {
+		if line == "" {
+			continue
+		}
+		value := line[1:]
+		switch line[0] {
+		case 'p':
+			pid, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			procs = append(procs, FileProcess{PID: pid})
+		case 'c', 'L', 'a':
+			if len(procs) == 0 {
+				continue
+			}
+			proc := &procs[len(procs)-1]
+			switch line[0] {
+			case 'c':
+				proc.Command = value
+			case 'L':
+				proc.User = value
+			case 'a':
+				proc.Writing = proc.Writing || value == "w" || value == "u"
+			}
+		}
+	}
+	return procs
+}
+
+// parseFuserOutput parses fuser -v output, whose first line names the
+// file and gives its first process:
+//
+//	                     USER        PID ACCESS COMMAND
+//	/var/log/syslog:     syslog      812 F.... rsyslogd
+//
+// An F in ACCESS means open for writing, f open for reading.
+func parseFuserOutput(output string) []FileProcess {
+	var procs []FileProcess
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && strings.HasSuffix(fields[0], ":") {
+			fields = fields[1:]
+		}
+		if len(fields) < 4 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue // the header, or a "kernel" entry
+		}
+		procs = append(procs, FileProcess{
+			PID:     pid,
+			Command: strings.Join(fields[3:], " "),
+			User:    fields[0],
+			Writing: strings.Contains(fields[2], "F"),
+		})
+	}
+	return procs
+}
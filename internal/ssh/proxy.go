@@ -0,0 +1,121 @@
+package ssh
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"log-monitor/internal/logger"
+
+	"golang.org/x/net/proxy"
+)
+
+// dialTCP opens a TCP connection to addr, through proxyURL when it is set.
+// Supported proxies are socks5:// (target resolved locally), socks5h://
+// (resolved by the proxy) and http:// (CONNECT), with optional user:password,
+// and ws:// or wss:// tunnels (see dialWebSocket).
+func dialTCP(ctx context.Context, proxyURL, addr string) (net.Conn, error) {
+	var d net.Dialer
+	if proxyURL == "" {
+		return d.DialContext(ctx, "tcp", addr)
+	}
+	if strings.HasPrefix(proxyURL, "ws://") || strings.HasPrefix(proxyURL, "wss://") {
+		return dialWebSocket(ctx, proxyURL, addr)
+	}
+
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing proxy: %w", err)
+	}
+	logger.Log("ssh", "dialing %s via proxy %s", addr, u.Redacted())
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		conn, err := socks5Dial(ctx, u, addr)
+		if err != nil {
+			return nil, fmt.Errorf("proxy %s: %w", u.Host, err)
+		}
+		return conn, nil
+	case "http":
+	default:
+		return nil, fmt.Errorf("proxy %s: unsupported scheme %q", u.Host, u.Scheme)
+	}
+
+	conn, err := d.DialContext(ctx, "tcp", u.Host)
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s: %w", u.Host, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	tunnel, err := httpConnect(conn, u, addr)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %w", u.Host, err)
+	}
+	conn.SetDeadline(time.Time{})
+	return tunnel, nil
+}
+
+// socks5Dial connects to addr through a SOCKS5 proxy. With socks5:// the
+// target is resolved here and the proxy is given its address; socks5h://
+// leaves resolving it to the proxy.
+func socks5Dial(ctx context.Context, u *url.URL, addr string) (net.Conn, error) {
+	if u.Scheme == "socks5" {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) == nil {
+			ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			addr = net.JoinHostPort(ips[0].IP.String(), port)
+		}
+	}
+	var auth *proxy.Auth
+	if u.User != nil {
+		pass, _ := u.User.Password()
+		auth = &proxy.Auth{User: u.User.Username(), Password: pass}
+	}
+	d, err := proxy.SOCKS5("tcp", u.Host, auth, &net.Dialer{})
+	if err != nil {
+		return nil, err
+	}
+	return d.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+}
+
+// httpConnect opens a tunnel to addr with an HTTP CONNECT request. The
+// returned conn includes anything the proxy sent after its response.
+func httpConnect(conn net.Conn, u *url.URL, addr string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if u.User != nil {
+		pass, _ := u.User.Password()
+		cred := base64.StdEncoding.EncodeToString([]byte(u.User.Username() + ":" + pass))
+		req.Header.Set("Proxy-Authorization", "Basic "+cred)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		return nil, err
+	}
+	// The body of a successful CONNECT is the tunnel itself; don't read it.
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CONNECT %s: %s", addr, resp.Status)
+	}
+	return &bufferedConn{Conn: conn, r: r}, nil
+}
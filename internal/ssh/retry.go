@@ -0,0 +1,71 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/logger"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// dialBackoff is the wait before the first retry; it doubles for each one.
+const dialBackoff = 500 * time.Millisecond
+
+// dial connects to a server like dialOnce, trying again up to
+// srv.DialRetries times when the attempt failed for a reason that may not
+// last, such as a dropped SYN or a handshake cut short by a busy sshd. The
+// waits are jittered so servers behind the same flaky link aren't retried
+// in lockstep. All attempts share ctx's deadline.
+func (p *Pool) dial(ctx context.Context, srv config.ServerConfig) (*ssh.Client, ssh.PublicKey, error) {
+	for attempt := 0; ; attempt++ {
+		client, hostKey, err := p.dialOnce(ctx, srv)
+		p.countDial(ServerKey(srv), err)
+		if err == nil || attempt >= srv.DialRetries || ctx.Err() != nil || !transientDialError(err) {
+			return client, hostKey, err
+		}
+		// Wait between half and all of the backoff
+		backoff := dialBackoff << min(attempt, 5)
+		wait := backoff/2 + rand.N(backoff/2)
+		logger.Log("ssh", "dial %s failed (attempt %d of %d), retrying in %s: %v",
+			srv.Host, attempt+1, srv.DialRetries+1, wait.Round(time.Millisecond), err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, nil, err
+		}
+	}
+}
+
+// transientDialError reports whether a failed dial is worth retrying:
+// timeouts, refused, reset or unreachable connections, temporary DNS
+// failures and connections closed during the handshake. Authentication and
+// host key failures are not.
+func transientDialError(err error) bool {
+	var hkErr *HostKeyError
+	var ppErr *PassphraseError
+	if errors.As(err, &hkErr) || errors.As(err, &ppErr) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+	for _, errno := range []error{syscall.ECONNREFUSED, syscall.ECONNRESET, syscall.ECONNABORTED,
+		syscall.EHOSTUNREACH, syscall.ENETUNREACH, syscall.ETIMEDOUT} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,84 @@
+package ssh
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// TailRotation is a change of the tailed file that tail -F followed, told
+// on its stderr. The lines that come after it are from the new file.
+type TailRotation int
+
+const (
+	RotationReplaced  TailRotation = iota // another file took the name, e.g. after logrotate
+	RotationTruncated                     // the file was truncated in place (copytruncate)
+	RotationGone                          // the file went away; tail waits for it to come back
+	RotationAppeared                      // a file took the name again after it was gone
+)
+
+// rotationNotices are what GNU and busybox tail print on stderr for each
+// rotation.
+var rotationNotices = []struct {
+	text     string
+	rotation TailRotation
+}{
+	{"has been replaced", RotationReplaced},
+	{"file truncated", RotationTruncated},
+	{"has become inaccessible", RotationGone},
+	{"cannot open", RotationGone},
+	{"can't open", RotationGone},
+	{"has appeared", RotationAppeared},
+}
+
+// tailStderr keeps what tail prints on stderr, to tell why it exited, and
+// reports rotations as their notices arrive.
+type tailStderr struct {
+	mu        sync.Mutex
+	all       bytes.Buffer
+	partial   []byte
+	rotations chan TailRotation
+}
+
+func newTailStderr() *tailStderr {
+	return &tailStderr{rotations: make(chan TailRotation, 8)}
+}
+
+func (s *tailStderr) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.all.Write(p)
+	s.partial = append(s.partial, p...)
+	for {
+		i := bytes.IndexByte(s.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(s.partial[:i])
+		s.partial = s.partial[i+1:]
+		for _, n := range rotationNotices {
+			if strings.Contains(line, n.text) {
+				select {
+				case s.rotations <- n.rotation:
+				default:
+					// Nobody is reading them: the tail runs in the background
+				}
+				break
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// String returns everything tail printed on stderr.
+func (s *tailStderr) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.all.String()
+}
+
+// Rotations returns the rotations of the file tail followed, as they happen.
+// A few are kept while nobody reads them; later ones are dropped.
+func (t *Tailer) Rotations() <-chan TailRotation {
+	return t.rotations
+}
@@ -0,0 +1,189 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"log-monitor/internal/logger"
+
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Servers cap the sessions open at once on a connection (sshd's MaxSessions,
+// often lowered to 2 or 3). Sessions beyond the cap are queued here rather
+// than failing: each connection has a limit, taken from the server's
+// max_sessions or learned when the server refuses a session while others
+// are open.
+
+// sessionLimiter counts the sessions open on one connection.
+type sessionLimiter struct {
+	mu    sync.Mutex
+	limit int           // most sessions allowed at once, 0 if unknown
+	open  int           // sessions open or being opened
+	freed chan struct{} // closed (and replaced) when a session ends
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[*gossh.Client]*sessionLimiter)
+
+	// Every open session, for the kill switch (Pool.KillAll).
+	activeMu sync.Mutex
+	active   = make(map[*session]struct{})
+)
+
+// limiterFor returns the limiter of a connection, creating it if needed.
+func limiterFor(client *gossh.Client) *sessionLimiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	l, ok := limiters[client]
+	if !ok {
+		l = &sessionLimiter{freed: make(chan struct{})}
+		limiters[client] = l
+	}
+	return l
+}
+
+// openSessions returns how many sessions are open on client.
+func openSessions(client *gossh.Client) int {
+	limitersMu.Lock()
+	l, ok := limiters[client]
+	limitersMu.Unlock()
+	if !ok {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.open
+}
+
+// setSessionLimit caps the sessions open at once on client; 0 leaves it
+// to be learned from the server.
+func setSessionLimit(client *gossh.Client, n int) {
+	l := limiterFor(client)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = n
+}
+
+// forgetLimiter drops the limiter of a closed connection.
+func forgetLimiter(client *gossh.Client) {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	delete(limiters, client)
+}
+
+// acquire waits for a free session slot.
+func (l *sessionLimiter) acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.limit == 0 || l.open < l.limit {
+			l.open++
+			l.mu.Unlock()
+			return nil
+		}
+		freed := l.freed
+		l.mu.Unlock()
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for a free session: %w", ctx.Err())
+		}
+	}
+}
+
+// release frees a session slot and wakes the waiters.
+func (l *sessionLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.open--
+	close(l.freed)
+	l.freed = make(chan struct{})
+}
+
+// refused handles the server refusing a session. If other sessions are
+// open, the server's cap was reached: the limit is lowered to them and the
+// caller should wait and retry. Otherwise the refusal is final.
+func (l *sessionLimiter) refused() (retry bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	others := l.open - 1
+	if others < 1 {
+		return false
+	}
+	if l.limit == 0 || others < l.limit {
+		logger.Log("ssh", "server refused a session with %d open; queueing sessions beyond that", others)
+		l.limit = others
+	}
+	return true
+}
+
+// session is an SSH session holding a slot of its connection's limiter,
+// which is freed on Close.
+type session struct {
+	*gossh.Session
+	release func()
+	once    sync.Once
+}
+
+// Close closes the session and frees its slot.
+func (s *session) Close() error {
+	err := s.Session.Close()
+	s.once.Do(func() {
+		activeMu.Lock()
+		delete(active, s)
+		activeMu.Unlock()
+		s.release()
+	})
+	return err
+}
+
+// closeSessions closes every open session and returns how many there were.
+func closeSessions() int {
+	activeMu.Lock()
+	open := make([]*session, 0, len(active))
+	for s := range active {
+		open = append(open, s)
+	}
+	activeMu.Unlock()
+	for _, s := range open {
+		s.Close()
+	}
+	return len(open)
+}
+
+// newSession opens a session once the connection has a free slot, with the
+// local SSH agent forwarded into it when opts.ForwardAgent is set.
+func newSession(ctx context.Context, client *gossh.Client, opts CommandOpts) (*session, error) {
+	l := limiterFor(client)
+	for {
+		if err := l.acquire(ctx); err != nil {
+			return nil, err
+		}
+		sess, err := client.NewSession()
+		if err != nil {
+			var openErr *gossh.OpenChannelError
+			retry := errors.As(err, &openErr) && openErr.Reason == gossh.Prohibited && l.refused()
+			l.release()
+			if retry {
+				continue
+			}
+			return nil, fmt.Errorf("creating session: %w", err)
+		}
+		s := &session{Session: sess, release: l.release}
+		activeMu.Lock()
+		active[s] = struct{}{}
+		activeMu.Unlock()
+		if opts.ForwardAgent {
+			if err := agent.RequestAgentForwarding(sess); err != nil {
+				s.Close()
+				return nil, fmt.Errorf("requesting agent forwarding: %w", err)
+			}
+		}
+		return s, nil
+	}
+}
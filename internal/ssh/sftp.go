@@ -0,0 +1,193 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"log-monitor/internal/logger"
+
+	"github.com/pkg/sftp"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// SFTP is spoken by github.com/pkg/sftp, on a session of our own: each
+// operation opens its own "sftp" subsystem session, the way runCommand
+// opens a session per command, so it counts against the server's session
+// limit and is closed when its context is done.
+
+// sftpConn is one SFTP session.
+type sftpConn struct {
+	*sftp.Client
+	sess *session
+	done chan struct{}
+}
+
+// openSFTP starts the sftp subsystem and negotiates the protocol version.
+// The session is closed when ctx is done, failing any request in progress.
+func openSFTP(ctx context.Context, client *gossh.Client) (*sftpConn, error) {
+	sess, err := newSession(ctx, client, CommandOpts{})
+	if err != nil {
+		return nil, err
+	}
+	w, err := sess.StdinPipe()
+	if err != nil {
+		sess.Close()
+		return nil, fmt.Errorf("stdin pipe: %w", err)
+	}
+	r, err := sess.StdoutPipe()
+	if err != nil {
+		sess.Close()
+		return nil, fmt.Errorf("stdout pipe: %w", err)
+	}
+	if err := sess.RequestSubsystem("sftp"); err != nil {
+		sess.Close()
+		return nil, fmt.Errorf("starting sftp subsystem: %w", err)
+	}
+
+	c := &sftpConn{sess: sess, done: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			sess.Close()
+		case <-c.done:
+		}
+	}()
+
+	if c.Client, err = sftp.NewClientPipe(r, w); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("sftp init: %w", err)
+	}
+	return c, nil
+}
+
+func (c *sftpConn) Close() error {
+	close(c.done)
+	if c.Client != nil {
+		c.Client.Close()
+	}
+	return c.sess.Close()
+}
+
+// sftpBackend does file operations over SFTP. It has no way to change
+// user, so it can't serve sudo servers (config validation rejects that).
+type sftpBackend struct{}
+
+func (sftpBackend) ListFiles(ctx context.Context, client *gossh.Client, dir string, patterns []string, opts CommandOpts) ([]FileInfo, error) {
+	c, err := openSFTP(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", dir, sftpCtxErr(ctx, err))
+	}
+	defer c.Close()
+
+	entries, err := c.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", dir, sftpCtxErr(ctx, err))
+	}
+
+	var files []FileInfo
+	for _, e := range entries {
+		if e.Mode()&os.ModeSymlink != 0 {
+			// Show what the link points at, like a rotated log's "current" link
+			if target, err := c.Stat(path.Join(dir, e.Name())); err == nil {
+				e = target
+			}
+		}
+		files = append(files, FileInfo{
+			Name:    e.Name(),
+			Size:    e.Size(),
+			ModTime: e.ModTime(),
+			IsDir:   e.IsDir(),
+		})
+	}
+
+	if len(patterns) > 0 {
+		files = FilterByPatterns(files, patterns)
+	}
+
+	sortListing(files)
+	return files, nil
+}
+
+func (sftpBackend) StatFile(ctx context.Context, client *gossh.Client, remotePath string, opts CommandOpts) (*FileInfo, error) {
+	c, err := openSFTP(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", remotePath, sftpCtxErr(ctx, err))
+	}
+	defer c.Close()
+
+	info, err := c.Stat(remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", remotePath, sftpCtxErr(ctx, err))
+	}
+	return &FileInfo{
+		Name:    path.Base(remotePath),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		IsDir:   info.IsDir(),
+	}, nil
+}
+
+func (sftpBackend) DownloadFile(client *gossh.Client, remotePath, localPath string, opts CommandOpts, ctx context.Context, progressCh chan<- int64) error {
+	if progressCh != nil {
+		defer close(progressCh)
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
+		return fmt.Errorf("creating local directory: %w", err)
+	}
+
+	logger.Log("ssh", "DownloadFile (sftp): %s → %s", remotePath, localPath)
+
+	c, err := openSFTP(ctx, client)
+	if err != nil {
+		return sftpCtxErr(ctx, err)
+	}
+	defer c.Close()
+
+	f, err := os.Create(localPath)
+	if err != nil {
+		return fmt.Errorf("creating local file: %w", err)
+	}
+	defer f.Close()
+
+	err = c.download(remotePath, downloadWriter(ctx, f, opts, progressCh))
+	if errors.Is(err, errDownloadLimit) {
+		logger.Log("ssh", "DownloadFile (sftp): %s stopped at %d bytes, it grew during the download", remotePath, opts.DownloadLimit)
+		return nil
+	}
+	if err != nil {
+		// On cancel/error, remove partial file
+		f.Close()
+		os.Remove(localPath)
+		return fmt.Errorf("downloading file: %w", sftpCtxErr(ctx, err))
+	}
+	return nil
+}
+
+// sftpCtxErr reports a cancelled or timed-out context instead of the
+// closed-session error it caused.
+func sftpCtxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// download copies a remote file to w, with several reads in flight.
+func (c *sftpConn) download(remotePath string, w io.Writer) error {
+	f, err := c.Open(remotePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteTo(w)
+	return err
+}
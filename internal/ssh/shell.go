@@ -0,0 +1,52 @@
+package ssh
+
+import (
+	"strings"
+
+	"al.essio.dev/pkg/shellescape"
+)
+
+// The server runs every command through the login shell of the user.
+// Commands are written for a POSIX sh and sent as they are to sh, bash,
+// dash, ksh or zsh; for a login shell that isn't one, they are handed to
+// /bin/sh, quoted the way that shell expects.
+const (
+	ShellCsh  = "csh" // csh and tcsh
+	ShellFish = "fish"
+)
+
+// commandLine returns the command line sent to the server to run the POSIX
+// sh command cmd: inside the setup command, if any, under the login shell
+// of opts.
+func commandLine(cmd string, opts CommandOpts) string {
+	return forShell(withSetup(cmd, opts), opts.Shell)
+}
+
+// forShell returns a command line that makes shell run the POSIX sh
+// command cmd.
+func forShell(cmd, shell string) string {
+	switch shell {
+	case ShellCsh:
+		return "/bin/sh -c " + cshQuote(cmd)
+	case ShellFish:
+		return "/bin/sh -c " + fishQuote(cmd)
+	default:
+		return cmd
+	}
+}
+
+// cshQuote quotes s for csh and tcsh. Single quotes work as in sh, except
+// that history substitution (!) still applies inside them and a newline
+// has to be escaped.
+func cshQuote(s string) string {
+	q := shellescape.Quote(s)
+	q = strings.ReplaceAll(q, "!", `\!`)
+	return strings.ReplaceAll(q, "\n", "\\\n")
+}
+
+// fishQuote quotes s for fish, where a backslash escapes a single quote or
+// another backslash inside single quotes.
+func fishQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "'" + r.Replace(s) + "'"
+}
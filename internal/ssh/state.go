@@ -0,0 +1,111 @@
+package ssh
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/logger"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ConnState is the state of a server's pooled connection.
+type ConnState int
+
+const (
+	StateDisconnected ConnState = iota
+	StateConnecting
+	StateConnected
+	StateError // the last attempt failed or the connection was lost
+)
+
+// String returns the state's name, e.g. "connected".
+func (s ConnState) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateError:
+		return "failed"
+	default:
+		return "disconnected"
+	}
+}
+
+// StateChange reports that the connection for a server key (see ServerKey)
+// moved to a new state. Err is set for StateError. A connected server is
+// reported again with Latency set each time its round trip is measured.
+type StateChange struct {
+	Key     string
+	State   ConnState
+	Err     error
+	Latency time.Duration // round trip of the last keepalive, 0 if not measured
+}
+
+// SetStateCallback registers fn to be told about connection state changes.
+// It is called from whichever goroutine caused the change, so it must not
+// block or call back into the pool.
+func (p *Pool) SetStateCallback(fn func(StateChange)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onState = fn
+}
+
+// notify reports a state change. Must be called without p.mu held.
+func (p *Pool) notify(key string, state ConnState, err error) {
+	p.mu.Lock()
+	fn := p.onState
+	p.mu.Unlock()
+	if fn != nil {
+		fn(StateChange{Key: key, State: state, Err: err})
+	}
+}
+
+// errServerClosed is the loss of a connection the server closed without
+// saying why. sshd does so when ClientAliveCountMax keepalives go unanswered
+// or an idle timeout such as UnusedConnectionTimeout expires.
+var errServerClosed = errors.New("closed by the server (idle or keepalive timeout?)")
+
+// describeLoss returns why a connection ended, given what Wait returned.
+func describeLoss(err error) error {
+	if err == nil || errors.Is(err, io.EOF) {
+		return errServerClosed
+	}
+	return err
+}
+
+// LostReason returns why the server's last pooled connection ended, or nil
+// if it is connected or was closed on purpose (idle timeout, kill switch).
+func (p *Pool) LostReason(srv config.ServerConfig) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lost[ServerKey(srv)]
+}
+
+// watch waits for a pooled connection to end. If it is still the pooled
+// connection for key, it died on its own: it is dropped from the pool so the
+// next GetClient dials afresh, and the loss is reported.
+func (p *Pool) watch(key string, c *ssh.Client) {
+	err := c.Wait()
+	forgetLimiter(c)
+	p.mu.Lock()
+	lost := p.clients[key] == c
+	if lost {
+		delete(p.clients, key)
+		delete(p.latency, key)
+		p.lost[key] = describeLoss(err)
+	}
+	p.mu.Unlock()
+	if !lost {
+		return
+	}
+	logger.Log("ssh", "connection to %s lost: %v", key, err)
+	if err != nil {
+		p.notify(key, StateError, err)
+	} else {
+		p.notify(key, StateDisconnected, nil)
+	}
+}
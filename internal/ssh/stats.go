@@ -0,0 +1,125 @@
+package ssh
+
+import (
+	"net"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"log-monitor/internal/config"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// PoolStats is a snapshot of the pool's connections.
+type PoolStats struct {
+	Open    int           // pooled connections
+	Busy    int           // connections with a tail or download running
+	Servers []ServerStats // every server dialed so far, by key
+}
+
+// ServerStats counts what the pool did with one server since it was
+// created. Bytes are those on the wire, encrypted and compressed, and
+// include a hop's traffic for the servers behind it.
+type ServerStats struct {
+	Key           string // ServerKey of the server
+	Connected     bool
+	Dials         int           // connection attempts
+	Failures      int           // attempts that failed
+	BytesSent     int64         // written to the server's connection
+	BytesReceived int64         // read from it
+	Sessions      int           // sessions open on the connection now
+	Latency       time.Duration // round trip of the last keepalive, 0 if unknown
+}
+
+// serverCounters accumulates a server's ServerStats. Dials and failures are
+// guarded by Pool.mu; the byte counts are updated by the connections.
+type serverCounters struct {
+	dials, failures int
+	sent, received  atomic.Int64
+}
+
+// counters returns the counters of a server key, creating them if needed.
+// The caller must hold p.mu.
+func (p *Pool) counters(key string) *serverCounters {
+	c, ok := p.traffic[key]
+	if !ok {
+		c = &serverCounters{}
+		p.traffic[key] = c
+	}
+	return c
+}
+
+// countDial records a connection attempt to a server and whether it failed.
+func (p *Pool) countDial(key string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c := p.counters(key)
+	c.dials++
+	if err != nil {
+		c.failures++
+	}
+}
+
+// countedConn returns conn counting the bytes through it for srv.
+func (p *Pool) countedConn(srv config.ServerConfig, conn net.Conn) net.Conn {
+	p.mu.Lock()
+	c := p.counters(ServerKey(srv))
+	p.mu.Unlock()
+	return &countingConn{Conn: conn, c: c}
+}
+
+// countingConn is a connection that adds its traffic to a server's counters.
+type countingConn struct {
+	net.Conn
+	c *serverCounters
+}
+
+func (cc *countingConn) Read(b []byte) (int, error) {
+	n, err := cc.Conn.Read(b)
+	cc.c.received.Add(int64(n))
+	return n, err
+}
+
+func (cc *countingConn) Write(b []byte) (int, error) {
+	n, err := cc.Conn.Write(b)
+	cc.c.sent.Add(int64(n))
+	return n, err
+}
+
+// Stats returns how many connections the pool holds and what it did with
+// each server.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	s := PoolStats{Open: len(p.clients)}
+	for key := range p.clients {
+		if p.busy[key] > 0 {
+			s.Busy++
+		}
+	}
+	clients := make(map[string]*gossh.Client, len(p.clients))
+	for key, c := range p.traffic {
+		client, ok := p.clients[key]
+		if ok {
+			clients[key] = client
+		}
+		s.Servers = append(s.Servers, ServerStats{
+			Key:           key,
+			Connected:     ok,
+			Dials:         c.dials,
+			Failures:      c.failures,
+			BytesSent:     c.sent.Load(),
+			BytesReceived: c.received.Load(),
+			Latency:       p.latency[key],
+		})
+	}
+	p.mu.Unlock()
+
+	for i := range s.Servers {
+		if client, ok := clients[s.Servers[i].Key]; ok {
+			s.Servers[i].Sessions = openSessions(client)
+		}
+	}
+	sort.Slice(s.Servers, func(i, j int) bool { return s.Servers[i].Key < s.Servers[j].Key })
+	return s
+}
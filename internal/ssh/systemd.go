@@ -0,0 +1,40 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+
+	"al.essio.dev/pkg/shellescape"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// Both systemctl and journalctl exit non-zero for stopped or unknown units,
+// and say why on stderr, so their output is returned either way. The pager
+// and colors are turned off for reading it back.
+const systemdEnv = "SYSTEMD_PAGER= SYSTEMD_COLORS=0"
+
+// ServiceStatus returns the output of `systemctl status` for a systemd unit,
+// without its journal lines.
+func ServiceStatus(ctx context.Context, client *gossh.Client, unit string, opts CommandOpts) (string, error) {
+	cmd := fmt.Sprintf("sh -c %s _ %s",
+		shellescape.Quote(systemdEnv+` systemctl status --no-pager --full --lines=0 -- "$1" 2>&1; true`),
+		shellescape.Quote(unit))
+	out, err := runCommand(ctx, client, cmd, opts)
+	if err != nil {
+		return "", fmt.Errorf("systemctl status %s: %w", unit, err)
+	}
+	return out, nil
+}
+
+// ServiceJournal returns the last lines the journal holds for a systemd
+// unit. Reading it may need sudo or membership of systemd-journal.
+func ServiceJournal(ctx context.Context, client *gossh.Client, unit string, lines int, opts CommandOpts) (string, error) {
+	cmd := fmt.Sprintf("sh -c %s _ %s",
+		shellescape.Quote(fmt.Sprintf(systemdEnv+` journalctl --no-pager --output=short-iso --lines=%d --unit="$1" 2>&1; true`, lines)),
+		shellescape.Quote(unit))
+	out, err := runCommand(ctx, client, cmd, opts)
+	if err != nil {
+		return "", fmt.Errorf("journalctl for %s: %w", unit, err)
+	}
+	return out, nil
+}
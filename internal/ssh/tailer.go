@@ -2,25 +2,113 @@ package ssh
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
+	"time"
 
 	"al.essio.dev/pkg/shellescape"
 	gossh "golang.org/x/crypto/ssh"
 )
 
-// Tailer streams the output of `tail -f` on a remote file to a writer.
+// Tailer streams the output of `tail -F` on a remote file to a writer. It
+// follows the file's name, so rotations carry on with the new file.
 type Tailer struct {
 	cancel      context.CancelFunc
+	rotations   <-chan TailRotation
 	done        chan struct{}
 	mu          sync.Mutex
 	err         error
 	errCallback func(error)
 }
 
-// SetErrCallback sets a function to be called when the tail stream ends with an error
-// (e.g. SSH disconnect). The callback is invoked from a background goroutine.
+// TailExitReason is why a remote tail ended without being stopped.
+type TailExitReason int
+
+const (
+	TailChannelClosed  TailExitReason = iota // no exit status: the session or the connection went away
+	TailFileGone                             // the file was deleted or moved away
+	TailPermissionLost                       // the file can no longer be read
+	TailKilled                               // killed by a signal
+	TailExited                               // exited for another reason
+)
+
+// tailWaitTimeout bounds how long an ended stream waits for the exit status
+// of the remote tail; a dead connection never sends one.
+const tailWaitTimeout = 2 * time.Second
+
+// TailExitError is the error a Tailer ends with when its stream stops.
+type TailExitError struct {
+	Reason TailExitReason
+	Status int    // exit status, for TailExited
+	Signal string // signal name without SIG, for TailKilled
+	Stderr string // first line tail printed on stderr, if any
+	Err    error  // the error the session ended with
+}
+
+func (e *TailExitError) Error() string {
+	switch e.Reason {
+	case TailFileGone, TailPermissionLost:
+		return e.Stderr
+	case TailKilled:
+		return fmt.Sprintf("tail was killed by SIG%s", e.Signal)
+	case TailExited:
+		if e.Stderr != "" {
+			return fmt.Sprintf("tail exited with status %d: %s", e.Status, e.Stderr)
+		}
+		return fmt.Sprintf("tail exited with status %d", e.Status)
+	default:
+		return "the tail's channel closed without an exit status"
+	}
+}
+
+func (e *TailExitError) Unwrap() error {
+	return e.Err
+}
+
+// classifyTailExit works out why a tail ended from the error its session
+// ended with and what it printed on stderr.
+func classifyTailExit(waitErr error, stderr string) *TailExitError {
+	e := &TailExitError{Err: waitErr}
+	stderr = strings.TrimSpace(stderr)
+	if first, _, _ := strings.Cut(stderr, "\n"); first != "" {
+		e.Stderr = first
+	}
+	var exitErr *gossh.ExitError
+	switch {
+	case waitErr == nil:
+		e.Reason = TailExited
+	case errors.As(waitErr, &exitErr):
+		e.Status = exitErr.ExitStatus()
+		e.Signal = exitErr.Signal()
+		// A shell between us and tail reports a signal as 128+n.
+		switch e.Status {
+		case 128 + 9:
+			e.Signal = "KILL"
+		case 128 + 15:
+			e.Signal = "TERM"
+		}
+		switch {
+		case e.Signal != "":
+			e.Reason = TailKilled
+		case strings.Contains(stderr, "No such file"), strings.Contains(stderr, "has become inaccessible"):
+			e.Reason = TailFileGone
+		case strings.Contains(stderr, "Permission denied"), strings.Contains(stderr, "Operation not permitted"):
+			e.Reason = TailPermissionLost
+		default:
+			e.Reason = TailExited
+		}
+	default:
+		e.Reason = TailChannelClosed
+	}
+	return e
+}
+
+// SetErrCallback sets a function to be called when the tail stream ends with an error,
+// a *TailExitError unless reading the stream failed. The callback is invoked from a
+// background goroutine.
 func (t *Tailer) SetErrCallback(fn func(error)) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -30,9 +118,27 @@ func (t *Tailer) SetErrCallback(fn func(error)) {
 // StartTail begins tailing a remote file, writing output to w.
 // The returned Tailer can be stopped via Stop().
 func StartTail(ctx context.Context, client *gossh.Client, path string, lines int, w io.Writer, opts CommandOpts) (*Tailer, error) {
-	sess, err := client.NewSession()
+	return StartTailFiles(ctx, client, []string{path}, lines, w, opts)
+}
+
+// StartTailFiles tails several remote files in one session, like
+// `tail -F a b`: tail puts a "==> path <==" header before the lines of each
+// file whenever it switches between them.
+func StartTailFiles(ctx context.Context, client *gossh.Client, paths []string, lines int, w io.Writer, opts CommandOpts) (*Tailer, error) {
+	quoted := make([]string, len(paths))
+	for i, p := range paths {
+		quoted[i] = shellescape.Quote(p)
+	}
+	cmd := fmt.Sprintf("tail -n %d -F %s", lines, strings.Join(quoted, " "))
+	return startStream(ctx, client, cmd, w, opts)
+}
+
+// startStream runs a command that keeps printing, such as tail -F, and
+// copies its output to w until it is stopped or ends.
+func startStream(ctx context.Context, client *gossh.Client, cmd string, w io.Writer, opts CommandOpts) (*Tailer, error) {
+	sess, err := newSession(ctx, client, opts)
 	if err != nil {
-		return nil, fmt.Errorf("creating session: %w", err)
+		return nil, err
 	}
 
 	stdout, err := sess.StdoutPipe()
@@ -40,23 +146,18 @@ func StartTail(ctx context.Context, client *gossh.Client, path string, lines int
 		sess.Close()
 		return nil, fmt.Errorf("stdout pipe: %w", err)
 	}
+	// tail tells of rotations on stderr, and why it gave up once it has
+	// exited.
+	stderr := newTailStderr()
+	sess.Stderr = stderr
 
-	cmd := fmt.Sprintf("tail -n %d -f %s", lines, shellescape.Quote(path))
-	if opts.SudoPassword != "" {
-		cmd = fmt.Sprintf("sudo -S %s", cmd)
-		stdin, err := sess.StdinPipe()
-		if err != nil {
-			sess.Close()
-			return nil, fmt.Errorf("stdin pipe: %w", err)
-		}
-		if err := sess.Start(cmd); err != nil {
+	if opts.Sudo {
+		if err := startSudo(sess.Session, cmd, opts); err != nil {
 			sess.Close()
 			return nil, fmt.Errorf("starting tail: %w", err)
 		}
-		fmt.Fprintf(stdin, "%s\n", opts.SudoPassword)
-		stdin.Close()
 	} else {
-		if err := sess.Start(cmd); err != nil {
+		if err := sess.Start(commandLine(cmd, opts)); err != nil {
 			sess.Close()
 			return nil, fmt.Errorf("starting tail: %w", err)
 		}
@@ -64,8 +165,9 @@ func StartTail(ctx context.Context, client *gossh.Client, path string, lines int
 
 	ctx, cancel := context.WithCancel(ctx)
 	t := &Tailer{
-		cancel: cancel,
-		done:   make(chan struct{}),
+		cancel:    cancel,
+		rotations: stderr.rotations,
+		done:      make(chan struct{}),
 	}
 
 	go func() {
@@ -85,11 +187,16 @@ func StartTail(ctx context.Context, client *gossh.Client, path string, lines int
 			sess.Signal(gossh.SIGTERM)
 			sess.Close()
 		case err := <-copyDone:
+			if err == nil {
+				// The stream ended without us stopping it: the remote tail
+				// exited or the connection went away.
+				err = tailExit(sess, stderr)
+			}
 			t.mu.Lock()
 			t.err = err
 			cb := t.errCallback
 			t.mu.Unlock()
-			if err != nil && cb != nil {
+			if cb != nil {
 				cb(err)
 			}
 		}
@@ -98,12 +205,34 @@ func StartTail(ctx context.Context, client *gossh.Client, path string, lines int
 	return t, nil
 }
 
+// tailExit waits for the exit status of a tail whose stream ended and says
+// why it ended.
+func tailExit(sess *session, stderr *tailStderr) error {
+	waited := make(chan error, 1)
+	go func() {
+		waited <- sess.Wait()
+	}()
+	select {
+	case err := <-waited:
+		return classifyTailExit(err, stderr.String())
+	case <-time.After(tailWaitTimeout):
+		sess.Close()
+		return classifyTailExit(io.EOF, "")
+	}
+}
+
 // Stop cancels the tail and waits for the goroutine to finish.
 func (t *Tailer) Stop() {
 	t.cancel()
 	<-t.done
 }
 
+// Done returns a channel that is closed once the tail has ended, whether it
+// was stopped or the stream failed.
+func (t *Tailer) Done() <-chan struct{} {
+	return t.done
+}
+
 // Err returns any error that occurred during tailing.
 func (t *Tailer) Err() error {
 	t.mu.Lock()
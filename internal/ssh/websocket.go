@@ -0,0 +1,55 @@
+package ssh
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"log-monitor/internal/logger"
+
+	"golang.org/x/net/websocket"
+)
+
+// dialWebSocket runs the connection to addr inside a WebSocket to the
+// endpoint at rawURL (ws:// or wss://), for servers only reachable through
+// a web tunnel such as a cloud bastion gateway. The endpoint relays the
+// WebSocket's binary messages to the server's SSH port; gateways that take
+// the target from the URL get it from %host% and %port%. A user:password
+// in the URL is sent as basic authentication.
+func dialWebSocket(ctx context.Context, rawURL, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	rawURL = strings.NewReplacer("%host%", url.QueryEscape(host), "%port%", port).Replace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing tunnel URL: %w", err)
+	}
+	logger.Log("ssh", "dialing %s via WebSocket %s", addr, u.Redacted())
+
+	user := u.User
+	u.User = nil
+	origin := "http://" + u.Host
+	if u.Scheme == "wss" {
+		origin = "https://" + u.Host
+	}
+	cfg, err := websocket.NewConfig(u.String(), origin)
+	if err != nil {
+		return nil, fmt.Errorf("tunnel %s: %w", u.Host, err)
+	}
+	if user != nil {
+		pass, _ := user.Password()
+		cred := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + pass))
+		cfg.Header.Set("Authorization", "Basic "+cred)
+	}
+	ws, err := cfg.DialContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("tunnel %s: %w", u.Host, err)
+	}
+	ws.PayloadType = websocket.BinaryFrame
+	return ws, nil
+}
@@ -0,0 +1,281 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// data is the on-disk layout of the state file.
+type data struct {
+	// Notes maps server key -> remote file path -> note text.
+	Notes map[string]map[string]string `yaml:"notes,omitempty"`
+	// LastFiles maps server key -> folder path -> name of the file last
+	// opened there.
+	LastFiles map[string]map[string]string `yaml:"last_files,omitempty"`
+	// SudoKeychain lists the server keys whose sudo password was put in the
+	// OS keychain from the password prompt.
+	SudoKeychain map[string]bool `yaml:"sudo_keychain,omitempty"`
+	// SudoNoPasswd lists the server keys found to allow sudo without a
+	// password, so they aren't probed again.
+	SudoNoPasswd map[string]bool `yaml:"sudo_nopasswd,omitempty"`
+}
+
+// Store holds what the app remembers between runs, backed by a YAML file.
+// It is safe to use from any goroutine.
+type Store struct {
+	mu   sync.Mutex
+	path string
+	data data
+}
+
+// DefaultPath returns the state file location under the user config
+// directory (e.g. ~/.config/log-monitor/state.yaml).
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "log-monitor-state.yaml"
+	}
+	return filepath.Join(dir, "log-monitor", "state.yaml")
+}
+
+// Load reads the state file at path. A missing file yields an empty store.
+// On a read or parse error the returned store is still usable (empty), so
+// callers can report the error and carry on.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, fmt.Errorf("reading state: %w", err)
+	}
+	if err := yaml.Unmarshal(raw, &s.data); err != nil {
+		return s, fmt.Errorf("parsing state %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Note returns the note attached to a file on a server, or "".
+func (s *Store) Note(serverKey, path string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.Notes[serverKey][path]
+}
+
+// SetNote attaches a note to a file on a server. An empty note removes it.
+// Call Save to persist the change.
+func (s *Store) SetNote(serverKey, path, note string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if note == "" {
+		delete(s.data.Notes[serverKey], path)
+		if len(s.data.Notes[serverKey]) == 0 {
+			delete(s.data.Notes, serverKey)
+		}
+		return
+	}
+	if s.data.Notes == nil {
+		s.data.Notes = make(map[string]map[string]string)
+	}
+	if s.data.Notes[serverKey] == nil {
+		s.data.Notes[serverKey] = make(map[string]string)
+	}
+	s.data.Notes[serverKey][path] = note
+}
+
+// LastFile returns the name of the file last opened in a folder on a
+// server, or "".
+func (s *Store) LastFile(serverKey, folder string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.LastFiles[serverKey][folder]
+}
+
+// SetLastFile records the file last opened in a folder on a server. Call
+// Save to persist the change.
+func (s *Store) SetLastFile(serverKey, folder, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.LastFiles == nil {
+		s.data.LastFiles = make(map[string]map[string]string)
+	}
+	if s.data.LastFiles[serverKey] == nil {
+		s.data.LastFiles[serverKey] = make(map[string]string)
+	}
+	s.data.LastFiles[serverKey][folder] = name
+}
+
+// SudoInKeychain reports whether the sudo password of a server was stored
+// in the OS keychain from the password prompt.
+func (s *Store) SudoInKeychain(serverKey string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.SudoKeychain[serverKey]
+}
+
+// SetSudoInKeychain records whether the sudo password of a server is in
+// the OS keychain. Call Save to persist the change.
+func (s *Store) SetSudoInKeychain(serverKey string, stored bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !stored {
+		delete(s.data.SudoKeychain, serverKey)
+		return
+	}
+	if s.data.SudoKeychain == nil {
+		s.data.SudoKeychain = make(map[string]bool)
+	}
+	s.data.SudoKeychain[serverKey] = true
+}
+
+// SudoNoPasswd reports whether a server was found to allow sudo without a
+// password.
+func (s *Store) SudoNoPasswd(serverKey string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.SudoNoPasswd[serverKey]
+}
+
+// SetSudoNoPasswd records whether a server allows sudo without a password.
+// Call Save to persist the change.
+func (s *Store) SetSudoNoPasswd(serverKey string, nopasswd bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !nopasswd {
+		delete(s.data.SudoNoPasswd, serverKey)
+		return
+	}
+	if s.data.SudoNoPasswd == nil {
+		s.data.SudoNoPasswd = make(map[string]bool)
+	}
+	s.data.SudoNoPasswd[serverKey] = true
+}
+
+// Save writes the state file, replacing it atomically.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out, err := yaml.Marshal(&s.data)
+	if err != nil {
+		return fmt.Errorf("encoding state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("creating state dir: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, out, 0o600); err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing state: %w", err)
+	}
+	return nil
+}
+
+// sharedVersion is the layout version of export files.
+const sharedVersion = 1
+
+// Shared is the part of the state worth handing to others, as written to
+// an export file: the notes. Servers are named as in the config, so the
+// file works for teammates who log in as other users.
+type Shared struct {
+	Version int `yaml:"version"`
+	// Notes maps server name (or server key, for servers not in the
+	// config) -> remote file path -> note text.
+	Notes map[string]map[string]string `yaml:"notes,omitempty"`
+}
+
+// Export returns the shareable state, with each server key replaced by
+// name(key).
+func (s *Store) Export(name func(serverKey string) string) Shared {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sh := Shared{Version: sharedVersion, Notes: make(map[string]map[string]string)}
+	for key, notes := range s.data.Notes {
+		server := name(key)
+		if sh.Notes[server] == nil {
+			sh.Notes[server] = make(map[string]string)
+		}
+		for path, note := range notes {
+			sh.Notes[server][path] = note
+		}
+	}
+	return sh
+}
+
+// ImportResult says what Import did.
+type ImportResult struct {
+	Added   int      // notes taken over
+	Kept    int      // notes skipped because the file already has a different one
+	Unknown []string // servers of the file that couldn't be matched
+}
+
+// Import merges shared state into the store. key resolves a server named
+// in the file to its server key, and reports false for unknown servers.
+// Existing notes are kept. Call Save to persist the change.
+func (s *Store) Import(sh Shared, key func(server string) (string, bool)) ImportResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res ImportResult
+	for server, notes := range sh.Notes {
+		serverKey, ok := key(server)
+		if !ok {
+			res.Unknown = append(res.Unknown, server)
+			continue
+		}
+		for path, note := range notes {
+			switch old := s.data.Notes[serverKey][path]; {
+			case note == "" || old == note:
+			case old != "":
+				res.Kept++
+			default:
+				if s.data.Notes == nil {
+					s.data.Notes = make(map[string]map[string]string)
+				}
+				if s.data.Notes[serverKey] == nil {
+					s.data.Notes[serverKey] = make(map[string]string)
+				}
+				s.data.Notes[serverKey][path] = note
+				res.Added++
+			}
+		}
+	}
+	sort.Strings(res.Unknown)
+	return res
+}
+
+// WriteShared writes shared state to an export file.
+func WriteShared(path string, sh Shared) error {
+	out, err := yaml.Marshal(&sh)
+	if err != nil {
+		return fmt.Errorf("encoding export: %w", err)
+	}
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		return fmt.Errorf("writing export: %w", err)
+	}
+	return nil
+}
+
+// ReadShared reads an export file.
+func ReadShared(path string) (Shared, error) {
+	var sh Shared
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return sh, fmt.Errorf("reading export: %w", err)
+	}
+	if err := yaml.Unmarshal(raw, &sh); err != nil {
+		return sh, fmt.Errorf("parsing export %s: %w", path, err)
+	}
+	if sh.Version > sharedVersion {
+		return sh, fmt.Errorf("export %s has version %d, this build reads up to %d", path, sh.Version, sharedVersion)
+	}
+	return sh, nil
+}
@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+)
+
+// alignTimestampRe matches a leading timestamp (ISO 8601, syslog-style, or
+// time-only), optionally wrapped in brackets, followed by whitespace.
+var alignTimestampRe = regexp.MustCompile(
+	`^(\[?(?:\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?` +
+		`|[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}` +
+		`|\d{2}:\d{2}:\d{2}(?:[.,]\d+)?)\]?)\s+`)
+
+// alignLevelRe matches a log level token directly after the timestamp,
+// optionally bracketed and/or followed by a colon.
+var alignLevelRe = regexp.MustCompile(
+	`^(\[?(?i:ERROR|FATAL|PANIC|CRITICAL|WARN|WARNING|NOTICE|INFO|DEBUG|TRACE)\]?:?)\s+`)
+
+// clockRe matches the time of day inside a timestamp.
+var clockRe = regexp.MustCompile(`\d{2}:\d{2}:\d{2}`)
+
+// lineTimeOfDay returns the HH:MM:SS time of day of a line's leading
+// timestamp. ok is false when the line does not start with a timestamp.
+func lineTimeOfDay(line string) (string, bool) {
+	ts, _, _, ok := splitLogPrefix(line)
+	if !ok {
+		return "", false
+	}
+	at := clockRe.FindString(ts)
+	return at, at != ""
+}
+
+// splitLogPrefix splits a line into its leading timestamp, level token and
+// message body. ok is false when the line does not start with a timestamp.
+func splitLogPrefix(line string) (ts, level, body string, ok bool) {
+	m := alignTimestampRe.FindStringSubmatchIndex(line)
+	if m == nil {
+		return "", "", line, false
+	}
+	ts = line[m[2]:m[3]]
+	rest := line[m[1]:]
+	if lm := alignLevelRe.FindStringSubmatchIndex(rest); lm != nil {
+		level = rest[lm[2]:lm[3]]
+		rest = rest[lm[1]:]
+	}
+	return ts, level, rest, true
+}
+
+// alignColumns pads the timestamp and level columns of a line to the given
+// widths so message bodies line up vertically. Lines without a leading
+// timestamp (e.g. stack trace continuations) are returned unchanged.
+func alignColumns(line string, tsWidth, levelWidth int) string {
+	ts, level, body, ok := splitLogPrefix(line)
+	if !ok {
+		return line
+	}
+	var b strings.Builder
+	b.WriteString(padRight(ts, tsWidth))
+	b.WriteByte(' ')
+	if levelWidth > 0 {
+		b.WriteString(padRight(level, levelWidth))
+		b.WriteByte(' ')
+	}
+	b.WriteString(body)
+	return b.String()
+}
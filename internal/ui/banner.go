@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"strings"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/i18n"
+	"log-monitor/internal/ssh"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// bannerMaxLines caps each of the banner and the MOTD in the popups.
+const bannerMaxLines = 20
+
+// maybeShowBanner pops up the banner and MOTD of a server the first time
+// they are known in this run, with defaults.show_banner.
+func (m Model) maybeShowBanner(srv config.ServerConfig) Model {
+	if !m.cfg.Defaults.ShowBanner || m.modal != modalNone {
+		return m
+	}
+	key := ssh.ServerKey(srv)
+	info := m.pool.HostInfo(srv)
+	if m.bannersShown[key] || info.Banner == "" && info.MOTD == "" {
+		return m
+	}
+	m.bannersShown[key] = true
+	m.modal = modalBanner
+	return m
+}
+
+// bannerSummary renders the banner and MOTD of the current server for a
+// popup, or "" if it sent neither.
+func (m Model) bannerSummary() string {
+	if m.currentServer == nil {
+		return ""
+	}
+	info := m.pool.HostInfo(*m.currentServer)
+	var parts []string
+	if text := bannerLines(info.Banner); text != "" {
+		parts = append(parts, modalHintStyle.Render(i18n.T("Banner:"))+"\n"+text)
+	}
+	if text := bannerLines(info.MOTD); text != "" {
+		parts = append(parts, modalHintStyle.Render(i18n.T("Message of the day:"))+"\n"+text)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// bannerLines strips escape sequences from server-sent text and cuts it to
+// bannerMaxLines.
+func bannerLines(text string) string {
+	text = strings.TrimSpace(strings.ReplaceAll(ansi.Strip(text), "\r", ""))
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) > bannerMaxLines {
+		more := len(lines) - bannerMaxLines
+		lines = append(lines[:bannerMaxLines], modalHintStyle.Render(i18n.T("… %d more line(s)", more)))
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("15")).Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Size of the viewer and lines per tail chunk in a bench replay, close to
+// a large terminal receiving a busy log.
+const (
+	benchWidth      = 200
+	benchHeight     = 50
+	benchChunkLines = 64
+)
+
+// benchResult is the cost of one stage of the display pipeline.
+type benchResult struct {
+	stage   string
+	lines   int
+	bytes   int
+	elapsed time.Duration
+	allocs  uint64
+	alloced uint64
+}
+
+// Bench replays a local log file through the display pipeline as fast as
+// it can and writes the throughput and allocations of each stage to w:
+// colorizing, the tail filter (when filter is set) and the viewer, which
+// takes the file in tail-sized chunks and renders a frame after each.
+func Bench(path, filter string, w io.Writer) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading bench input: %w", err)
+	}
+	data = decodeLog(data, "auto")
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+
+	results := []benchResult{benchStage("colorize", lines, func() {
+		for _, line := range lines {
+			ColorizeLine(sanitizeLine(line, 8, "strip"))
+		}
+	})}
+	if filter != "" {
+		query := strings.ToLower(filter)
+		results = append(results, benchStage("filter", lines, func() {
+			for _, line := range lines {
+				if strings.Contains(strings.ToLower(line), query) {
+					highlightFilterANSI(line, filter)
+				}
+			}
+		}))
+	}
+	results = append(results, benchStage("viewer", lines, func() {
+		vp := NewViewerPaneModel()
+		vp.SetSize(benchWidth, benchHeight)
+		vp.SetTailFilter(filter)
+		for start := 0; start < len(lines); start += benchChunkLines {
+			end := min(start+benchChunkLines, len(lines))
+			vp.AppendTailData([]byte(strings.Join(lines[start:end], "\n") + "\n"))
+			vp.View(true)
+		}
+	}))
+
+	fmt.Fprintf(w, "%s: %s lines, %.1f MB\n\n", path, formatLineCount(len(lines)), float64(len(data))/(1<<20))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "stage\ttime\tlines/s\tMB/s\tallocs/line\tbytes/line\t")
+	for _, r := range results {
+		secs := r.elapsed.Seconds()
+		fmt.Fprintf(tw, "%s\t%s\t%.0f\t%.1f\t%.1f\t%.0f\t\n", r.stage, r.elapsed.Round(time.Millisecond),
+			float64(r.lines)/secs, float64(r.bytes)/secs/(1<<20),
+			float64(r.allocs)/float64(r.lines), float64(r.alloced)/float64(r.lines))
+	}
+	return tw.Flush()
+}
+
+// benchStage times run and counts the allocations it makes.
+func benchStage(stage string, lines []string, run func()) benchResult {
+	r := benchResult{stage: stage, lines: max(len(lines), 1)}
+	for _, line := range lines {
+		r.bytes += len(line) + 1
+	}
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	run()
+	r.elapsed = time.Since(start)
+	runtime.ReadMemStats(&after)
+	r.allocs = after.Mallocs - before.Mallocs
+	r.alloced = after.TotalAlloc - before.TotalAlloc
+	return r
+}
@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"log-monitor/internal/metrics"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// chartPaneHeight is the height of the chart pane, borders included.
+const chartPaneHeight = 10
+
+// ChartPaneModel draws one extracted metric as a braille line chart below
+// the viewer.
+type ChartPaneModel struct {
+	width  int
+	height int
+	metric string // metric being charted, "" when the chart is hidden
+}
+
+// SetSize sets the dimensions of the chart pane.
+func (cp *ChartPaneModel) SetSize(w, h int) {
+	cp.width = w
+	cp.height = h
+}
+
+// Visible reports whether a metric is being charted.
+func (cp ChartPaneModel) Visible() bool {
+	return cp.metric != ""
+}
+
+// Metric returns the metric being charted.
+func (cp ChartPaneModel) Metric() string {
+	return cp.metric
+}
+
+// SetMetric charts the named metric; "" hides the chart.
+func (cp *ChartPaneModel) SetMetric(name string) {
+	cp.metric = name
+}
+
+// View renders the chart of samples, oldest first.
+func (cp ChartPaneModel) View(samples []metrics.Sample, focused bool) string {
+	paneStyle, titleStyle := unfocusedPaneStyle, unfocusedTitleStyle
+	if focused {
+		paneStyle, titleStyle = focusedPaneStyle, focusedTitleStyle
+	}
+	innerW, innerH := max(cp.width-2, 1), max(cp.height-2, 1)
+	paneStyle = paneStyle.Width(innerW).Height(innerH)
+
+	title := fmt.Sprintf(" %s ", cp.metric)
+	var body string
+	if len(samples) == 0 {
+		body = dimStyle.Render("No values yet")
+	} else {
+		last := samples[len(samples)-1].Value
+		title = fmt.Sprintf(" %s: %s ", cp.metric, formatMetric(last))
+		body = renderChart(samples, innerW, innerH)
+	}
+	return placeTitleInBorder(paneStyle.Render(body), titleStyle.Render(title))
+}
+
+// braille dot bits by column (0-1) and row (0-3) within a cell.
+var brailleDots = [2][4]rune{
+	{0x01, 0x02, 0x04, 0x40},
+	{0x08, 0x10, 0x20, 0x80},
+}
+
+// renderChart plots samples over their time span in a width×height area:
+// the y axis labels on the left, a braille line and the time span below.
+// Samples falling into the same dot column are averaged.
+func renderChart(samples []metrics.Sample, width, height int) string {
+	s := metrics.Summarize(samples)
+	hi, lo := formatMetric(s.Max), formatMetric(s.Min)
+	gutter := max(len(hi), len(lo)) + 1
+	plotW, plotH := width-gutter, height-1
+	if plotW < 2 || plotH < 1 {
+		return ""
+	}
+
+	// Average the samples per dot column
+	cols := plotW * 2
+	sums := make([]float64, cols)
+	counts := make([]int, cols)
+	start, end := samples[0].At, samples[len(samples)-1].At
+	span := end.Sub(start)
+	for i, sm := range samples {
+		var col int
+		switch {
+		case span > 0:
+			col = int(float64(sm.At.Sub(start)) / float64(span) * float64(cols-1))
+		case len(samples) > 1:
+			// All at the same time: spread them out in order
+			col = i * (cols - 1) / (len(samples) - 1)
+		}
+		col = min(max(col, 0), cols-1)
+		sums[col] += sm.Value
+		counts[col]++
+	}
+
+	// Plot each column's dot and join it to the previous one
+	rows := plotH * 4
+	dotY := func(v float64) int {
+		if s.Max == s.Min {
+			return rows / 2
+		}
+		return int((s.Max - v) / (s.Max - s.Min) * float64(rows-1))
+	}
+	cells := make([][]rune, plotH)
+	for r := range cells {
+		cells[r] = make([]rune, plotW)
+	}
+	set := func(x, y int) {
+		cells[y/4][x/2] |= brailleDots[x%2][y%4]
+	}
+	prev := -1
+	for x := range cols {
+		if counts[x] == 0 {
+			continue
+		}
+		y := dotY(sums[x] / float64(counts[x]))
+		set(x, y)
+		if prev >= 0 {
+			for yy := min(prev, y); yy <= max(prev, y); yy++ {
+				set(x, yy)
+			}
+		}
+		prev = y
+	}
+
+	lineStyle := lipgloss.NewStyle().Foreground(accentColor)
+	var b strings.Builder
+	for r, row := range cells {
+		label := ""
+		switch r {
+		case 0:
+			label = hi
+		case plotH - 1:
+			label = lo
+		}
+		b.WriteString(dimStyle.Render(fmt.Sprintf("%*s ", gutter-1, label)))
+		for i := range row {
+			row[i] += 0x2800
+		}
+		b.WriteString(lineStyle.Render(string(row)))
+		b.WriteByte('\n')
+	}
+	from, to := start.Format("15:04:05"), end.Format("15:04:05")
+	axis := from + strings.Repeat(" ", max(plotW-len(from)-len(to), 1)) + to
+	if span < time.Second {
+		values := formatLineCount(len(samples)) + " values"
+		if len(samples) == 1 {
+			values = "1 value"
+		}
+		axis = values + " at " + to
+	}
+	b.WriteString(dimStyle.Render(strings.Repeat(" ", gutter) + axis))
+	return b.String()
+}
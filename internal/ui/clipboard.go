@@ -0,0 +1,22 @@
+package ui
+
+import (
+	"os"
+
+	"log-monitor/internal/logger"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+)
+
+// copyToClipboard puts text on the system clipboard. When no clipboard
+// utility is available (e.g. a headless Linux box) it falls back to an OSC 52
+// escape sequence, which most terminals honor even over SSH.
+func copyToClipboard(text string) {
+	err := clipboard.WriteAll(text)
+	if err == nil {
+		return
+	}
+	logger.Log("ui", "clipboard unavailable, using OSC 52: %v", err)
+	osc52.New(text).WriteTo(os.Stdout)
+}
@@ -3,107 +3,334 @@ package ui
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"log-monitor/internal/config"
+	"log-monitor/internal/events"
+	"log-monitor/internal/keychain"
 	"log-monitor/internal/logger"
 	"log-monitor/internal/ssh"
+	"log-monitor/internal/state"
 
 	tea "github.com/charmbracelet/bubbletea"
+	gossh "golang.org/x/crypto/ssh"
 )
 
 // connectAndListCmd connects to a server and lists files in a folder.
 func connectAndListCmd(pool *ssh.Pool, srv config.ServerConfig, folder config.LogFolder) tea.Cmd {
 	return func() tea.Msg {
-		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		showUpDir := len(srv.LogFolders) > 1 || folder.Root != ""
+		if srv.Local || isLocalKube(folder) {
+			// The files, or kubectl, are here: there is nothing to connect to
+			cmdCtx, cmdCancel := context.WithTimeout(context.Background(), srv.CommandTimeout)
+			defer cmdCancel()
+			files, err := listFolder(cmdCtx, nil, srv, folder, ssh.CommandOpts{})
+			if err != nil {
+				return FilesErrorMsg{Err: err}
+			}
+			return FilesLoadedMsg{Files: files, Dir: folder.Path, Root: folder.Root, ShowUpDir: showUpDir}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), srv.ConnectTimeout)
 		defer cancel()
 
 		logger.Log("cmd", "connecting to %s...", srv.Name)
 		client, err := pool.GetClient(ctx, srv)
 		if err != nil {
-			if strings.Contains(err.Error(), "sudo authentication failed") {
+			if isSudoAuthError(err) {
 				pool.ClearSudoPassword(srv)
 				return SudoRetryMsg{Server: srv}
 			}
-			return ConnectErrorMsg{Err: err, Server: srv}
+			return connectErrorMsg(srv, err)
 		}
 
-		opts := ssh.CommandOpts{}
-		if srv.Sudo {
-			opts.SudoPassword = pool.GetSudoPassword(srv)
-		}
+		opts := commandOpts(pool, srv)
 
-		files, err := ssh.ListFiles(client, folder.Path, folder.FilePatterns, opts)
+		cmdCtx, cmdCancel := context.WithTimeout(context.Background(), srv.CommandTimeout)
+		defer cmdCancel()
+
+		files, err := listFolder(cmdCtx, client, srv, folder, opts)
+		denied, err := splitPartial(err)
 		if err != nil {
-			if strings.Contains(err.Error(), "sudo authentication failed") {
+			if isSudoAuthError(err) {
 				pool.ClearSudoPassword(srv)
 				return SudoRetryMsg{Server: srv}
 			}
 			return FilesErrorMsg{Err: err}
 		}
 
-		showUpDir := len(srv.LogFolders) > 1
-		return FilesLoadedMsg{Files: files, Dir: folder.Path, ShowUpDir: showUpDir}
+		return FilesLoadedMsg{Files: files, Dir: folder.Path, Root: folder.Root, ShowUpDir: showUpDir, Denied: denied}
 	}
 }
 
-// countAndReadFileCmd reads the last N lines and counts total lines in a single command.
-func countAndReadFileCmd(pool *ssh.Pool, srv config.ServerConfig, fullPath string, tailLines int) tea.Cmd {
+// splitPartial separates an incomplete listing from a failed one: it
+// returns the number of entries ls could not read, and err unless that is
+// all it reports.
+func splitPartial(err error) (denied int, rest error) {
+	var partial *ssh.PartialListError
+	if errors.As(err, &partial) {
+		return partial.Denied, nil
+	}
+	return 0, err
+}
+
+// loadCatalogCmd fetches the shared server catalog.
+func loadCatalogCmd(cfg *config.Config, initial bool) tea.Cmd {
+	c, d := cfg.Catalog, cfg.Defaults
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), d.CommandTimeout)
+		defer cancel()
+		servers, err := config.FetchCatalog(ctx, c, d)
+		return CatalogLoadedMsg{Servers: servers, Err: err, Initial: initial}
+	}
+}
+
+// fetchEventsCmd polls the event feed.
+func fetchEventsCmd(cfg *config.Config) tea.Cmd {
+	c, timeout := cfg.Events, cfg.Defaults.CommandTimeout
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		evs, err := events.Fetch(ctx, c)
+		return EventsLoadedMsg{Events: evs, Err: err}
+	}
+}
+
+// acceptHostKeyCmd adds a host key the user accepted to known_hosts.
+func acceptHostKeyCmd(pool *ssh.Pool, srv config.ServerConfig, hkErr *ssh.HostKeyError) tea.Cmd {
+	return func() tea.Msg {
+		if err := pool.AcceptHostKey(hkErr); err != nil {
+			return ConnectErrorMsg{Err: err, Server: srv}
+		}
+		return HostKeyAcceptedMsg{Server: srv}
+	}
+}
+
+// connectErrorMsg turns a failed connection into the message to handle it:
+// a prompt when the user can resolve it (unknown host key, locked key),
+// otherwise ConnectErrorMsg.
+func connectErrorMsg(srv config.ServerConfig, err error) tea.Msg {
+	var hkErr *ssh.HostKeyError
+	if errors.As(err, &hkErr) {
+		return HostKeyPromptMsg{Server: srv, Err: hkErr}
+	}
+	var ppErr *ssh.PassphraseError
+	if errors.As(err, &ppErr) {
+		return KeyPassphraseMsg{Server: srv, Err: ppErr}
+	}
+	return ConnectErrorMsg{Err: err, Server: srv}
+}
+
+// unlockKeyCmd decrypts a private key with the entered passphrase.
+func unlockKeyCmd(pool *ssh.Pool, srv config.ServerConfig, ppErr *ssh.PassphraseError, passphrase string) tea.Cmd {
+	return func() tea.Msg {
+		if err := pool.UnlockKey(ppErr.KeyPath, passphrase); err != nil {
+			logger.Log("cmd", "unlock %s: %v", ppErr.KeyPath, err)
+			return KeyPassphraseMsg{Server: srv, Err: ppErr, Failed: err.Error()}
+		}
+		return KeyUnlockedMsg{Server: srv}
+	}
+}
+
+// commandOpts returns the remote command options for a server, including
+// any stored sudo password.
+func commandOpts(pool *ssh.Pool, srv config.ServerConfig) ssh.CommandOpts {
+	opts := ssh.CommandOpts{Compress: srv.Compression, ForwardAgent: srv.AgentForwarding, Setup: srv.SetupCommand, Shell: srv.Shell}
+	if srv.Sudo {
+		opts.Sudo = true
+		opts.SudoPassword = pool.GetSudoPassword(srv)
+		opts.Escalation = srv.Escalation
+		opts.SudoUser = srv.SudoUser
+	}
+	return opts
+}
+
+// probeSudoCmd connects to a server and checks whether sudo (or its
+// escalation command) works without a password.
+func probeSudoCmd(pool *ssh.Pool, srv config.ServerConfig) tea.Cmd {
 	return func() tea.Msg {
-		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), srv.ConnectTimeout)
 		defer cancel()
 
 		client, err := pool.GetClient(ctx, srv)
 		if err != nil {
-			return FileReadErrorMsg{Err: err}
+			return connectErrorMsg(srv, err)
 		}
+		cmdCtx, cmdCancel := context.WithTimeout(context.Background(), srv.CommandTimeout)
+		defer cmdCancel()
 
-		opts := ssh.CommandOpts{}
-		if srv.Sudo {
-			opts.SudoPassword = pool.GetSudoPassword(srv)
+		return SudoProbeMsg{Server: srv, NoPasswd: ssh.ProbeSudoNoPasswd(cmdCtx, client, commandOpts(pool, srv))}
+	}
+}
+
+// keychainLookupCmd looks up a stored sudo password in the OS keychain. The
+// lookup can block on an OS unlock dialog, so it runs off the update loop.
+func keychainLookupCmd(srv config.ServerConfig) tea.Cmd {
+	return func() tea.Msg {
+		return KeychainPasswordMsg{Server: srv, Password: keychain.GetSudoPassword(ssh.ServerKey(srv))}
+	}
+}
+
+// passwordCommandCmd runs a server's password_command for its sudo
+// password.
+func passwordCommandCmd(srv config.ServerConfig) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), srv.CommandTimeout)
+		defer cancel()
+		pw, err := keychain.CommandPassword(ctx, srv.PasswordCommand)
+		return CommandPasswordMsg{Server: srv, Password: pw, Err: err}
+	}
+}
+
+// keychainStoreCmd saves a sudo password in the OS keychain.
+func keychainStoreCmd(srv config.ServerConfig, password string) tea.Cmd {
+	return func() tea.Msg {
+		if err := keychain.SetSudoPassword(ssh.ServerKey(srv), password); err != nil {
+			return StatusMsg{Error: err.Error()}
 		}
+		return nil
+	}
+}
 
-		totalLines, content, err := ssh.CountAndReadFileContent(client, fullPath, tailLines, opts)
-		if err != nil {
-			return FileReadErrorMsg{Err: err}
+// saveStateCmd writes the local state file.
+func saveStateCmd(store *state.Store) tea.Cmd {
+	return func() tea.Msg {
+		if err := store.Save(); err != nil {
+			return StatusMsg{Error: err.Error()}
 		}
+		return nil
+	}
+}
 
-		startLine := 1
-		if totalLines > tailLines {
-			startLine = totalLines - tailLines + 1
+// keychainDeleteCmd removes a rejected sudo password from the OS keychain.
+func keychainDeleteCmd(srv config.ServerConfig) tea.Cmd {
+	return func() tea.Msg {
+		keychain.DeleteSudoPassword(ssh.ServerKey(srv))
+		return nil
+	}
+}
+
+// fetchHostInfoCmd asks the server for its hostname so the UI can show which
+// machine the connection actually reached.
+func fetchHostInfoCmd(pool *ssh.Pool, srv config.ServerConfig) tea.Cmd {
+	return func() tea.Msg {
+		if srv.Local {
+			hostname, err := os.Hostname()
+			if err != nil {
+				logger.Log("cmd", "hostname lookup failed: %v", err)
+				return nil
+			}
+			pool.SetRemoteHostname(srv, hostname)
+			return HostInfoMsg{Server: srv}
 		}
 
-		return FileContentMsg{Content: content, StartLine: startLine}
+		ctx, cancel := context.WithTimeout(context.Background(), srv.ConnectTimeout)
+		defer cancel()
+
+		client, err := pool.GetClient(ctx, srv)
+		if err != nil {
+			return nil
+		}
+		opts := commandOpts(pool, srv)
+		hostname, err := ssh.RemoteHostname(ctx, client, opts)
+		if err != nil {
+			logger.Log("cmd", "hostname lookup on %s failed: %v", srv.Name, err)
+			return nil
+		}
+		pool.SetRemoteHostname(srv, hostname)
+		if motd, err := ssh.RemoteMOTD(ctx, client, opts); err != nil {
+			logger.Log("cmd", "motd on %s: %v", srv.Name, err)
+		} else {
+			pool.SetRemoteMOTD(srv, motd)
+		}
+		return HostInfoMsg{Server: srv}
 	}
 }
 
-// startTailCmd starts tailing and sends data through a channel.
-func startTailCmd(pool *ssh.Pool, srv config.ServerConfig, fullPath string, ch chan<- []byte) tea.Cmd {
+// eagerConnectCmd dials a server in the background at startup so the pool
+// already holds its connection when it is selected.
+func eagerConnectCmd(pool *ssh.Pool, srv config.ServerConfig) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), srv.ConnectTimeout)
+		defer cancel()
+
+		_, err := pool.GetClient(ctx, srv)
+		return EagerConnectDoneMsg{Server: srv, Err: err}
+	}
+}
+
+// killAllCmd closes every remote session and connection.
+func killAllCmd(pool *ssh.Pool) tea.Cmd {
+	return func() tea.Msg {
+		sessions, conns := pool.KillAll()
+		return KillDoneMsg{Sessions: sessions, Conns: conns}
+	}
+}
+
+// isSudoAuthError reports whether err was caused by a rejected sudo password.
+func isSudoAuthError(err error) bool {
+	return strings.Contains(err.Error(), "sudo authentication failed")
+}
+
+// countAndReadFileCmd reads the last N lines and counts total lines in a single command.
+func countAndReadFileCmd(pool *ssh.Pool, srv config.ServerConfig, fullPath string, tailLines int) tea.Cmd {
 	return func() tea.Msg {
-		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		if srv.Local {
+			cmdCtx, cmdCancel := context.WithTimeout(context.Background(), srv.CommandTimeout)
+			defer cmdCancel()
+			totalLines, content, err := ssh.CountAndReadLocalFile(cmdCtx, fullPath, tailLines)
+			if err != nil {
+				return FileReadErrorMsg{Err: err}
+			}
+			return lastLinesMsg(content, totalLines, tailLines)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), srv.ConnectTimeout)
 		defer cancel()
 
 		client, err := pool.GetClient(ctx, srv)
 		if err != nil {
-			return TailErrorMsg{Err: err}
+			return FileReadErrorMsg{Err: err}
 		}
 
-		opts := ssh.CommandOpts{}
-		if srv.Sudo {
-			opts.SudoPassword = pool.GetSudoPassword(srv)
+		opts := commandOpts(pool, srv)
+
+		cmdCtx, cmdCancel := context.WithTimeout(context.Background(), srv.CommandTimeout)
+		defer cmdCancel()
+
+		totalLines, content, err := ssh.CountAndReadFileContent(cmdCtx, client, fullPath, tailLines, opts)
+		if err != nil {
+			if isSudoAuthError(err) {
+				pool.ClearSudoPassword(srv)
+				return SudoRetryMsg{Server: srv, FileOp: true}
+			}
+			return FileReadErrorMsg{Err: err}
 		}
+		return lastLinesMsg(content, totalLines, tailLines)
+	}
+}
 
-		// Create a writer that buffers complete lines and sends them to the channel
-		w := &chanWriter{ch: ch}
+// lastLinesMsg shows the last tailLines lines of a file of totalLines,
+// numbered as in the file.
+func lastLinesMsg(content string, totalLines, tailLines int) FileContentMsg {
+	startLine := 1
+	if totalLines > tailLines {
+		startLine = totalLines - tailLines + 1
+	}
+	return FileContentMsg{Content: content, StartLine: startLine}
+}
 
-		tailCtx, tailCancel := context.WithCancel(context.Background())
-		tailer, err := ssh.StartTail(tailCtx, client, fullPath, 0, w, opts)
+// startTailCmd starts tailing and sends data through a channel.
+func startTailCmd(pool *ssh.Pool, srv config.ServerConfig, fullPath string, ch chan<- []byte) tea.Cmd {
+	return func() tea.Msg {
+		// The writer buffers complete lines and sends them to the channel
+		tailer, cancel, err := startTailer(pool, srv, []string{fullPath}, 0, &chanWriter{ch: ch})
 		if err != nil {
-			tailCancel()
 			return TailErrorMsg{Err: err}
 		}
 
@@ -113,8 +340,42 @@ func startTailCmd(pool *ssh.Pool, srv config.ServerConfig, fullPath string, ch c
 			close(ch)
 		})
 
-		return TailStartedMsg{Tailer: tailer, Cancel: tailCancel}
+		return TailStartedMsg{Tailer: tailer, Cancel: cancel}
+	}
+}
+
+// startTailer runs tail -F on paths of srv, over its connection or, for a
+// local server, here. The connection is held until the tail ends.
+func startTailer(pool *ssh.Pool, srv config.ServerConfig, paths []string, lines int, w io.Writer) (*ssh.Tailer, context.CancelFunc, error) {
+	tailCtx, tailCancel := context.WithCancel(context.Background())
+	if srv.Local {
+		tailer, err := ssh.StartLocalTail(tailCtx, paths, lines, w)
+		if err != nil {
+			tailCancel()
+			return nil, nil, err
+		}
+		return tailer, tailCancel, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), srv.ConnectTimeout)
+	defer cancel()
+	client, err := pool.GetClient(ctx, srv)
+	if err != nil {
+		tailCancel()
+		return nil, nil, err
+	}
+	tailer, err := ssh.StartTailFiles(tailCtx, client, paths, lines, w, commandOpts(pool, srv))
+	if err != nil {
+		tailCancel()
+		return nil, nil, err
 	}
+
+	release := pool.Hold(srv)
+	go func() {
+		<-tailer.Done()
+		release()
+	}()
+	return tailer, tailCancel, nil
 }
 
 // waitForTailData waits for the next chunk of tail data from the channel.
@@ -128,25 +389,102 @@ func waitForTailData(ch <-chan []byte) tea.Cmd {
 	}
 }
 
-// downloadFileCmd downloads a remote file with progress reporting and cancellation support.
-func downloadFileCmd(pool *ssh.Pool, srv config.ServerConfig, remotePath, localDir, localFilename string, dlCtx context.Context, progressCh chan<- int64) tea.Cmd {
+// waitForRotation waits for the next rotation of the file a tail follows,
+// until the tail ends.
+func waitForRotation(t *ssh.Tailer) tea.Cmd {
 	return func() tea.Msg {
-		localPath := filepath.Join(localDir, localFilename)
+		select {
+		case r := <-t.Rotations():
+			return TailRotatedMsg{Tailer: t, Rotation: r}
+		case <-t.Done():
+			return nil
+		}
+	}
+}
 
-		connCtx, connCancel := context.WithTimeout(context.Background(), 15*time.Second)
-		defer connCancel()
+// waitForChallenge waits for the next keyboard-interactive prompt.
+func waitForChallenge(ch <-chan ssh.Challenge) tea.Cmd {
+	return func() tea.Msg {
+		return AuthChallengeMsg{Challenge: <-ch}
+	}
+}
+
+// waitForChallengeExpiry reports when the login stops waiting for the
+// answers to c.
+func waitForChallengeExpiry(c ssh.Challenge) tea.Cmd {
+	if c.Done == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		<-c.Done
+		return challengeExpiredMsg{reply: c.Reply}
+	}
+}
+
+// exportTextCmd writes exported viewer text to path.
+func exportTextCmd(path, text string, lines int) tea.Cmd {
+	return func() tea.Msg {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return ExportDoneMsg{Err: fmt.Errorf("creating directory: %w", err)}
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			return ExportDoneMsg{Err: err}
+		}
+		return ExportDoneMsg{Path: path, Lines: lines}
+	}
+}
+
+// waitForConnState waits for the next connection state change.
+func waitForConnState(ch <-chan ssh.StateChange) tea.Cmd {
+	return func() tea.Msg {
+		return ConnStateMsg{Change: <-ch}
+	}
+}
 
-		client, err := pool.GetClient(connCtx, srv)
+// statForDownloadCmd fetches the current size of a file before downloading it.
+func statForDownloadCmd(pool *ssh.Pool, srv config.ServerConfig, remotePath string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), srv.ConnectTimeout+srv.CommandTimeout)
+		defer cancel()
+
+		var client *gossh.Client // none for a local server
+		if !srv.Local {
+			var err error
+			if client, err = pool.GetClient(ctx, srv); err != nil {
+				return DownloadStatMsg{Err: fmt.Errorf("download connect: %v", err)}
+			}
+		}
+		info, err := ssh.Backend(srv.FileBackend).StatFile(ctx, client, remotePath, commandOpts(pool, srv))
 		if err != nil {
-			return DownloadErrorMsg{Err: fmt.Errorf("download connect: %v", err)}
+			return DownloadStatMsg{Err: fmt.Errorf("download: %v", err)}
 		}
+		return DownloadStatMsg{Size: info.Size}
+	}
+}
+
+// downloadFileCmd downloads a remote file with progress reporting and
+// cancellation support. A positive limit stops it after that many bytes.
+func downloadFileCmd(pool *ssh.Pool, srv config.ServerConfig, remotePath, localDir, localFilename string, limit int64, dlCtx context.Context, progressCh chan<- int64) tea.Cmd {
+	return func() tea.Msg {
+		localPath := filepath.Join(localDir, localFilename)
+
+		connCtx, connCancel := context.WithTimeout(context.Background(), srv.ConnectTimeout)
+		defer connCancel()
 
-		opts := ssh.CommandOpts{}
-		if srv.Sudo {
-			opts.SudoPassword = pool.GetSudoPassword(srv)
+		var client *gossh.Client // none for a local server
+		if !srv.Local {
+			var err error
+			if client, err = pool.GetClient(connCtx, srv); err != nil {
+				return DownloadErrorMsg{Err: fmt.Errorf("download connect: %v", err)}
+			}
 		}
 
-		if err := ssh.DownloadFile(client, remotePath, localPath, opts, dlCtx, progressCh); err != nil {
+		opts := commandOpts(pool, srv)
+		opts.DownloadLimit = limit
+
+		release := pool.Hold(srv)
+		defer release()
+		if err := ssh.Backend(srv.FileBackend).DownloadFile(client, remotePath, localPath, opts, dlCtx, progressCh); err != nil {
 			if dlCtx.Err() != nil {
 				return DownloadErrorMsg{Err: fmt.Errorf("download cancelled"), Cancelled: true}
 			}
@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
+)
+
+// decodeLog converts log data in a folder's encoding to UTF-8 for display.
+// With no encoding (or "auto"), valid UTF-8 lines are kept and other lines
+// are assumed to be Windows-1252, the usual encoding of legacy Latin-1 logs.
+// "utf-8" disables transcoding.
+func decodeLog(data []byte, encoding string) []byte {
+	switch strings.ToLower(encoding) {
+	case "", "auto":
+		if utf8.Valid(data) {
+			return data
+		}
+		dec := charmap.Windows1252.NewDecoder()
+		lines := bytes.SplitAfter(data, []byte("\n"))
+		for i, line := range lines {
+			if !utf8.Valid(line) {
+				if out, err := dec.Bytes(line); err == nil {
+					lines[i] = out
+				}
+			}
+		}
+		return bytes.Join(lines, nil)
+	case "utf-8", "utf8":
+		return data
+	}
+
+	enc, err := htmlindex.Get(encoding)
+	if err != nil {
+		// Rejected when the config is loaded; show the bytes as they are
+		return data
+	}
+	out, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return data
+	}
+	return out
+}
+
+// logDecoder decodes log data that arrives in chunks, as a tail does,
+// holding back a character split across two chunks until the rest of it
+// arrives: decoded on its own, each half would show as garbage.
+type logDecoder struct {
+	encoding string
+	dec      transform.Transformer // stateful decoder of encodings other than UTF-8
+	pending  []byte                // start of a character cut off by the last chunk
+}
+
+// decode converts the next chunk of data in encoding to UTF-8.
+func (d *logDecoder) decode(data []byte, encoding string) []byte {
+	if encoding != d.encoding {
+		*d = logDecoder{encoding: encoding}
+	}
+	if len(d.pending) > 0 {
+		data = append(d.pending, data...)
+		d.pending = nil
+	}
+
+	switch strings.ToLower(encoding) {
+	case "", "auto", "utf-8", "utf8":
+		if n := partialRune(data); n > 0 {
+			d.pending = bytes.Clone(data[len(data)-n:])
+			data = data[:len(data)-n]
+		}
+		return decodeLog(data, encoding)
+	}
+
+	if d.dec == nil {
+		enc, err := htmlindex.Get(encoding)
+		if err != nil {
+			return data
+		}
+		d.dec = enc.NewDecoder()
+	}
+	out := make([]byte, 0, len(data)+len(data)/2)
+	buf := make([]byte, 4096)
+	for {
+		nDst, nSrc, err := d.dec.Transform(buf, data, false)
+		out = append(out, buf[:nDst]...)
+		data = data[nSrc:]
+		switch err {
+		case transform.ErrShortDst:
+			continue
+		case transform.ErrShortSrc:
+			d.pending = bytes.Clone(data)
+		case nil:
+		default:
+			// Show the rest as it is, as decodeLog does
+			out = append(out, data...)
+			d.dec.Reset()
+		}
+		return out
+	}
+}
+
+// partialRune returns the length of a UTF-8 sequence cut off at the end of
+// data, or 0. A Windows-1252 character that looks like the start of one is
+// held back too, and decoded with the next chunk.
+func partialRune(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax+1; i-- {
+		if utf8.RuneStart(data[i]) {
+			if utf8.FullRune(data[i:]) {
+				return 0
+			}
+			return len(data) - i
+		}
+	}
+	return 0
+}
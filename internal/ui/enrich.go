@@ -0,0 +1,182 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"log-monitor/internal/enrich"
+	"log-monitor/internal/i18n"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// enrichTimeout bounds the reverse DNS lookups of one request.
+const enrichTimeout = 5 * time.Second
+
+// lookupCursorIPs looks up the IP addresses on the line under the viewer
+// cursor.
+func (m Model) lookupCursorIPs() (tea.Model, tea.Cmd) {
+	text, _, ok := m.viewerPane.CursorLine()
+	if !ok {
+		m.errorMsg = i18n.T("click a line to select it first")
+		return m, nil
+	}
+	ips := enrich.FindIPs(text)
+	if len(ips) == 0 {
+		m.errorMsg = i18n.T("no IP address on the selected line")
+		return m, nil
+	}
+	m.contextMsg = i18n.T("\033[36mLooking up\033[0m %s…", ips[0])
+	return m, enrichCmd(ips, m.geo)
+}
+
+// annotateAll labels every occurrence of the looked-up addresses in the
+// viewer.
+func (m Model) annotateAll() Model {
+	labels := make(map[string]string)
+	for _, info := range m.enrichInfos {
+		if label := info.Label(); label != "" {
+			labels[info.Addr.String()] = label
+		}
+	}
+	if len(labels) == 0 {
+		m.errorMsg = i18n.T("nothing known about these addresses")
+		return m
+	}
+	m.viewerPane.Annotate(labels)
+	m.contextMsg = i18n.T("\033[36mAnnotated\033[0m %d address(es) in the viewer", len(labels))
+	return m
+}
+
+// enrichSummary renders the lookup results for the popup.
+func (m Model) enrichSummary() string {
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
+	var b strings.Builder
+	for i, info := range m.enrichInfos {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(lipgloss.NewStyle().Foreground(accentColor).Bold(true).Render(info.Addr.String()))
+		field := func(name, value string) {
+			b.WriteString("\n" + modalHintStyle.Render(fmt.Sprintf("  %-9s", name)) + valueStyle.Render(value))
+		}
+		switch {
+		case len(info.Names) > 0:
+			field("Name:", strings.Join(info.Names, ", "))
+		case info.DNSErr != nil:
+			field("Name:", fmt.Sprintf("lookup failed: %v", info.DNSErr))
+		default:
+			field("Name:", "(no reverse DNS)")
+		}
+		if loc := info.Location(); loc != "" {
+			field("Location:", loc)
+		}
+		if info.ASN != 0 {
+			field("Network:", strings.TrimSpace(fmt.Sprintf("AS%d %s", info.ASN, info.ASOrg)))
+		}
+	}
+	if m.geo == nil {
+		b.WriteString("\n\n" + modalHintStyle.Render("Set defaults.geoip_db for locations"))
+	}
+	return b.String()
+}
+
+// enrichCmd looks up the addresses in parallel.
+func enrichCmd(ips []netip.Addr, geo *enrich.GeoDB) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), enrichTimeout)
+		defer cancel()
+
+		infos := make([]enrich.Info, len(ips))
+		var wg sync.WaitGroup
+		for i, ip := range ips {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				infos[i] = enrich.Lookup(ctx, ip, geo)
+			}()
+		}
+		wg.Wait()
+		return EnrichDoneMsg{Infos: infos}
+	}
+}
+
+// annotateANSI inserts each address's label after its occurrences in a
+// colorized line, after the port if an IPv4 address has one. Longer runs
+// that merely contain an address, such as 10.0.0.10 for 10.0.0.1, are left
+// alone.
+func annotateANSI(text string, labels map[string]string) string {
+	for ip, label := range labels {
+		if !strings.Contains(text, ip) {
+			continue
+		}
+		tag := "\033[90m ⟨" + label + "⟩\033[0m"
+		var b strings.Builder
+		pos := 0
+		for {
+			idx := strings.Index(text[pos:], ip)
+			if idx == -1 {
+				b.WriteString(text[pos:])
+				break
+			}
+			start, end := pos+idx, pos+idx+len(ip)
+			// Look past the address's own color codes, and past a port
+			next := skipANSI(text, end)
+			if !strings.Contains(ip, ":") && next < len(text) && text[next] == ':' {
+				port := next + 1
+				for port < len(text) && text[port] >= '0' && text[port] <= '9' {
+					port++
+				}
+				if port > next+1 {
+					end, next = port, port
+				}
+			}
+			b.WriteString(text[pos:end])
+			if !addrChar(text, start-1) && addrEnds(text, next) {
+				b.WriteString(tag)
+			}
+			pos = end
+		}
+		text = b.String()
+	}
+	return text
+}
+
+// addrEnds reports whether an address (or port) ending before text[i] isn't
+// continued there. Punctuation such as a full stop ending a sentence
+// doesn't continue it.
+func addrEnds(text string, i int) bool {
+	if i < len(text) && (text[i] == '.' || text[i] == ':') {
+		return !addrChar(text, i+1)
+	}
+	return !addrChar(text, i)
+}
+
+// skipANSI returns the index after any escape sequences starting at i.
+func skipANSI(text string, i int) int {
+	for i+1 < len(text) && text[i] == '\033' && text[i+1] == '[' {
+		j := i + 2
+		for j < len(text) && (text[j] < 0x40 || text[j] > 0x7E) {
+			j++
+		}
+		if j == len(text) {
+			return i
+		}
+		i = j + 1
+	}
+	return i
+}
+
+// addrChar reports whether text[i] could continue an IP address.
+func addrChar(text string, i int) bool {
+	if i < 0 || i >= len(text) {
+		return false
+	}
+	c := text[i]
+	return c == '.' || c == ':' || c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 	"unicode/utf8"
 
@@ -32,13 +33,30 @@ type FilePaneModel struct {
 	selectedFolderIdx int // last selected folder index
 	hasUpDir          bool
 	folderPath        string
+	root              string // configured folder when browsing a subdirectory of it
 	message           string // error/status message to display
 
 	// Fuzzy filter
 	filterQuery    string
 	filteredIdxMap []int // maps display index -> original file index
+
+	// Folder path -> files gained in the last refresh of all folders
+	newFiles map[string]int
+
+	// Shown above the list, e.g. when the listing is incomplete
+	warning string
+
+	// Names only, in as many columns as fit (mc-style), for the session
+	columns bool
+
+	// Names of the files marked with Space, to tail together
+	marked map[string]bool
 }
 
+// fileColumnMaxW caps a column of the multi-column layout, so one long
+// name doesn't leave room for a single column.
+const fileColumnMaxW = 40
+
 // NewFilePaneModel creates a new file pane model.
 func NewFilePaneModel() FilePaneModel {
 	return FilePaneModel{
@@ -137,9 +155,38 @@ func (fp *FilePaneModel) MoveDown() {
 	}
 }
 
+// MoveLeft moves the cursor to the previous column of the multi-column
+// layout. It reports false when the layout isn't shown.
+func (fp *FilePaneModel) MoveLeft() bool {
+	if cols, _ := fp.columnLayout(); cols == 0 {
+		return false
+	}
+	fp.cursor = max(fp.cursor-max(fp.listRows(), 1), 0)
+	return true
+}
+
+// MoveRight moves the cursor to the next column of the multi-column
+// layout. It reports false when the layout isn't shown.
+func (fp *FilePaneModel) MoveRight() bool {
+	if cols, _ := fp.columnLayout(); cols == 0 {
+		return false
+	}
+	fp.cursor = max(min(fp.cursor+max(fp.listRows(), 1), fp.totalRows()-1), 0)
+	return true
+}
+
+// pageSize returns how many entries one screen of the pane shows.
+func (fp *FilePaneModel) pageSize() int {
+	rows := fp.listRows()
+	if cols, _ := fp.columnLayout(); cols > 0 {
+		return rows * cols
+	}
+	return rows
+}
+
 // PageUp moves cursor up by one page.
 func (fp *FilePaneModel) PageUp() {
-	pageSize := fp.height - 5
+	pageSize := fp.pageSize()
 	if pageSize < 1 {
 		pageSize = 1
 	}
@@ -151,7 +198,7 @@ func (fp *FilePaneModel) PageUp() {
 
 // PageDown moves cursor down by one page.
 func (fp *FilePaneModel) PageDown() {
-	pageSize := fp.height - 5
+	pageSize := fp.pageSize()
 	if pageSize < 1 {
 		pageSize = 1
 	}
@@ -164,14 +211,14 @@ func (fp *FilePaneModel) PageDown() {
 	}
 }
 
-// SetCursorFromY moves the cursor based on a mouse Y coordinate within the pane.
+// SetCursorFromXY moves the cursor based on mouse coordinates within the pane.
 // The pane layout is: row 0 = border, row 1 = table header, row 2+ = items.
-func (fp *FilePaneModel) SetCursorFromY(y int) {
+func (fp *FilePaneModel) SetCursorFromXY(x, y int) {
 	total := fp.totalRows()
 	if total == 0 {
 		return
 	}
-	innerHeight := fp.height - 5
+	innerHeight := fp.listRows()
 	if innerHeight < 1 {
 		innerHeight = 1
 	}
@@ -179,7 +226,13 @@ func (fp *FilePaneModel) SetCursorFromY(y int) {
 	if fp.cursor >= innerHeight {
 		startIdx = fp.cursor - innerHeight + 1
 	}
-	itemIdx := startIdx + (y - 2) // row 0=border, row 1=table header
+	row := y - 2 - fp.warningRows() // row 0=border, row 1=table header
+	if cols, colW := fp.columnLayout(); cols > 0 {
+		col := min(max(x-1, 0)/colW, cols-1)
+		startIdx = fp.firstColumn(cols) * innerHeight
+		row = col*innerHeight + min(max(row, 0), innerHeight-1)
+	}
+	itemIdx := startIdx + row
 	if itemIdx < 0 {
 		itemIdx = 0
 	}
@@ -189,12 +242,70 @@ func (fp *FilePaneModel) SetCursorFromY(y int) {
 	fp.cursor = itemIdx
 }
 
+// listRows returns how many rows of the list fit in the pane.
+func (fp *FilePaneModel) listRows() int {
+	return fp.height - 5 - fp.warningRows()
+}
+
+// warningRows returns the rows taken by the warning line.
+func (fp *FilePaneModel) warningRows() int {
+	if fp.warning == "" || fp.mode == modeFolders {
+		return 0
+	}
+	return 1
+}
+
+// ToggleColumns switches between the detailed list and the multi-column
+// layout, which shows only names, top to bottom and then left to right. It
+// returns whether the multi-column layout is now on.
+func (fp *FilePaneModel) ToggleColumns() bool {
+	fp.columns = !fp.columns
+	return fp.columns
+}
+
+// columnLayout returns how many columns the multi-column layout shows and
+// how wide each is, or 0 when the detailed list is shown instead: with the
+// layout off, in folder mode, or when the names don't fit twice side by
+// side.
+func (fp *FilePaneModel) columnLayout() (cols, colW int) {
+	if !fp.columns || fp.mode == modeFolders {
+		return 0, 0
+	}
+	longest := 3 // "/.."
+	for _, idx := range fp.filteredIdxMap {
+		longest = max(longest, lipgloss.Width(fp.files[idx].Name))
+	}
+	innerWidth := fp.width - 2
+	// Room for the active file's marker and a space between columns
+	cols = innerWidth / (min(longest, fileColumnMaxW) + 3)
+	if cols < 2 {
+		return 0, 0
+	}
+	return cols, innerWidth / cols
+}
+
+// firstColumn returns the first of the columns shown, scrolled so the
+// cursor's column is visible.
+func (fp *FilePaneModel) firstColumn(cols int) int {
+	cursorCol := fp.cursor / max(fp.listRows(), 1)
+	if cursorCol < cols {
+		return 0
+	}
+	return cursorCol - cols + 1
+}
+
+// SetWarning shows a warning above the file list; "" removes it.
+func (fp *FilePaneModel) SetWarning(msg string) {
+	fp.warning = msg
+}
+
 // SetFolders switches to folder mode.
 func (fp *FilePaneModel) SetFolders(folders []config.LogFolder) {
 	fp.mode = modeFolders
 	fp.folders = folders
 	fp.files = nil
 	fp.dir = ""
+	fp.root = ""
 	fp.folderPath = ""
 	fp.selectedFileIdx = -1
 	fp.filterQuery = ""
@@ -207,12 +318,28 @@ func (fp *FilePaneModel) SetFolders(folders []config.LogFolder) {
 	}
 }
 
-// SetFiles switches to files mode and populates file data.
-func (fp *FilePaneModel) SetFiles(dir string, files []ssh.FileInfo, showUpDir bool) {
+// SetFiles switches to files mode and populates file data. root is the
+// configured folder when dir is a subdirectory of it, otherwise "".
+func (fp *FilePaneModel) SetFiles(dir, root string, files []ssh.FileInfo, showUpDir bool) {
+	if dir != fp.dir {
+		fp.marked = nil
+	} else if len(fp.marked) > 0 {
+		// A refresh keeps the marks of the files still listed
+		listed := make(map[string]bool, len(files))
+		for _, f := range files {
+			listed[f.Name] = true
+		}
+		for name := range fp.marked {
+			if !listed[name] {
+				delete(fp.marked, name)
+			}
+		}
+	}
 	fp.mode = modeFiles
 	fp.folders = nil
 	fp.files = files
 	fp.dir = dir
+	fp.root = root
 	fp.selectedFileIdx = -1
 	fp.filterQuery = ""
 	fp.hasUpDir = showUpDir
@@ -236,12 +363,14 @@ func (fp *FilePaneModel) Clear() {
 	fp.files = nil
 	fp.folders = nil
 	fp.dir = ""
+	fp.root = ""
 	fp.folderPath = ""
 	fp.selectedFileIdx = -1
 	fp.selectedFolderIdx = -1
 	fp.filterQuery = ""
 	fp.hasUpDir = false
 	fp.message = ""
+	fp.warning = ""
 	fp.cursor = 0
 	fp.filteredIdxMap = nil
 }
@@ -252,11 +381,56 @@ func (fp *FilePaneModel) SetMessage(msg string) {
 	fp.message = msg
 }
 
+// SetNewFiles marks folders with the number of files they gained; nil
+// clears the marks.
+func (fp *FilePaneModel) SetNewFiles(counts map[string]int) {
+	fp.newFiles = counts
+}
+
 // MarkSelected marks a file as selected.
 func (fp *FilePaneModel) MarkSelected(idx int) {
 	fp.selectedFileIdx = idx
 }
 
+// ToggleMark marks the file under the cursor, or unmarks it, and moves the
+// cursor down. Directories can't be marked.
+func (fp *FilePaneModel) ToggleMark() bool {
+	_, _, _, _, file := fp.SelectedItem()
+	if file == nil || file.IsDir {
+		return false
+	}
+	if fp.marked[file.Name] {
+		delete(fp.marked, file.Name)
+	} else {
+		if fp.marked == nil {
+			fp.marked = make(map[string]bool)
+		}
+		fp.marked[file.Name] = true
+	}
+	fp.MoveDown()
+	return true
+}
+
+// MarkedFiles returns the marked files, in list order.
+func (fp *FilePaneModel) MarkedFiles() []ssh.FileInfo {
+	var files []ssh.FileInfo
+	for _, f := range fp.files {
+		if fp.marked[f.Name] {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// ClearMarks unmarks all files. It returns false if none were marked.
+func (fp *FilePaneModel) ClearMarks() bool {
+	if len(fp.marked) == 0 {
+		return false
+	}
+	fp.marked = nil
+	return true
+}
+
 // IsInFolderMode returns true if showing folders.
 func (fp *FilePaneModel) IsInFolderMode() bool {
 	return fp.mode == modeFolders
@@ -286,6 +460,34 @@ func (fp *FilePaneModel) SelectedItem() (isUpDir bool, folderIdx int, folder *co
 	return false, -1, nil, -1, nil
 }
 
+// SingleMatch returns the only file left by the filter and moves the cursor
+// onto it. ok is false without a filter or with more or fewer matches.
+func (fp *FilePaneModel) SingleMatch() (origIdx int, file *ssh.FileInfo, ok bool) {
+	if fp.mode == modeFolders || fp.filterQuery == "" || len(fp.filteredIdxMap) != 1 {
+		return -1, nil, false
+	}
+	fp.cursor = 0
+	if fp.hasUpDir {
+		fp.cursor = 1
+	}
+	origIdx = fp.filteredIdxMap[0]
+	return origIdx, &fp.files[origIdx], true
+}
+
+// SetFileCursor moves the cursor onto the file at origIdx, if the filter
+// shows it.
+func (fp *FilePaneModel) SetFileCursor(origIdx int) {
+	for i, idx := range fp.filteredIdxMap {
+		if idx == origIdx {
+			fp.cursor = i
+			if fp.hasUpDir {
+				fp.cursor++
+			}
+			return
+		}
+	}
+}
+
 // GetFiles returns the current file list.
 func (fp *FilePaneModel) GetFiles() []ssh.FileInfo {
 	return fp.files
@@ -315,7 +517,7 @@ func (fp *FilePaneModel) View(focused bool) string {
 	if fp.mode == modeFolders {
 		titleText = " Folders "
 	} else if fp.folderPath != "" {
-		titleText = " " + fp.folderPath + " "
+		titleText = " " + fp.breadcrumb() + " "
 	}
 	if fp.filterQuery != "" {
 		titleText = fmt.Sprintf("%s[%s] ", titleText, fp.filterQuery)
@@ -348,6 +550,17 @@ func (fp *FilePaneModel) View(focused bool) string {
 		nameColW = 10
 	}
 
+	if fp.warningRows() > 0 {
+		b.WriteString(lipgloss.NewStyle().Foreground(warnColor).Render(truncateString("⚠ "+fp.warning, innerWidth)))
+		b.WriteByte('\n')
+	}
+
+	if cols, colW := fp.columnLayout(); cols > 0 {
+		fp.renderColumns(&b, cols, colW)
+		content := paneStyle.Render(b.String())
+		return placeTitleInBorder(content, titleStyle.Render(titleText))
+	}
+
 	// Header row
 	header := fmt.Sprintf("%-*s %*s  %s",
 		nameColW, "Name",
@@ -367,13 +580,27 @@ func (fp *FilePaneModel) View(focused bool) string {
 	return placeTitleInBorder(content, title)
 }
 
+// breadcrumb returns the path of the listed directory, with the part below
+// the configured folder split at each level: "/var/log › nginx › old".
+func (fp *FilePaneModel) breadcrumb() string {
+	if fp.root == "" {
+		return fp.folderPath
+	}
+	root := filepath.Clean(fp.root)
+	rel := strings.TrimPrefix(strings.TrimPrefix(fp.folderPath, root), "/")
+	if rel == "" || rel == fp.folderPath {
+		return fp.folderPath
+	}
+	return root + " › " + strings.ReplaceAll(rel, "/", " › ")
+}
+
 func (fp *FilePaneModel) renderFolders(b *strings.Builder, nameW, sizeW, timeW int) {
 	if len(fp.folders) == 0 {
 		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render("(no folders)"))
 		return
 	}
 
-	innerHeight := fp.height - 5
+	innerHeight := fp.listRows()
 	if innerHeight < 1 {
 		innerHeight = 1
 	}
@@ -391,15 +618,19 @@ func (fp *FilePaneModel) renderFolders(b *strings.Builder, nameW, sizeW, timeW i
 
 	for i := startIdx; i < endIdx; i++ {
 		name := truncateString(fp.folders[i].Path, nameW)
+		kind, kindColor := "DIR", accentColor
+		if n := fp.newFiles[fp.folders[i].Path]; n > 0 {
+			kind, kindColor = fmt.Sprintf("+%d", n), lipgloss.Color("10")
+		}
 		// Build plain-text line with proper column alignment
-		line := fmt.Sprintf("%-*s %*s  %s", nameW, name, sizeW, "DIR", strings.Repeat(" ", timeW))
+		line := fmt.Sprintf("%-*s %*s  %s", nameW, name, sizeW, kind, strings.Repeat(" ", timeW))
 
 		if i == fp.cursor {
 			b.WriteString(selectedRowStyle.Render(padRight(line, lineWidth)))
 		} else {
 			// Color the DIR part after formatting
 			plainLine := fmt.Sprintf("%-*s ", nameW, name)
-			dirPart := lipgloss.NewStyle().Foreground(accentColor).Render(fmt.Sprintf("%*s", sizeW, "DIR"))
+			dirPart := lipgloss.NewStyle().Foreground(kindColor).Render(fmt.Sprintf("%*s", sizeW, kind))
 			b.WriteString(plainLine + dirPart + strings.Repeat(" ", timeW+2))
 		}
 		if i < endIdx-1 {
@@ -415,7 +646,7 @@ func (fp *FilePaneModel) renderFiles(b *strings.Builder, nameW, sizeW, timeW int
 		return
 	}
 
-	innerHeight := fp.height - 5
+	innerHeight := fp.listRows()
 	if innerHeight < 1 {
 		innerHeight = 1
 	}
@@ -453,9 +684,13 @@ func (fp *FilePaneModel) renderFiles(b *strings.Builder, nameW, sizeW, timeW int
 		if fileDisplayIdx >= 0 && fileDisplayIdx < len(fp.filteredIdxMap) {
 			origIdx := fp.filteredIdxMap[fileDisplayIdx]
 			f := fp.files[origIdx]
-			name := f.Name
+			name := entryName(f)
 			isActive := origIdx == fp.selectedFileIdx
+			isMarked := fp.marked[f.Name]
 
+			if isMarked {
+				name = "+ " + name
+			}
 			if isActive {
 				name = "› " + name
 			}
@@ -463,6 +698,13 @@ func (fp *FilePaneModel) renderFiles(b *strings.Builder, nameW, sizeW, timeW int
 
 			sizeStr := ssh.FormatSize(f.Size)
 			timeStr := f.ModTime.Format("Jan _2 15:04")
+			if f.ModTime.IsZero() {
+				// Listed, but ls could not read it
+				sizeStr, timeStr = "?", "?"
+			}
+			if f.IsDir {
+				sizeStr = "DIR"
+			}
 
 			if di == fp.cursor {
 				// Cursor row — plain text, full-width highlight
@@ -474,6 +716,14 @@ func (fp *FilePaneModel) renderFiles(b *strings.Builder, nameW, sizeW, timeW int
 				plainName := truncateString(f.Name, nameW-2)
 				meta := dimStyle.Render(fmt.Sprintf(" %*s  %s", sizeW, sizeStr, timeStr))
 				b.WriteString(marker + padRight(plainName, nameW-2) + meta)
+			} else if isMarked {
+				namePart := markedFileStyle.Render(fmt.Sprintf("%-*s", nameW, name))
+				meta := dimStyle.Render(fmt.Sprintf(" %*s  %s", sizeW, sizeStr, timeStr))
+				b.WriteString(namePart + meta)
+			} else if f.IsDir {
+				namePart := lipgloss.NewStyle().Foreground(accentColor).Render(fmt.Sprintf("%-*s", nameW, name))
+				meta := dimStyle.Render(fmt.Sprintf(" %*s  %s", sizeW, sizeStr, timeStr))
+				b.WriteString(namePart + meta)
 			} else {
 				namePart := fmt.Sprintf("%-*s", nameW, name)
 				meta := dimStyle.Render(fmt.Sprintf(" %*s  %s", sizeW, sizeStr, timeStr))
@@ -488,3 +738,89 @@ func (fp *FilePaneModel) renderFiles(b *strings.Builder, nameW, sizeW, timeW int
 		}
 	}
 }
+
+// renderColumns renders the files in the multi-column layout: names only,
+// each column filled top to bottom before the next.
+func (fp *FilePaneModel) renderColumns(b *strings.Builder, cols, colW int) {
+	nameW := colW - 1
+	header := strings.Repeat(padRight("Name", colW), cols)
+	b.WriteString(tableHeaderStyle.Render(strings.TrimRight(header, " ")))
+	b.WriteByte('\n')
+
+	total := fp.totalRows()
+	if total == 0 && len(fp.files) == 0 {
+		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render("(no files found)"))
+		return
+	}
+
+	rows := max(fp.listRows(), 1)
+	start := fp.firstColumn(cols) * rows
+	for r := 0; r < rows && start+r < total; r++ {
+		if r > 0 {
+			b.WriteByte('\n')
+		}
+		for c := 0; c < cols; c++ {
+			di := start + c*rows + r
+			if di >= total {
+				break
+			}
+			if c > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(fp.renderCell(di, nameW))
+		}
+	}
+}
+
+// renderCell renders the entry at display index di in a column nameW wide.
+func (fp *FilePaneModel) renderCell(di, nameW int) string {
+	fileDisplayIdx := di
+	if fp.hasUpDir {
+		if di == 0 {
+			if di == fp.cursor {
+				return selectedRowStyle.Render(padRight("/..", nameW))
+			}
+			return lipgloss.NewStyle().Foreground(accentColor).Render(padRight("/..", nameW))
+		}
+		fileDisplayIdx--
+	}
+	if fileDisplayIdx >= len(fp.filteredIdxMap) {
+		return strings.Repeat(" ", nameW)
+	}
+	origIdx := fp.filteredIdxMap[fileDisplayIdx]
+	f := fp.files[origIdx]
+	isActive := origIdx == fp.selectedFileIdx
+	isMarked := fp.marked[f.Name]
+
+	switch {
+	case di == fp.cursor:
+		name := entryName(f)
+		if isMarked {
+			name = "+ " + name
+		}
+		if isActive {
+			name = "› " + name
+		}
+		return selectedRowStyle.Render(padRight(truncateString(name, nameW), nameW))
+	case isActive:
+		return activeMarkerStyle.Render("› ") + padRight(truncateString(f.Name, nameW-2), nameW-2)
+	case isMarked:
+		return markedFileStyle.Render(padRight(truncateString("+ "+f.Name, nameW), nameW))
+	case f.IsDir:
+		return lipgloss.NewStyle().Foreground(accentColor).Render(padRight(truncateString(entryName(f), nameW), nameW))
+	case f.ModTime.IsZero():
+		// Listed, but ls could not read it
+		return dimStyle.Render(padRight(truncateString(f.Name, nameW), nameW))
+	default:
+		return padRight(truncateString(f.Name, nameW), nameW)
+	}
+}
+
+// entryName returns the name a listing entry is shown with: directories
+// start with a slash, like the up-dir row.
+func entryName(f ssh.FileInfo) string {
+	if f.IsDir {
+		return "/" + f.Name
+	}
+	return f.Name
+}
@@ -0,0 +1,167 @@
+package ui
+
+import (
+	"context"
+	"path/filepath"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/i18n"
+	"log-monitor/internal/logger"
+	"log-monitor/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// fileAction is a truncate or delete of a file waiting for confirmation.
+type fileAction struct {
+	path   string
+	size   int64
+	delete bool // delete the file rather than truncate it
+	chosen bool // truncate or delete was picked; Enter runs it
+}
+
+// fileActionDoneMsg reports a truncated or deleted file.
+type fileActionDoneMsg struct {
+	srv    string
+	path   string
+	delete bool
+	err    error
+}
+
+// showFileActions asks what to do with the targeted file: the one under
+// the cursor in the file pane, otherwise the open one. Servers with
+// read_only refuse.
+func (m Model) showFileActions() Model {
+	if m.kubectlRefused() {
+		return m
+	}
+	_, path, ok := m.noteTarget()
+	if !ok {
+		m.errorMsg = i18n.T("select a file first")
+		return m
+	}
+	if m.currentServer.IsReadOnly() {
+		m.errorMsg = i18n.T("%s is read-only: set read_only: false in its config to change files", m.currentServer.Name)
+		return m
+	}
+	file := m.currentFile
+	if m.focused == paneFile {
+		_, _, _, _, file = m.filePane.SelectedItem()
+	}
+	m.fileAction = &fileAction{path: path, size: file.Size}
+	m.modal = modalFileAction
+	return m
+}
+
+// fileActionKey picks truncate (t) or delete (d).
+func (m Model) fileActionKey(k string) Model {
+	if m.fileAction.chosen {
+		return m
+	}
+	switch k {
+	case "t":
+		m.fileAction.chosen = true
+	case "d":
+		m.fileAction.delete = true
+		m.fileAction.chosen = true
+	}
+	return m
+}
+
+// runFileAction truncates or deletes the file once confirmed.
+func (m Model) runFileAction() (tea.Model, tea.Cmd) {
+	a := m.fileAction
+	if a == nil || !a.chosen || m.currentServer == nil {
+		return m, nil
+	}
+	m.modal = modalNone
+	m.fileAction = nil
+	verb, doing := "Truncating", i18n.T("\033[33mTruncating\033[0m %s…", a.path)
+	if a.delete {
+		verb, doing = "Deleting", i18n.T("\033[33mDeleting\033[0m %s…", a.path)
+		if m.currentFile != nil && m.currentFolder != nil && filepath.Join(m.currentFolder.Path, m.currentFile.Name) == a.path {
+			// Following it would only report it gone
+			m.stopTailInPlace()
+		}
+	}
+	logger.Log("app", "%s %s on %s", verb, a.path, m.currentServer.Name)
+	m.setContext(doing)
+	return m, fileActionCmd(m.pool, *m.currentServer, a.path, a.delete)
+}
+
+// fileActionCmd truncates or deletes a file on the server.
+func fileActionCmd(pool *ssh.Pool, srv config.ServerConfig, path string, del bool) tea.Cmd {
+	return func() tea.Msg {
+		done := fileActionDoneMsg{srv: srv.Name, path: path, delete: del}
+		if srv.Local {
+			if del {
+				done.err = ssh.DeleteLocalFile(path)
+			} else {
+				done.err = ssh.TruncateLocalFile(path)
+			}
+			return done
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), srv.ConnectTimeout)
+		defer cancel()
+		client, err := pool.GetClient(ctx, srv)
+		if err != nil {
+			done.err = err
+			return done
+		}
+		cmdCtx, cmdCancel := context.WithTimeout(context.Background(), srv.CommandTimeout)
+		defer cmdCancel()
+		if del {
+			done.err = ssh.DeleteFile(cmdCtx, client, path, commandOpts(pool, srv))
+		} else {
+			done.err = ssh.TruncateFile(cmdCtx, client, path, commandOpts(pool, srv))
+		}
+		return done
+	}
+}
+
+// onFileActionDone reports the outcome and lists the folder again.
+func (m Model) onFileActionDone(msg fileActionDoneMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		logger.Log("app", "%v", msg.err)
+		m.contextMsg = m.lastContext
+		m.errorMsg = msg.err.Error()
+		return m, nil
+	}
+	if msg.delete {
+		m.setContext(i18n.T("\033[32mDeleted\033[0m %s on %s", msg.path, msg.srv))
+	} else {
+		m.setContext(i18n.T("\033[32mTruncated\033[0m %s on %s", msg.path, msg.srv))
+	}
+	if m.currentServer == nil || m.currentFolder == nil || m.currentServer.Name != msg.srv ||
+		m.currentFolder.Path != filepath.Dir(msg.path) {
+		return m, nil
+	}
+	return m, connectAndListCmd(m.pool, *m.currentServer, *m.currentFolder)
+}
+
+// fileActionSummary describes the pending truncate or delete.
+func (m Model) fileActionSummary() string {
+	a := m.fileAction
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true)
+	buttonCancel := modalButtonStyle.Render(i18n.T("[Esc] Cancel"))
+
+	summary := valueStyle.Render(a.path) + "\n" +
+		modalHintStyle.Render(i18n.T("%s on %s", ssh.FormatSize(a.size), m.currentServer.Name))
+	switch {
+	case !a.chosen:
+		return summary + "\n\n" +
+			modalButtonStyle.Render(i18n.T("[t] Truncate to 0 bytes")) + "  " +
+			modalButtonStyle.Render(i18n.T("[d] Delete")) + "  " + buttonCancel
+	case a.delete:
+		return summary + "\n\n" + warnStyle.Render(i18n.T("Delete this file? This can't be undone.")) + "\n" +
+			modalHintStyle.Render(i18n.T("A process still writing it keeps its disk space until it closes the file; truncating frees it at once.")) +
+			"\n\n" + modalButtonStyle.Render(i18n.T("[Enter] Delete")) + "  " + buttonCancel
+	default:
+		return summary + "\n\n" + warnStyle.Render(i18n.T("Empty this file? Its content can't be restored.")) + "\n" +
+			modalHintStyle.Render(i18n.T("Processes writing it carry on into the empty file.")) +
+			"\n\n" + modalButtonStyle.Render(i18n.T("[Enter] Truncate")) + "  " + buttonCancel
+	}
+}
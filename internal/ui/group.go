@@ -0,0 +1,349 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/i18n"
+	"log-monitor/internal/logger"
+	"log-monitor/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// warmTail is a tail started in the background on a server of a group. It
+// keeps the file's last lines, which are shown at once when the file is
+// opened, and streams new ones to the viewer while it shows the file.
+type warmTail struct {
+	server config.ServerConfig
+	path   string
+	limit  int // lines kept
+
+	mu        sync.Mutex
+	ready     bool     // the first read is done and the tail is running
+	dead      bool     // the tail ended or could not start
+	stopped   bool     // the group was stopped while the tail was shown
+	lines     []string // the file's last lines, each with its newline
+	startLine int      // line number of lines[0] in the file
+	out       chan []byte
+	detached  chan struct{} // closed when the viewer lets go of out
+	tailer    *ssh.Tailer
+	cancel    context.CancelFunc
+}
+
+// buffer adds lines to the last lines kept, dropping the oldest beyond the
+// limit. The caller must hold w.mu.
+func (w *warmTail) buffer(data string) {
+	lines := strings.SplitAfter(data, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	// The first read may end in the middle of a line the tail completes
+	if n := len(w.lines); n > 0 && len(lines) > 0 && !strings.HasSuffix(w.lines[n-1], "\n") {
+		w.lines[n-1] += lines[0]
+		lines = lines[1:]
+	}
+	w.lines = append(w.lines, lines...)
+	if over := len(w.lines) - w.limit; over > 0 {
+		w.lines = append([]string(nil), w.lines[over:]...)
+		w.startLine += over
+	}
+}
+
+// pump keeps the tail's lines and sends them to the viewer while it shows
+// the file, until the tail ends.
+func (w *warmTail) pump(in <-chan []byte) {
+	for data := range in {
+		w.mu.Lock()
+		w.buffer(string(data))
+		out, detached := w.out, w.detached
+		w.mu.Unlock()
+		if out != nil {
+			select {
+			case out <- data:
+			case <-detached:
+			}
+		}
+	}
+	w.mu.Lock()
+	w.dead = true
+	if w.out != nil {
+		close(w.out)
+		w.out = nil
+	}
+	w.mu.Unlock()
+}
+
+// adopt hands the tail to the viewer: it returns the last lines and from
+// then on sends new ones to out. It fails if the tail isn't running.
+func (w *warmTail) adopt(out chan []byte) (content string, startLine int, ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.ready || w.dead || w.out != nil {
+		return "", 0, false
+	}
+	w.out = out
+	w.detached = make(chan struct{})
+	return strings.Join(w.lines, ""), w.startLine, true
+}
+
+// detach takes the tail back from the viewer, which stops reading out. It
+// is the viewer's cancel function for an adopted tail.
+func (w *warmTail) detach() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.out == nil {
+		return
+	}
+	w.out = nil
+	close(w.detached)
+	if w.stopped {
+		w.cancel()
+	}
+}
+
+// stop ends the tail, or leaves it to the viewer showing it, which ends it
+// when it lets go.
+func (w *warmTail) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+	if w.out == nil && w.cancel != nil {
+		w.cancel()
+	}
+}
+
+// warmTails are the background tails of a tail group, by server and path.
+// The map is shared by the copies of the model.
+type warmTails struct {
+	mu    sync.Mutex
+	group string
+	tails map[string]*warmTail
+}
+
+func warmKey(srv config.ServerConfig, path string) string {
+	return ssh.ServerKey(srv) + "\x00" + path
+}
+
+// get returns the live warm tail of path on srv, or nil.
+func (g *warmTails) get(srv config.ServerConfig, path string) *warmTail {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	w := g.tails[warmKey(srv, path)]
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	dead := w.dead
+	w.mu.Unlock()
+	if dead {
+		delete(g.tails, warmKey(srv, path))
+		return nil
+	}
+	return w
+}
+
+// fileIn returns the name of the file warm on srv in dir, or "".
+func (g *warmTails) fileIn(srv config.ServerConfig, dir string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := ssh.ServerKey(srv)
+	for _, w := range g.tails {
+		if ssh.ServerKey(w.server) == key && filepath.Dir(w.path) == filepath.Clean(dir) {
+			return filepath.Base(w.path)
+		}
+	}
+	return ""
+}
+
+// stopAll stops every warm tail and forgets the group.
+func (g *warmTails) stopAll() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	n := len(g.tails)
+	for _, w := range g.tails {
+		w.stop()
+	}
+	g.tails = make(map[string]*warmTail)
+	g.group = ""
+	return n
+}
+
+// groupServers returns the servers of a group, in config order.
+func (m Model) groupServers(group string) []config.ServerConfig {
+	var servers []config.ServerConfig
+	for _, srv := range m.cfg.Servers {
+		if srv.Group == group {
+			servers = append(servers, srv)
+		}
+	}
+	return servers
+}
+
+// toggleTailGroup starts a background tail of the open file on every server
+// of the current server's group, so that switching to one of them shows the
+// file at once. Pressed again, it stops them.
+func (m Model) toggleTailGroup() (tea.Model, tea.Cmd) {
+	if group := m.warm.group; group != "" {
+		n := m.warm.stopAll()
+		m.warmPending = 0
+		m.setContext(i18n.T("\033[33mTail group stopped\033[0m %s — \033[90m%d background tail(s) closed\033[0m", group, n))
+		return m, nil
+	}
+	if m.currentServer == nil || m.currentFolder == nil || m.currentFile == nil || m.currentFile.IsDir {
+		m.errorMsg = i18n.T("open a file to tail it across its group")
+		return m, nil
+	}
+	if m.kubectlRefused() {
+		return m, nil
+	}
+	group := m.currentServer.Group
+	if group == "" {
+		m.errorMsg = i18n.T("%s is in no group: set group in its config", m.currentServer.Name)
+		return m, nil
+	}
+	fullPath := filepath.Join(m.currentFolder.Path, m.currentFile.Name)
+
+	var cmds []tea.Cmd
+	var skipped []string
+	m.warm.mu.Lock()
+	for _, srv := range m.groupServers(group) {
+		// Nobody is there to answer a prompt for a background tail
+		if m.needsSudoCredentials(srv) || srv.Auth.Method == "keyboard-interactive" {
+			skipped = append(skipped, srv.Name)
+			continue
+		}
+		w := &warmTail{server: srv, path: fullPath, limit: m.cfg.Defaults.TailLines}
+		m.warm.tails[warmKey(srv, fullPath)] = w
+		cmds = append(cmds, startWarmTailCmd(m.pool, w))
+	}
+	if len(cmds) > 0 {
+		m.warm.group = group
+	}
+	m.warm.mu.Unlock()
+
+	if len(cmds) == 0 {
+		m.errorMsg = i18n.T("no server of group %s to tail", group)
+		return m, nil
+	}
+	m.warmPending = len(cmds)
+	m.warmFailed = nil
+	note := ""
+	if len(skipped) > 0 {
+		note = fmt.Sprintf(", skipping %s (needs a password)", strings.Join(skipped, ", "))
+	}
+	m.setContext(i18n.T("\033[33mWarming\033[0m %s on %d server(s) of %s%s…", fullPath, len(cmds), group, note))
+	return m, tea.Batch(cmds...)
+}
+
+// onWarmTail reports the background tails of the group once they have all
+// started or failed.
+func (m Model) onWarmTail(msg WarmTailMsg) (tea.Model, tea.Cmd) {
+	if msg.Err != nil {
+		logger.Log("app", "tail group: %s: %v", msg.Server, msg.Err)
+		m.warmFailed = append(m.warmFailed, msg.Server)
+	}
+	if m.warmPending == 0 {
+		return m, nil
+	}
+	m.warmPending--
+	if m.warmPending > 0 || m.warm.group == "" {
+		return m, nil
+	}
+	if len(m.warmFailed) > 0 {
+		m.setContext(i18n.T("\033[33mTail group\033[0m %s warm — \033[90mfailed on %s, see -debug log\033[0m", m.warm.group, strings.Join(m.warmFailed, ", ")))
+	} else {
+		m.setContext(i18n.T("\033[38;2;3;175;255mTail group\033[0m %s warm — \033[90mswitch servers to see the file at once, Ctrl-G to stop\033[0m", m.warm.group))
+	}
+	return m, nil
+}
+
+// adoptWarmTail shows a warm tail of fullPath on srv in the viewer, if the
+// group has one running.
+func (m *Model) adoptWarmTail(srv config.ServerConfig, fullPath string) (tea.Cmd, bool) {
+	w := m.warm.get(srv, fullPath)
+	if w == nil {
+		return nil, false
+	}
+	ch := make(chan []byte)
+	content, startLine, ok := w.adopt(ch)
+	if !ok {
+		return nil, false
+	}
+	// Rotations seen in the background are behind the lines kept
+	for drained := false; !drained; {
+		select {
+		case <-w.tailer.Rotations():
+		default:
+			drained = true
+		}
+	}
+	logger.Log("app", "showing warm tail of %s:%s", srv.Name, fullPath)
+	m.tailChan = ch
+	return tea.Sequence(
+		func() tea.Msg { return FileContentMsg{Content: content, StartLine: startLine} },
+		func() tea.Msg { return TailStartedMsg{Tailer: w.tailer, Cancel: w.detach} },
+	), true
+}
+
+// startWarmTailCmd reads the last lines of a warm tail's file and starts
+// tailing it in the background.
+func startWarmTailCmd(pool *ssh.Pool, w *warmTail) tea.Cmd {
+	srv := w.server
+	return func() tea.Msg {
+		fail := func(err error) tea.Msg {
+			w.mu.Lock()
+			w.dead = true
+			w.mu.Unlock()
+			return WarmTailMsg{Server: srv.Name, Err: err}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), srv.ConnectTimeout)
+		defer cancel()
+		client, err := pool.GetClient(ctx, srv)
+		if err != nil {
+			return fail(err)
+		}
+		opts := commandOpts(pool, srv)
+
+		cmdCtx, cmdCancel := context.WithTimeout(context.Background(), srv.CommandTimeout)
+		defer cmdCancel()
+		total, content, err := ssh.CountAndReadFileContent(cmdCtx, client, w.path, w.limit, opts)
+		if err != nil {
+			return fail(err)
+		}
+
+		in := make(chan []byte, 64)
+		tailCtx, tailCancel := context.WithCancel(context.Background())
+		tailer, err := ssh.StartTail(tailCtx, client, w.path, 0, &chanWriter{ch: in}, opts)
+		if err != nil {
+			tailCancel()
+			return fail(err)
+		}
+		release := pool.Hold(srv)
+		go func() {
+			<-tailer.Done()
+			release()
+		}()
+		tailer.SetErrCallback(func(error) { close(in) })
+
+		w.mu.Lock()
+		w.tailer = tailer
+		w.cancel = tailCancel
+		w.startLine = max(total-w.limit+1, 1)
+		if content != "" {
+			w.buffer(content)
+		}
+		w.ready = true
+		if w.stopped {
+			tailCancel()
+		}
+		w.mu.Unlock()
+		go w.pump(in)
+		return WarmTailMsg{Server: srv.Name}
+	}
+}
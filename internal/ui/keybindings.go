@@ -1,6 +1,13 @@
 package ui
 
-import "github.com/charmbracelet/bubbles/key"
+import (
+	"fmt"
+	"strings"
+
+	"log-monitor/internal/i18n"
+
+	"github.com/charmbracelet/bubbles/key"
+)
 
 type keyMap struct {
 	Quit       key.Binding
@@ -12,13 +19,40 @@ type keyMap struct {
 	Down       key.Binding
 	Home       key.Binding
 	End        key.Binding
+	Info        key.Binding
+	Search      key.Binding
+	Note        key.Binding
+	Processes   key.Binding
+	Service     key.Binding
 	Download    key.Binding
 	TailFilter  key.Binding
 	Refresh     key.Binding
+	RefreshAll  key.Binding
+	SudoRetry   key.Binding
+	Columns     key.Binding
+	Mark        key.Binding
 	ResumeTail  key.Binding
+	Catalog     key.Binding
+	RestartTail key.Binding
 	GotoTop     key.Binding
 	GotoBottom  key.Binding
 	Wrap        key.Binding
+	Align       key.Binding
+	CopyLine    key.Binding
+	RawMode     key.Binding
+	Marker      key.Binding
+	LastMarker  key.Binding
+	Export      key.Binding
+	Metrics     key.Binding
+	Chart       key.Binding
+	LookupIP    key.Binding
+	KillAll     key.Binding
+	Stats       key.Binding
+	TailGroup   key.Binding
+	Trace       key.Binding
+	Patterns    key.Binding
+	FileAction  key.Binding
+	Help        key.Binding
 }
 
 var keys = keyMap{
@@ -58,6 +92,26 @@ var keys = keyMap{
 		key.WithKeys("end"),
 		key.WithHelp("End", "Scroll to bottom"),
 	),
+	Info: key.NewBinding(
+		key.WithKeys("f2"),
+		key.WithHelp("F2", "Server info"),
+	),
+	Search: key.NewBinding(
+		key.WithKeys("f3"),
+		key.WithHelp("F3", "Search folder"),
+	),
+	Note: key.NewBinding(
+		key.WithKeys("f4"),
+		key.WithHelp("F4", "File note"),
+	),
+	Processes: key.NewBinding(
+		key.WithKeys("ctrl+w"),
+		key.WithHelp("Ctrl-W", "Who has the file open"),
+	),
+	Service: key.NewBinding(
+		key.WithKeys("ctrl+u"),
+		key.WithHelp("Ctrl-U", "Service status"),
+	),
 	Download: key.NewBinding(
 		key.WithKeys("f5"),
 		key.WithHelp("F5", "Download"),
@@ -66,6 +120,22 @@ var keys = keyMap{
 		key.WithKeys("f6"),
 		key.WithHelp("F6", "Refresh"),
 	),
+	RefreshAll: key.NewBinding(
+		key.WithKeys("f18"), // Shift-F6 in xterm-like terminals
+		key.WithHelp("Shift-F6", "Refresh all folders"),
+	),
+	SudoRetry: key.NewBinding(
+		key.WithKeys("ctrl+s"),
+		key.WithHelp("Ctrl-S", "Retry listing with sudo"),
+	),
+	Columns: key.NewBinding(
+		key.WithKeys("alt+t"),
+		key.WithHelp("Alt-T", "Toggle columns"),
+	),
+	Mark: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("Space", "Mark to tail together"),
+	),
 	TailFilter: key.NewBinding(
 		key.WithKeys("f7"),
 		key.WithHelp("F7", "Tail filter"),
@@ -74,6 +144,14 @@ var keys = keyMap{
 		key.WithKeys("f8"),
 		key.WithHelp("F8", "Resume tail"),
 	),
+	Catalog: key.NewBinding(
+		key.WithKeys("f9"),
+		key.WithHelp("F9", "Refresh catalog"),
+	),
+	RestartTail: key.NewBinding(
+		key.WithKeys("ctrl+r"),
+		key.WithHelp("Ctrl-R", "Restart tail"),
+	),
 	GotoTop: key.NewBinding(
 		key.WithKeys("g"),
 		key.WithHelp("g", "Top"),
@@ -86,12 +164,123 @@ var keys = keyMap{
 		key.WithKeys("w"),
 		key.WithHelp("w", "Toggle wrap"),
 	),
+	Align: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "Align columns"),
+	),
+	CopyLine: key.NewBinding(
+		key.WithKeys("y"),
+		key.WithHelp("y", "Copy line"),
+	),
+	RawMode: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "Raw mode"),
+	),
+	Marker: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "Add marker"),
+	),
+	LastMarker: key.NewBinding(
+		key.WithKeys("M"),
+		key.WithHelp("M", "Jump to marker"),
+	),
+	Export: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "Export"),
+	),
+	Metrics: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "Metrics"),
+	),
+	Chart: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "Chart"),
+	),
+	LookupIP: key.NewBinding(
+		key.WithKeys("i"),
+		key.WithHelp("i", "IP info"),
+	),
+	KillAll: key.NewBinding(
+		key.WithKeys("ctrl+x"),
+		key.WithHelp("Ctrl-X", "Kill all sessions"),
+	),
+	Stats: key.NewBinding(
+		key.WithKeys("ctrl+t"),
+		key.WithHelp("Ctrl-T", "Connection stats"),
+	),
+	TailGroup: key.NewBinding(
+		key.WithKeys("ctrl+g"),
+		key.WithHelp("Ctrl-G", "Tail group"),
+	),
+	Trace: key.NewBinding(
+		key.WithKeys("ctrl+f"),
+		key.WithHelp("Ctrl-F", "Trace request ID"),
+	),
+	Patterns: key.NewBinding(
+		key.WithKeys("ctrl+p"),
+		key.WithHelp("Ctrl-P", "Edit file patterns"),
+	),
+	FileAction: key.NewBinding(
+		key.WithKeys("ctrl+d"),
+		key.WithHelp("Ctrl-D", "Truncate or delete file"),
+	),
+	Help: key.NewBinding(
+		key.WithKeys("f1"),
+		key.WithHelp("F1", "Shortcuts"),
+	),
+}
+
+// paneBindings returns the shortcuts that work in a pane, for the F1
+// overlay: the pane's own first, then the global ones.
+func paneBindings(p pane, folderMode, catalog bool) []key.Binding {
+	var own []key.Binding
+	switch {
+	case p == paneServer:
+		own = []key.Binding{keys.Enter, keys.Up, keys.Down}
+		if catalog {
+			own = append(own, keys.Catalog)
+		}
+	case p == paneFile && folderMode:
+		own = []key.Binding{keys.Enter, keys.Up, keys.Down, keys.RefreshAll}
+	case p == paneFile:
+		own = []key.Binding{keys.Enter, keys.Up, keys.Down, keys.Search, keys.Note, keys.Processes, keys.Service, keys.Download, keys.Refresh, keys.RefreshAll, keys.SudoRetry, keys.Columns, keys.Mark, keys.TailGroup, keys.Trace, keys.Patterns, keys.FileAction}
+	case p >= panePlugin:
+		// Plugins document their own keys
+	default:
+		own = []key.Binding{
+			keys.Home, keys.End, keys.GotoTop, keys.GotoBottom,
+			keys.Search, keys.Note, keys.Processes, keys.Service, keys.Refresh, keys.TailFilter, keys.ResumeTail, keys.RestartTail, keys.TailGroup, keys.Trace, keys.Patterns, keys.FileAction,
+			keys.Wrap, keys.Align, keys.RawMode, keys.CopyLine, keys.Marker, keys.LastMarker,
+			keys.Export, keys.Metrics, keys.Chart, keys.LookupIP,
+		}
+	}
+	global := []key.Binding{keys.Tab, keys.ShiftTab, keys.Escape, keys.Info, keys.Stats, keys.KillAll, keys.Help, keys.Quit}
+	return append(own, global...)
+}
+
+// paneNames are the pane titles used in the F1 overlay.
+var paneNames = map[pane]string{
+	paneServer: "Locations",
+	paneFile:   "Files",
+	paneViewer: "Log Viewer",
+}
+
+// renderHelp lists bindings one per line, keys aligned in a column.
+func renderHelp(bindings []key.Binding) string {
+	lines := make([]string, len(bindings))
+	for i, b := range bindings {
+		h := b.Help()
+		lines[i] = statusKeyStyle.Render(fmt.Sprintf("%-9s", h.Key)) + " " + statusSepStyle.Render(i18n.T(h.Desc))
+	}
+	return strings.Join(lines, "\n")
 }
 
 // Pane-specific shortcut hint strings.
 const (
-	shortcutsListPane   = "Type: Filter | Enter: Select | Tab: Switch pane | Esc: Clear filter | Ctrl-C: Exit"
-	shortcutsFolderPane = "Enter: Select folder | Tab: Switch pane | Ctrl-C: Exit"
-	shortcutsFilePane   = "Type: Filter | Enter: Select file | F5: Download | F6: Refresh | Tab: Switch pane | Esc: Clear filter | Ctrl-C: Exit"
-	shortcutsViewerPane = "F6: Refresh | F7: Filter | g/G: Top/Bottom | w: Wrap | Shift+Click: Select text | Esc: Stop tail | Ctrl-C: Exit"
+	shortcutsListPane    = "F1: Help | Type: Filter | Enter: Select | Tab: Switch pane | Esc: Clear filter | Ctrl-C: Exit"
+	shortcutsCatalogPane = "F1: Help | Type: Filter | Enter: Select | F9: Refresh catalog | Tab: Switch pane | Esc: Clear filter | Ctrl-C: Exit"
+	shortcutsFolderPane  = "F1: Help | Enter: Select folder | F2: Info | Shift-F6: Refresh all | Tab: Switch pane | Ctrl-C: Exit"
+	shortcutsFilePane    = "F1: Help | Type: Filter | Enter: Select file | F2: Info | F4: Note | F5: Download | F6: Refresh | Tab: Switch pane | Esc: Clear filter | Ctrl-C: Exit"
+	shortcutsPluginPane  = "F1: Help | Tab: Switch pane | Ctrl-C: Exit"
+	shortcutsViewerPane  = "F1: Help | F4: Note | F6: Refresh | F7: Filter | Ctrl-R: Restart | g/G: Top/Bottom | w: Wrap | a: Align | y: Copy line | i: IP info | e: Export | s/c: Metrics/Chart | r: Raw | m/M: Marker/Jump | Shift+Click: Select text | Esc: Stop tail | Ctrl-C: Exit"
 )
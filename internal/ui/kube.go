@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"context"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/i18n"
+	"log-monitor/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// kubeOf returns which pods a kubectl folder shows and where kubectl runs.
+func kubeOf(folder config.LogFolder) ssh.Kube {
+	return ssh.Kube{Namespace: folder.Namespace, Selector: folder.Selector, Kubeconfig: folder.Kubeconfig}
+}
+
+// isLocalKube reports whether a folder's pods are reached with kubectl on
+// this machine, without connecting to the server.
+func isLocalKube(folder config.LogFolder) bool {
+	return folder.IsKubectl() && kubeOf(folder).Local()
+}
+
+// listFolder lists the entries of a folder: its files, or the pods of a
+// kubectl folder. client may be nil for pods listed locally.
+func listFolder(ctx context.Context, client *gossh.Client, srv config.ServerConfig, folder config.LogFolder, opts ssh.CommandOpts) ([]ssh.FileInfo, error) {
+	if folder.IsKubectl() {
+		return ssh.ListPods(ctx, client, kubeOf(folder), opts)
+	}
+	return ssh.Backend(srv.FileBackend).ListFiles(ctx, client, folder.Path, folder.FilePatterns, opts)
+}
+
+// kubectlRefused reports, with an error in the status bar, that the current
+// folder holds pods, which an action for files can't handle.
+func (m *Model) kubectlRefused() bool {
+	if m.currentFolder == nil || !m.currentFolder.IsKubectl() || m.filePane.IsInFolderMode() {
+		return false
+	}
+	m.errorMsg = i18n.T("not available for the pods of a kubectl folder")
+	return true
+}
+
+// startPodLogsCmd follows the log of a pod of a kubectl folder, starting
+// with its last lines, and sends it to ch.
+func startPodLogsCmd(pool *ssh.Pool, srv config.ServerConfig, folder config.LogFolder, name string, lines int, ch chan<- []byte) tea.Cmd {
+	return func() tea.Msg {
+		k := kubeOf(folder)
+		var client *gossh.Client
+		if !k.Local() {
+			ctx, cancel := context.WithTimeout(context.Background(), srv.ConnectTimeout)
+			defer cancel()
+			var err error
+			if client, err = pool.GetClient(ctx, srv); err != nil {
+				return TailErrorMsg{Err: err}
+			}
+		}
+
+		tailCtx, tailCancel := context.WithCancel(context.Background())
+		tailer, err := ssh.StartPodLogs(tailCtx, client, k, name, lines, &chanWriter{ch: ch}, commandOpts(pool, srv))
+		if err != nil {
+			tailCancel()
+			return TailErrorMsg{Err: err}
+		}
+		if !k.Local() {
+			release := pool.Hold(srv)
+			go func() {
+				<-tailer.Done()
+				release()
+			}()
+		}
+		tailer.SetErrCallback(func(error) { close(ch) })
+
+		return TailStartedMsg{Tailer: tailer, Cancel: tailCancel}
+	}
+}
+
+// followCmd follows the current file from its end, or the log of the
+// current pod from now on.
+func (m Model) followCmd(fullPath string, ch chan<- []byte) tea.Cmd {
+	if m.currentFolder.IsKubectl() {
+		return startPodLogsCmd(m.pool, *m.currentServer, *m.currentFolder, m.currentFile.Name, 0, ch)
+	}
+	return startTailCmd(m.pool, *m.currentServer, fullPath, ch)
+}
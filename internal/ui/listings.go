@@ -0,0 +1,180 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/i18n"
+	"log-monitor/internal/logger"
+	"log-monitor/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// newFilesFlash is how long folders that gained files stay marked after a
+// refresh of all folders.
+const newFilesFlash = 5 * time.Second
+
+// listingKey identifies a folder listing: the server key and folder path.
+type listingKey struct {
+	server string
+	folder string
+}
+
+// rememberListing records the file names of a folder listing and returns
+// how many weren't in the previous one. known is false for the first
+// listing of the folder.
+func (m *Model) rememberListing(srv config.ServerConfig, folder string, files []ssh.FileInfo) (added int, known bool) {
+	key := listingKey{ssh.ServerKey(srv), folder}
+	prev, known := m.listings[key]
+	names := make(map[string]bool, len(files))
+	for _, f := range files {
+		names[f.Name] = true
+		if known && !prev[f.Name] {
+			added++
+		}
+	}
+	m.listings[key] = names
+	return added, known
+}
+
+// refreshAllFolders lists every folder of the current server at once.
+func (m Model) refreshAllFolders() (tea.Model, tea.Cmd) {
+	if m.currentServer == nil {
+		m.errorMsg = i18n.T("select a server first")
+		return m, nil
+	}
+	srv := *m.currentServer
+	m.setContext(i18n.T("\033[33mRefreshing\033[0m %d folder(s) on %s...", len(srv.LogFolders), srv.Name))
+	return m, refreshAllFoldersCmd(m.pool, srv)
+}
+
+// onFoldersRefreshed compares the listings with the previous ones, marks
+// the folders that gained files for a moment and updates the open folder.
+func (m Model) onFoldersRefreshed(msg FoldersRefreshedMsg) (tea.Model, tea.Cmd) {
+	var gained, failed []string
+	newFiles := make(map[string]int)
+	var cmds []tea.Cmd
+	for _, l := range msg.Listings {
+		if l.Err != nil {
+			logger.Log("app", "refreshing %s on %s: %v", l.Folder, msg.Server.Name, l.Err)
+			failed = append(failed, l.Folder)
+			continue
+		}
+		if added, _ := m.rememberListing(msg.Server, l.Folder, l.Files); added > 0 {
+			gained = append(gained, fmt.Sprintf("%s (+%d)", l.Folder, added))
+			newFiles[l.Folder] = added
+		}
+		if m.currentServer != nil && ssh.ServerKey(*m.currentServer) == ssh.ServerKey(msg.Server) &&
+			m.currentFolder != nil && m.currentFolder.Path == l.Folder && !m.filePane.IsInFolderMode() {
+			loaded := FilesLoadedMsg{Files: l.Files, Dir: l.Folder, ShowUpDir: len(msg.Server.LogFolders) > 1, Denied: l.Denied}
+			cmds = append(cmds, func() tea.Msg { return loaded })
+		}
+	}
+
+	summary := fmt.Sprintf("\033[90mNo new files in %d folder(s)\033[0m", len(msg.Listings)-len(failed))
+	if len(gained) > 0 {
+		summary = "\033[32mNew files:\033[0m " + strings.Join(gained, ", ")
+	}
+	m.setContext(summary)
+	if len(failed) > 0 {
+		m.errorMsg = i18n.T("refresh failed for %s", strings.Join(failed, ", "))
+	}
+
+	if len(newFiles) > 0 {
+		m.filePane.SetNewFiles(newFiles)
+		m.flashGen++
+		gen := m.flashGen
+		cmds = append(cmds, tea.Tick(newFilesFlash, func(time.Time) tea.Msg {
+			return newFilesFlashDoneMsg{gen: gen}
+		}))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// newFilesFlashDoneMsg ends the marking of folders that gained files.
+type newFilesFlashDoneMsg struct {
+	gen int
+}
+
+// refreshAllFoldersCmd connects to a server and lists all its folders in
+// parallel.
+func refreshAllFoldersCmd(pool *ssh.Pool, srv config.ServerConfig) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), srv.ConnectTimeout)
+		defer cancel()
+
+		// Files of a local server and pods listed with kubectl here need no
+		// connection
+		var client *gossh.Client
+		if !srv.Local && slices.ContainsFunc(srv.LogFolders, func(f config.LogFolder) bool { return !isLocalKube(f) }) {
+			var err error
+			if client, err = pool.GetClient(ctx, srv); err != nil {
+				return connectErrorMsg(srv, err)
+			}
+		}
+		opts := commandOpts(pool, srv)
+
+		listings := make([]FolderListing, len(srv.LogFolders))
+		var wg sync.WaitGroup
+		for i, folder := range srv.LogFolders {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cmdCtx, cmdCancel := context.WithTimeout(context.Background(), srv.CommandTimeout)
+				defer cmdCancel()
+				files, err := listFolder(cmdCtx, client, srv, folder, opts)
+				denied, err := splitPartial(err)
+				listings[i] = FolderListing{Folder: folder.Path, Files: files, Denied: denied, Err: err}
+			}()
+		}
+		wg.Wait()
+		return FoldersRefreshedMsg{Server: srv, Listings: listings}
+	}
+}
+
+// warnPartialListing flags a listing that ls could only partly read, with
+// a warning above the files and, where sudo can help, the key to retry.
+func (m Model) warnPartialListing(denied int) Model {
+	m.listDenied = denied > 0 && m.canRetryWithSudo()
+	switch {
+	case denied == 0:
+		m.filePane.SetWarning("")
+	case m.listDenied:
+		m.filePane.SetWarning(i18n.T("%d entries unreadable — Ctrl-S: retry with sudo", denied))
+	default:
+		m.filePane.SetWarning(i18n.T("%d entries unreadable", denied))
+	}
+	return m
+}
+
+// canRetryWithSudo reports whether the current server's listing could be
+// retried with sudo: it doesn't use sudo yet and lists over the shell.
+func (m Model) canRetryWithSudo() bool {
+	return m.currentServer != nil && !m.currentServer.Sudo && m.currentServer.FileBackend != "sftp"
+}
+
+// retryListingWithSudo lists the current folder again with sudo, which
+// stays on for the server until it is selected again.
+func (m Model) retryListingWithSudo() (tea.Model, tea.Cmd) {
+	if m.currentServer == nil || m.currentFolder == nil {
+		return m, nil
+	}
+	srv := *m.currentServer
+	srv.Sudo = true
+	m.currentServer = &srv
+	m.listDenied = false
+	logger.Log("app", "retrying listing of %s on %s with sudo", m.currentFolder.Path, srv.Name)
+	// Only the listing is refreshed; the open file stays as it is
+	m.onFilesLoaded = func(*Model) tea.Cmd { return nil }
+	if m.needsSudoCredentials(srv) {
+		return m, m.startSudoProbe(srv)
+	}
+	return m, m.startConnection(srv)
+}
@@ -2,6 +2,8 @@ package ui
 
 import (
 	"log-monitor/internal/config"
+	"log-monitor/internal/enrich"
+	"log-monitor/internal/events"
 	"log-monitor/internal/ssh"
 )
 
@@ -16,11 +18,128 @@ type ConnectErrorMsg struct {
 	Server config.ServerConfig
 }
 
+// HostKeyPromptMsg signals that a server presented a host key that is not
+// in known_hosts (or differs from it) and the user must decide.
+type HostKeyPromptMsg struct {
+	Server config.ServerConfig // server being connected to
+	Err    *ssh.HostKeyError
+}
+
+// HostKeyAcceptedMsg signals that a host key was added to known_hosts.
+type HostKeyAcceptedMsg struct {
+	Server config.ServerConfig
+}
+
+// KeyPassphraseMsg signals that a server's private key is encrypted and its
+// passphrase must be entered before connecting.
+type KeyPassphraseMsg struct {
+	Server config.ServerConfig // server being connected to
+	Err    *ssh.PassphraseError
+	Failed string // why the previous passphrase was rejected, if any
+}
+
+// KeyUnlockedMsg signals that a private key was decrypted for this session.
+type KeyUnlockedMsg struct {
+	Server config.ServerConfig
+}
+
+// AuthChallengeMsg carries a keyboard-interactive prompt from a server
+// that is being logged into.
+type AuthChallengeMsg struct {
+	Challenge ssh.Challenge
+}
+
+// challengeExpiredMsg signals that the login a challenge came from no
+// longer waits for its answers.
+type challengeExpiredMsg struct {
+	reply chan<- []string
+}
+
+// CatalogLoadedMsg carries the servers read from the shared catalog.
+type CatalogLoadedMsg struct {
+	Servers []config.ServerConfig
+	Err     error
+	Initial bool // loaded at startup rather than by an explicit refresh
+}
+
+// ExportDoneMsg signals that the viewer contents were written to a file.
+type ExportDoneMsg struct {
+	Path  string
+	Lines int
+	Err   error
+}
+
+// ConnStateMsg reports a change in a pooled connection's state.
+type ConnStateMsg struct {
+	Change ssh.StateChange
+}
+
+// EventsLoadedMsg carries the events read from the event feed.
+type EventsLoadedMsg struct {
+	Events []events.Event
+	Err    error
+}
+
+// EagerConnectDoneMsg reports the end of a startup connection attempt.
+type EagerConnectDoneMsg struct {
+	Server config.ServerConfig
+	Err    error
+}
+
+// KillDoneMsg reports what the kill switch closed.
+type KillDoneMsg struct {
+	Sessions int
+	Conns    int
+}
+
+// EnrichDoneMsg carries what was found out about IP addresses in the logs.
+type EnrichDoneMsg struct {
+	Infos []enrich.Info
+}
+
+// FileProcessesMsg carries the processes found to have a remote file open.
+type FileProcessesMsg struct {
+	Path  string
+	Procs []ssh.FileProcess
+	Sudo  bool // listed with sudo, so other users' processes are included
+	Err   error
+}
+
+// ServiceStatusMsg carries what systemd says about a folder's unit: its
+// status, or its journal when Journal is set.
+type ServiceStatusMsg struct {
+	Unit    string
+	Output  string
+	Journal bool
+	Err     error
+}
+
+// HostInfoMsg signals that the remote hostname of a server is now known.
+type HostInfoMsg struct {
+	Server config.ServerConfig
+}
+
 // FilesLoadedMsg carries the file listing result.
 type FilesLoadedMsg struct {
 	Files     []ssh.FileInfo
 	Dir       string
+	Root      string // the configured folder when Dir is a subdirectory of it
 	ShowUpDir bool
+	Denied    int // entries ls could not read, 0 if the listing is complete
+}
+
+// FolderListing is the listing of one folder in FoldersRefreshedMsg.
+type FolderListing struct {
+	Folder string
+	Files  []ssh.FileInfo
+	Denied int // entries ls could not read
+	Err    error
+}
+
+// FoldersRefreshedMsg carries the listings of all folders of a server.
+type FoldersRefreshedMsg struct {
+	Server   config.ServerConfig
+	Listings []FolderListing
 }
 
 // FilesErrorMsg signals a file listing failure.
@@ -31,12 +150,35 @@ type FilesErrorMsg struct {
 // SudoRetryMsg signals that sudo auth failed and we should re-prompt.
 type SudoRetryMsg struct {
 	Server config.ServerConfig
+	FileOp bool // failure came from reading a file rather than listing
+}
+
+// SudoProbeMsg carries the result of checking for passwordless sudo.
+type SudoProbeMsg struct {
+	Server   config.ServerConfig
+	NoPasswd bool
+}
+
+// CommandPasswordMsg carries the sudo password printed by a server's
+// password_command.
+type CommandPasswordMsg struct {
+	Server   config.ServerConfig
+	Password string
+	Err      error
+}
+
+// KeychainPasswordMsg carries the sudo password found in the OS keychain
+// (empty if none was stored).
+type KeychainPasswordMsg struct {
+	Server   config.ServerConfig
+	Password string
 }
 
 // FileContentMsg carries the initial file content.
 type FileContentMsg struct {
 	Content   string
 	StartLine int
+	Cursor    int // line to put the cursor on, 0 for none
 }
 
 // FileReadErrorMsg signals a file read failure.
@@ -63,6 +205,12 @@ type TailErrorMsg struct {
 // TailStoppedMsg signals the tail channel was closed.
 type TailStoppedMsg struct{}
 
+// DownloadStatMsg carries the size of a file about to be downloaded.
+type DownloadStatMsg struct {
+	Size int64
+	Err  error
+}
+
 // DownloadProgressMsg carries download progress information.
 type DownloadProgressMsg struct {
 	BytesDownloaded int64
@@ -87,3 +235,17 @@ type StatusMsg struct {
 	Context string
 	Error   string
 }
+
+// WarmTailMsg signals that a background tail of a tail group started, or
+// why it could not.
+type WarmTailMsg struct {
+	Server string // server name
+	Err    error
+}
+
+// TailRotatedMsg signals that the tailed file rotated: the lines after it
+// come from a new file.
+type TailRotatedMsg struct {
+	Tailer   *ssh.Tailer
+	Rotation ssh.TailRotation
+}
@@ -0,0 +1,149 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"log-monitor/internal/i18n"
+	"log-monitor/internal/metrics"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// feedMetrics extracts metrics from newly received log text. Lines without
+// a timestamp (e.g. continuations) take the time of the line before them.
+func (m Model) feedMetrics(text []byte) {
+	if !m.metrics.Enabled() {
+		return
+	}
+	now := time.Now()
+	at := now
+	for _, line := range strings.Split(string(text), "\n") {
+		line = plainText(line)
+		if line == "" {
+			continue
+		}
+		if t, ok := lineTime(line, now); ok {
+			at = t
+		}
+		m.metrics.Add(line, at)
+	}
+}
+
+// lineTime returns when a line was logged, going by its leading time of day
+// in the local time zone. A time of day later than now is taken to be from
+// the day before.
+func lineTime(line string, now time.Time) (time.Time, bool) {
+	clock, ok := lineTimeOfDay(line)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse("15:04:05", clock)
+	if err != nil {
+		return time.Time{}, false
+	}
+	at := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), 0, now.Location())
+	if at.After(now.Add(time.Minute)) {
+		at = at.AddDate(0, 0, -1)
+	}
+	return at, true
+}
+
+// showMetrics opens the summary of the metrics extracted from the open file.
+func (m Model) showMetrics() Model {
+	if !m.metrics.Enabled() {
+		m.errorMsg = i18n.T("no metrics configured (see metrics: in the config)")
+		return m
+	}
+	if m.currentFile == nil {
+		m.errorMsg = i18n.T("open a file to see its metrics")
+		return m
+	}
+	m.modal = modalMetrics
+	return m
+}
+
+// cycleChart charts the next metric below the viewer, in order of first
+// appearance, and hides the chart after the last one.
+func (m Model) cycleChart() Model {
+	if !m.metrics.Enabled() {
+		m.errorMsg = i18n.T("no metrics configured (see metrics: in the config)")
+		return m
+	}
+	names := m.metrics.Names()
+	next := ""
+	if current := m.chartPane.Metric(); current == "" {
+		if len(names) == 0 {
+			m.errorMsg = i18n.T("no metric values in this file yet")
+			return m
+		}
+		next = names[0]
+	} else if i := slices.Index(names, current); i >= 0 && i+1 < len(names) {
+		next = names[i+1]
+	}
+	m.chartPane.SetMetric(next)
+	m.recalcSizes()
+	if next == "" {
+		m.contextMsg = m.lastContext
+	} else if !m.chartShown() {
+		m.errorMsg = i18n.T("window too small for the chart")
+	} else {
+		m.contextMsg = i18n.T("\033[36mCharting %s\033[0m — c: next metric", next)
+	}
+	return m
+}
+
+// exportMetrics saves every extracted sample as CSV to the download
+// directory.
+func (m Model) exportMetrics() (tea.Model, tea.Cmd) {
+	var buf bytes.Buffer
+	rows, err := m.metrics.WriteCSV(&buf)
+	if err != nil {
+		m.errorMsg = i18n.T("export: %v", err)
+		return m, nil
+	}
+	name := fmt.Sprintf("%s-metrics-%s.csv", m.currentFile.Name, time.Now().Format("20060102-150405"))
+	return m, exportTextCmd(filepath.Join(m.downloadDir(), name), buf.String(), rows)
+}
+
+// metricsSummary renders the min/avg/max/p95 table for the metrics popup.
+func (m Model) metricsSummary() string {
+	names := m.metrics.Names()
+	if len(names) == 0 {
+		return modalHintStyle.Render("No matching lines yet")
+	}
+	nameWidth := len("metric")
+	for _, name := range names {
+		nameWidth = max(nameWidth, len(name))
+	}
+	row := func(cols ...string) string {
+		line := fmt.Sprintf("%-*s", nameWidth, cols[0])
+		for _, c := range cols[1:] {
+			line += fmt.Sprintf(" %9s", c)
+		}
+		return line
+	}
+
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
+	lines := []string{modalHintStyle.Render(row("metric", "count", "min", "avg", "max", "p95"))}
+	for _, name := range names {
+		s := metrics.Summarize(m.metrics.Series(name))
+		lines = append(lines, valueStyle.Render(row(name, fmt.Sprint(s.Count),
+			formatMetric(s.Min), formatMetric(s.Avg), formatMetric(s.Max), formatMetric(s.P95))))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatMetric shows whole numbers as such and others with two decimals.
+func formatMetric(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
@@ -2,15 +2,23 @@ package ui
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"log-monitor/internal/config"
+	"log-monitor/internal/enrich"
+	"log-monitor/internal/events"
+	"log-monitor/internal/i18n"
 	"log-monitor/internal/logger"
+	"log-monitor/internal/metrics"
 	"log-monitor/internal/ssh"
+	"log-monitor/internal/state"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -23,6 +31,7 @@ const (
 	paneServer pane = iota
 	paneFile
 	paneViewer
+	panePlugin // first plugin pane; the others follow
 )
 
 type modalType int
@@ -32,12 +41,31 @@ const (
 	modalSudo
 	modalFilter
 	modalDownload
+	modalInfo
+	modalHostKey
+	modalNote
+	modalCatalog
+	modalPassphrase
+	modalChallenge
+	modalMetrics
+	modalEnrich
+	modalBanner
+	modalHelp
+	modalProcs
+	modalService
+	modalStats
+	modalSearch
+	modalTrace
+	modalPatterns
+	modalFileAction
 )
 
 type downloadPhase int
 
 const (
 	downloadPhaseInput    downloadPhase = iota
+	downloadPhaseChecking
+	downloadPhaseConfirm
 	downloadPhaseProgress
 	downloadPhaseDone
 	downloadPhaseError
@@ -48,12 +76,14 @@ type AutoSelect struct {
 	Server string
 	Folder string
 	File   string
+	Stdin  io.Reader // shown in the viewer like a tailed file (-stdin)
 }
 
 // Model is the top-level Bubble Tea model.
 type Model struct {
 	cfg        *config.Config
 	pool       *ssh.Pool
+	state      *state.Store
 	autoSelect AutoSelect
 	width      int
 	height     int
@@ -62,6 +92,7 @@ type Model struct {
 	serverPane ServerPaneModel
 	filePane   FilePaneModel
 	viewerPane ViewerPaneModel
+	chartPane  ChartPaneModel
 
 	// State
 	focused       pane
@@ -72,13 +103,67 @@ type Model struct {
 	tailCancel    func()
 	tailChan      chan []byte
 	tailing       bool
+	logDecoder    logDecoder // decodes the tail's data across chunks
+
+	// Tail group (Ctrl-G): background tails on the servers of a group
+	warm        *warmTails
+	warmPending int      // background tails still starting
+	warmFailed  []string // servers whose background tail failed
+
+	// Standard input (-stdin)
+	stdin     *stdinSource
+	stdinView bool // the viewer shows stdin
+
+	// Folder search (F3)
+	search       *folderSearch
+	searchCancel context.CancelFunc
+	lastSearch   string
+	atLine       int // line a search hit opened the file at; it isn't tailed
+
+	// Trace of a request ID across the server's folders (Ctrl-F)
+	trace       *trace
+	traceCancel context.CancelFunc
+	traceGroup  bool // the prompt traces across the server's group
+
+	// File patterns editor (Ctrl-P)
+	patternFiles []ssh.FileInfo // current folder listed without patterns; nil until listed
+	patternErr   error          // why it could not be listed
+	patternsSave bool           // the applied patterns are offered for saving
+
+	// Files of the folder tailed together (Space, Enter), with currentFile nil
+	multiFiles []string
 
 	// Modal state
-	modal       modalType
-	modalInput  textinput.Model
-	modalInput2 textinput.Model // second field for download
-	modalFocus  int             // which field focused in multi-field modals
-	sudoServer  *config.ServerConfig // server awaiting sudo password
+	modal         modalType
+	modalInput    textinput.Model
+	modalInput2   textinput.Model      // second field for download
+	modalFocus    int                  // which field focused in multi-field modals
+	sudoServer    *config.ServerConfig // server awaiting sudo password
+	sudoRetryFile *ssh.FileInfo        // file to re-open once sudo succeeds
+	sudoRemember  bool                 // store the password being entered in the OS keychain
+	sudoToStore   *config.ServerConfig // server whose password goes to the keychain once a listing accepts it
+	hostKeyErr    *ssh.HostKeyError    // unverified host key awaiting a decision
+	hostKeyServer *config.ServerConfig // server to reconnect to once it is accepted
+	passphraseErr *ssh.PassphraseError // locked private key awaiting its passphrase
+	passphraseSrv *config.ServerConfig // server to reconnect to once the key is unlocked
+	noteServer    string               // server key of the file being annotated
+	notePath      string               // remote path of the file being annotated
+	catalogDiff   []string             // server changes from the last catalog refresh
+	pendingPaste  string               // oversized paste awaiting confirmation
+	pasteLines    int                  // number of lines in pendingPaste
+	fileAction    *fileAction          // truncate or delete awaiting confirmation
+
+	// Keyboard-interactive login prompts
+	challengeCh      chan ssh.Challenge
+	challenge        *ssh.Challenge  // prompt being answered, one question at a time
+	challengeAnswers []string        // answers given so far to challenge
+	challengeQueue   []ssh.Challenge // prompts from parallel logins, shown next
+
+	connStateCh chan ssh.StateChange // connection state changes from the pool
+
+	// Startup connections (eager_connect)
+	eagerTotal int // connection attempts started
+	eagerDone  int // attempts finished, successfully or not
 
 	// Download progress state
 	downloadPhase           downloadPhase
@@ -111,19 +196,127 @@ type Model struct {
 
 	// Spinner tick state
 	spinnerTicking bool
+
+	// What this run did, for the summary on exit
+	session *sessionStats
+
+	// Tail auto-reconnect after a lost connection
+	reconnectAttempt int // 0 = not reconnecting
+	reconnectGen     int // bumped on cancel so stale ticks are ignored
+
+	// Deployment events from the event feed, shown as viewer markers
+	events       []events.Event
+	eventKeys    map[string]bool // keys of the events already known
+	eventsPolled bool            // the feed has been read at least once
+
+	metrics *metrics.Set // values extracted from the open file by the metrics rules
+	plugins []pluginPane // panes of the configured plugins, fed like metrics
+
+	// IP address lookups
+	geo         *enrich.GeoDB // local GeoIP database, nil if not configured
+	enrichInfos []enrich.Info // results shown in the lookup popup
+
+	procs   *FileProcessesMsg // processes using a file, shown in their popup
+	service *ServiceStatusMsg // systemd status or journal of the folder's unit
+
+	bannersShown map[string]bool // server keys whose banner popup was shown (show_banner)
+
+	listDenied bool // the last listing hit permission errors; Ctrl-S retries it with sudo
+
+	// File names of the last listing of each folder, to tell new files
+	listings map[listingKey]map[string]bool
+	flashGen int // bumped when folders are marked, so older unmark ticks are ignored
 }
 
 // NewModel creates the initial model.
 func NewModel(cfg *config.Config, autoSelect AutoSelect) Model {
-	return Model{
-		cfg:        cfg,
-		pool:       ssh.NewPool(),
-		autoSelect: autoSelect,
-		serverPane: NewServerPaneModel(cfg.Servers),
-		filePane:   NewFilePaneModel(),
-		viewerPane: NewViewerPaneModel(),
-		focused:    paneServer,
+	statePath := cfg.Defaults.StateFile
+	if statePath == "" {
+		statePath = state.DefaultPath()
+	}
+	store, err := state.Load(statePath)
+	metricSet, metricErr := metrics.NewSet(cfg.Metrics)
+	if metricErr != nil {
+		logger.Log("app", "metrics: %v", metricErr)
+		metricSet, _ = metrics.NewSet(nil)
+	}
+	challengeCh := make(chan ssh.Challenge)
+	connStateCh := make(chan ssh.StateChange, 64)
+	m := Model{
+		cfg:         cfg,
+		pool:        ssh.NewPool(cfg.Defaults.KnownHosts),
+		state:       store,
+		challengeCh: challengeCh,
+		connStateCh: connStateCh,
+		autoSelect:  autoSelect,
+		serverPane:  NewServerPaneModel(cfg.Servers),
+		filePane:    NewFilePaneModel(),
+		viewerPane:  NewViewerPaneModel(),
+		focused:     paneServer,
+		eventKeys:   make(map[string]bool),
+		metrics:     metricSet,
+		listings:    make(map[listingKey]map[string]bool),
+		session:     newSessionStats(),
+		warm:        &warmTails{tails: make(map[string]*warmTail)},
+
+		bannersShown: make(map[string]bool),
+	}
+	if len(cfg.Warnings) > 0 {
+		more := ""
+		if len(cfg.Warnings) > 1 {
+			more = fmt.Sprintf(" (+%d more, see -debug log)", len(cfg.Warnings)-1)
+		}
+		m.setContext(i18n.T("\033[33mConfig:\033[0m %s%s", cfg.Warnings[0], more))
+	}
+	if err != nil {
+		logger.Log("app", "state: %v", err)
+		m.errorMsg = err.Error()
+	}
+	m.viewerPane.SetDisplayOptions(cfg.Defaults.TabWidth, cfg.Defaults.ControlChars)
+	m.viewerPane.SetDimAfter(cfg.Defaults.DimAfter)
+	m.viewerPane.SetSpillHistory(cfg.Defaults.SpillHistory)
+	m.pool.SetChallenges(challengeCh)
+	for _, srv := range cfg.Servers {
+		if store.SudoNoPasswd(ssh.ServerKey(srv)) {
+			m.pool.SetSudoNoPasswd(srv)
+		}
+	}
+	m.pool.SetIdleTimeout(cfg.Defaults.IdleTimeout)
+	m.pool.SetStateCallback(func(c ssh.StateChange) {
+		select {
+		case connStateCh <- c:
+		default:
+			// Buffer full: the UI has stopped reading (e.g. while quitting)
+		}
+	})
+	if err := i18n.Load(cfg.Defaults.Locale, cfg.Defaults.LocaleFile); err != nil {
+		logger.Log("app", "%v", err)
+		m.errorMsg = err.Error()
+	}
+	if m.plugins, err = newPluginPanes(cfg.Plugins); err != nil {
+		logger.Log("app", "plugins: %v", err)
+		m.errorMsg = err.Error()
+	}
+	if cfg.Defaults.GeoIPDB != "" {
+		if m.geo, err = enrich.OpenGeoDB(cfg.Defaults.GeoIPDB); err != nil {
+			logger.Log("app", "%v", err)
+			m.errorMsg = err.Error()
+		}
+	}
+	if cfg.Defaults.ControlSocket != "" {
+		owner, err := m.pool.ShareConnections(cfg.Defaults.ControlSocket)
+		if err != nil {
+			logger.Log("app", "connection sharing: %v", err)
+			m.errorMsg = err.Error()
+		} else {
+			logger.Log("app", "connection sharing enabled (owner=%v)", owner)
+		}
+	}
+	if autoSelect.Stdin != nil {
+		m.stdin = newStdinSource(autoSelect.Stdin)
+		m.showStdin()
 	}
+	return m
 }
 
 // spinnerTickMsg is a periodic tick for the spinner animation.
@@ -135,17 +328,44 @@ func spinnerTickCmd() tea.Cmd {
 	})
 }
 
+// dimTickMsg is a periodic tick that dims the viewer lines grown old.
+type dimTickMsg struct{}
+
+// dimInterval is how often lines are checked for having grown old.
+const dimInterval = 30 * time.Second
+
+func dimTickCmd() tea.Cmd {
+	return tea.Tick(dimInterval, func(time.Time) tea.Msg {
+		return dimTickMsg{}
+	})
+}
+
 // Init implements tea.Model.
 func (m Model) Init() tea.Cmd {
 	setTerminalTitle("Log Monitor")
 
-	var cmds []tea.Cmd
+	cmds := []tea.Cmd{waitForChallenge(m.challengeCh), waitForConnState(m.connStateCh)}
+	if m.stdin != nil {
+		cmds = append(cmds, readStdinCmd(m.stdin), waitForStdin(m.stdin), spinnerTickCmd())
+	}
+	if m.cfg.Events.Enabled() {
+		cmds = append(cmds, fetchEventsCmd(m.cfg))
+	}
+	if m.cfg.Defaults.DimAfter > 0 {
+		cmds = append(cmds, dimTickCmd())
+	}
 
-	if m.autoSelect.Server != "" {
-		cmds = append(cmds, func() tea.Msg {
-			// Trigger auto-start after first render
-			return autoStartMsg{}
-		})
+	if m.cfg.Catalog.Source != "" {
+		// Auto-start waits for the catalog, which may define the server
+		cmds = append(cmds, loadCatalogCmd(m.cfg, true))
+	} else {
+		cmds = append(cmds, func() tea.Msg { return eagerConnectMsg{} })
+		if m.autoSelect.Server != "" {
+			cmds = append(cmds, func() tea.Msg {
+				// Trigger auto-start after first render
+				return autoStartMsg{}
+			})
+		}
 	}
 
 	return tea.Batch(cmds...)
@@ -153,6 +373,21 @@ func (m Model) Init() tea.Cmd {
 
 type autoStartMsg struct{}
 
+// eagerConnectMsg starts the startup connections once the server list is
+// final (after the catalog, if any, has loaded).
+type eagerConnectMsg struct{}
+
+// eventsTickMsg fires when the event feed is due to be polled again.
+type eventsTickMsg struct{}
+
+// reconnectTickMsg fires when the next tail reconnect attempt is due.
+type reconnectTickMsg struct {
+	gen int
+}
+
+// maxReconnectDelay caps the exponential backoff between reconnect attempts.
+const maxReconnectDelay = 30 * time.Second
+
 // Update implements tea.Model.
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
@@ -172,6 +407,55 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case autoStartMsg:
 		return m.autoStart()
 
+	case CatalogLoadedMsg:
+		return m.onCatalogLoaded(msg)
+
+	case EventsLoadedMsg:
+		return m.onEventsLoaded(msg)
+
+	case ExportDoneMsg:
+		if msg.Err != nil {
+			m.errorMsg = i18n.T("export: %v", msg.Err)
+			return m, nil
+		}
+		m.contextMsg = i18n.T("\033[32mExported\033[0m %s lines to %s", formatLineCount(msg.Lines), msg.Path)
+		return m, nil
+
+	case FileProcessesMsg:
+		m.contextMsg = m.lastContext
+		if msg.Err != nil {
+			m.errorMsg = msg.Err.Error()
+			return m, nil
+		}
+		m.procs = &msg
+		if m.modal == modalNone {
+			m.modal = modalProcs
+		}
+		return m, nil
+
+	case ServiceStatusMsg:
+		m.contextMsg = m.lastContext
+		if msg.Err != nil {
+			m.errorMsg = msg.Err.Error()
+			return m, nil
+		}
+		m.service = &msg
+		if m.modal == modalNone {
+			m.modal = modalService
+		}
+		return m, nil
+
+	case EnrichDoneMsg:
+		m.enrichInfos = msg.Infos
+		m.contextMsg = m.lastContext
+		if m.modal == modalNone {
+			m.modal = modalEnrich
+		}
+		return m, nil
+
+	case eventsTickMsg:
+		return m, fetchEventsCmd(m.cfg)
+
 	case spinnerTickMsg:
 		if m.viewerPane.IsSpinning() {
 			m.viewerPane.TickSpinner()
@@ -180,6 +464,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.spinnerTicking = false
 		return m, nil
 
+	case dimTickMsg:
+		m.viewerPane.RefreshDimming()
+		return m, dimTickCmd()
+
 	case ConnectedMsg:
 		// Not used directly — connectAndListCmd combines connect+list
 		return m, nil
@@ -188,17 +476,130 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		errDetail := fmt.Sprintf("connect %s: %v", msg.Server.Host, msg.Err)
 		m.filePane.SetMessage("Unable to connect\n\n" + errDetail)
 		m.focused = paneServer
+		m.dropChallenges(msg.Server)
+		return m, nil
+
+	case eagerConnectMsg:
+		return m.startEagerConnect()
+
+	case EagerConnectDoneMsg:
+		m.eagerDone++
+		if msg.Err != nil {
+			logger.Log("app", "eager connect to %s failed: %v", msg.Server.Name, msg.Err)
+		}
+		if m.eagerDone >= m.eagerTotal {
+			logger.Log("app", "eager connect finished (%d servers)", m.eagerTotal)
+			m.eagerDone, m.eagerTotal = 0, 0
+		}
+		m.serverPane.SetConnectProgress(m.eagerDone, m.eagerTotal)
+		return m, nil
+
+	case KillDoneMsg:
+		m.errorMsg = ""
+		m.setContext(i18n.T("\033[31mKilled\033[0m %d session(s) on %d connection(s) — servers reconnect when used", msg.Sessions, msg.Conns))
+		return m, nil
+
+	case ConnStateMsg:
+		m.serverPane.SetConnState(msg.Change.Key, msg.Change.State)
+		if msg.Change.Latency > 0 {
+			m.serverPane.SetLatency(msg.Change.Key, msg.Change.Latency)
+		}
+		return m, waitForConnState(m.connStateCh)
+
+	case AuthChallengeMsg:
+		next := waitForChallenge(m.challengeCh)
+		c := msg.Challenge
+		if len(c.Questions) == 0 {
+			// Informational only, e.g. "Duo push sent to your phone"
+			if text := strings.TrimSpace(c.Instruction); text != "" {
+				m.setContext(fmt.Sprintf("\033[33m%s:\033[0m %s", c.Server.Name, text))
+			}
+			return m, next
+		}
+		expiry := waitForChallengeExpiry(c)
+		if m.modal == modalChallenge {
+			m.challengeQueue = append(m.challengeQueue, c)
+			return m, tea.Batch(next, expiry)
+		}
+		m = m.showChallenge(c)
+		return m, tea.Batch(next, expiry)
+
+	case challengeExpiredMsg:
+		return m.expireChallenge(msg.reply), nil
+
+	case HostKeyPromptMsg:
+		m.filePane.SetMessage("Verify host key\n\n" + msg.Err.Error())
+		m.modal = modalHostKey
+		m.hostKeyErr = msg.Err
+		m.hostKeyServer = &msg.Server
+		return m, nil
+
+	case HostKeyAcceptedMsg:
+		if m.currentServer != nil && m.currentFolder != nil && ssh.ServerKey(*m.currentServer) == ssh.ServerKey(msg.Server) {
+			m.setContext(i18n.T("\033[33mConnecting to\033[0m %s...", msg.Server.Name))
+			return m, connectAndListCmd(m.pool, msg.Server, *m.currentFolder)
+		}
+		return m, nil
+
+	case KeyPassphraseMsg:
+		if m.modal == modalPassphrase {
+			// Parallel commands can hit the same locked key; prompt only once
+			return m, nil
+		}
+		if msg.Failed != "" {
+			m.errorMsg = msg.Failed
+		}
+		m = m.showPassphrasePrompt(msg.Server, msg.Err)
 		return m, nil
 
+	case KeyUnlockedMsg:
+		m.errorMsg = ""
+		if m.currentServer == nil || ssh.ServerKey(*m.currentServer) != ssh.ServerKey(msg.Server) {
+			return m, nil
+		}
+		if m.needsSudoCredentials(msg.Server) {
+			return m, m.startSudoProbe(msg.Server)
+		}
+		return m, m.startConnection(msg.Server)
+
 	case SudoRetryMsg:
-		m.errorMsg = "Sudo authentication failed — try again"
+		if m.modal == modalSudo {
+			// The parallel read and tail can both fail; prompt only once
+			return m, nil
+		}
+		if msg.FileOp && m.currentFile != nil {
+			file := *m.currentFile
+			m.sudoRetryFile = &file
+			m.stopTailInPlace()
+		}
+		m.errorMsg = i18n.T("Sudo authentication failed — try again")
 		m = m.showSudoPrompt(msg.Server)
-		return m, nil
+		key := ssh.ServerKey(msg.Server)
+		var cmds []tea.Cmd
+		save := false
+		if m.state.SudoNoPasswd(key) {
+			// sudo asks for a password after all
+			m.state.SetSudoNoPasswd(key, false)
+			save = true
+		}
+		if m.sudoInKeychain(msg.Server) {
+			m.sudoToStore = nil
+			m.state.SetSudoInKeychain(key, false)
+			cmds = append(cmds, keychainDeleteCmd(msg.Server))
+			save = true
+		}
+		if save {
+			cmds = append(cmds, saveStateCmd(m.state))
+		}
+		return m, tea.Batch(cmds...)
 
 	case FilesLoadedMsg:
+		if m.currentServer != nil {
+			m.rememberListing(*m.currentServer, msg.Dir, msg.Files)
+		}
 		// Preserve selected file across refresh
 		previousFile := m.currentFile
-		m.filePane.SetFiles(msg.Dir, msg.Files, msg.ShowUpDir)
+		m.filePane.SetFiles(msg.Dir, msg.Root, msg.Files, msg.ShowUpDir)
 		if previousFile != nil {
 			for i, f := range msg.Files {
 				if f.Name == previousFile.Name {
@@ -216,72 +617,249 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.errorMsg = ""
 		if m.currentFile != nil {
 			fullPath := filepath.Join(m.currentFolder.Path, m.currentFile.Name)
-			m.setContext(fmt.Sprintf("\033[38;2;3;175;255m%s\033[0m %s", m.currentServer.Name, fullPath))
+			m.setContext(fmt.Sprintf("\033[38;2;3;175;255m%s\033[0m %s", m.serverLabel(), fullPath))
 		} else {
-			m.setContext(fmt.Sprintf("\033[38;2;3;175;255m%s\033[0m — Select a file", m.currentServer.Name))
+			m.setContext(i18n.T("\033[38;2;3;175;255m%s\033[0m — Select a file", m.serverLabel()))
+		}
+		m = m.warnPartialListing(msg.Denied)
+		var cmds []tea.Cmd
+		if m.sudoToStore != nil && ssh.ServerKey(*m.sudoToStore) == ssh.ServerKey(*m.currentServer) {
+			cmds = append(cmds, m.storeSudoPassword(*m.sudoToStore))
+			m.sudoToStore = nil
+		}
+		if m.pool.HostInfo(*m.currentServer).Hostname == "" && (m.currentFolder == nil || !isLocalKube(*m.currentFolder)) {
+			cmds = append(cmds, fetchHostInfoCmd(m.pool, *m.currentServer))
 		}
 		// Fire auto-select callback if set
 		if m.onFilesLoaded != nil {
 			cb := m.onFilesLoaded
 			m.onFilesLoaded = nil
-			cmd := cb(&m)
-			return m, cmd
+			cmds = append(cmds, cb(&m))
+		}
+		return m, tea.Batch(cmds...)
+
+	case HostInfoMsg:
+		if m.currentServer == nil || ssh.ServerKey(*m.currentServer) != ssh.ServerKey(msg.Server) {
+			return m, nil
+		}
+		m = m.maybeShowBanner(msg.Server)
+		m.updateTerminalTitle()
+		if m.currentFolder != nil && m.currentFile == nil && !m.filePane.HasActiveFilter() {
+			m.setContext(i18n.T("\033[38;2;3;175;255m%s\033[0m — Select a file", m.serverLabel()))
 		}
 		return m, nil
 
 	case FilesErrorMsg:
 		errDetail := fmt.Sprintf("list files: %v", msg.Err)
+		m.listDenied = m.canRetryWithSudo() && strings.Contains(msg.Err.Error(), "Permission denied")
+		if m.listDenied {
+			errDetail += "\n\n" + i18n.T("Ctrl-S retries with sudo.")
+		}
 		m.filePane.SetMessage("Unable to list files\n\n" + errDetail)
 		m.focused = paneServer
 		return m, nil
 
+	case SudoProbeMsg:
+		if m.currentServer == nil || ssh.ServerKey(*m.currentServer) != ssh.ServerKey(msg.Server) {
+			return m, nil
+		}
+		if !msg.NoPasswd {
+			if msg.Server.PasswordCommand != "" {
+				return m, passwordCommandCmd(msg.Server)
+			}
+			if m.sudoInKeychain(msg.Server) {
+				return m, keychainLookupCmd(msg.Server)
+			}
+			m = m.showSudoPrompt(msg.Server)
+			return m, nil
+		}
+		logger.Log("app", "passwordless sudo on %s", msg.Server.Name)
+		m.pool.SetSudoNoPasswd(msg.Server)
+		m.state.SetSudoNoPasswd(ssh.ServerKey(msg.Server), true)
+		return m, tea.Batch(saveStateCmd(m.state), m.startConnection(msg.Server))
+
+	case CommandPasswordMsg:
+		if m.currentServer == nil || ssh.ServerKey(*m.currentServer) != ssh.ServerKey(msg.Server) {
+			return m, nil
+		}
+		if msg.Err != nil {
+			logger.Log("app", "password command for %s: %v", msg.Server.Name, msg.Err)
+			m.errorMsg = msg.Err.Error()
+			m = m.showSudoPrompt(msg.Server)
+			return m, nil
+		}
+		logger.Log("app", "using password command sudo password for %s", msg.Server.Name)
+		m.pool.SetSudoPassword(msg.Server, msg.Password)
+		return m, m.startConnection(msg.Server)
+
+	case KeychainPasswordMsg:
+		if m.currentServer == nil || ssh.ServerKey(*m.currentServer) != ssh.ServerKey(msg.Server) {
+			return m, nil
+		}
+		if msg.Password == "" {
+			m = m.showSudoPrompt(msg.Server)
+			return m, nil
+		}
+		logger.Log("app", "using keychain sudo password for %s", msg.Server.Name)
+		m.pool.SetSudoPassword(msg.Server, msg.Password)
+		return m, m.startConnection(msg.Server)
+
 	case FileContentMsg:
-		m.viewerPane.SetText(msg.Content, msg.StartLine)
+		m.session.received([]byte(msg.Content))
+		text := decodeLog([]byte(msg.Content), m.folderEncoding())
+		m.viewerPane.SetText(string(text), msg.StartLine)
+		if msg.Cursor > 0 {
+			m.viewerPane.CursorTo(msg.Cursor)
+		}
+		m.metrics.Reset()
+		m.feedMetrics(text)
+		m.resetPlugins()
+		m.feedPlugins(text)
+		m.placeEvents(m.events, false)
 		// Tailing is already started in parallel from onFileSelected
 		return m, nil
 
 	case FileReadErrorMsg:
-		m.errorMsg = fmt.Sprintf("read: %v", msg.Err)
+		m.errorMsg = i18n.T("read: %v", msg.Err)
 		return m, nil
 
 	case TailStartedMsg:
 		m.tailer = msg.Tailer
 		m.tailCancel = msg.Cancel
+		m.logDecoder = logDecoder{}
 		m.tailing = true
+		m.reconnectAttempt = 0
 		if m.currentServer != nil && m.currentFile != nil && m.currentFolder != nil {
 			fullPath := filepath.Join(m.currentFolder.Path, m.currentFile.Name)
-			m.setContext(fmt.Sprintf("\033[38;2;3;175;255mTailing\033[0m %s:%s", m.currentServer.Name, fullPath))
-			m.viewerPane.StartSpinner(fmt.Sprintf("Tailing: %s", m.currentFile.Name))
+			m.session.tailing(m.currentServer.Name, fullPath)
+			m.setContext(i18n.T("\033[38;2;3;175;255mTailing\033[0m %s:%s", m.currentServer.Name, fullPath))
+			m.viewerPane.StartSpinner(i18n.T("Tailing: %s", m.currentFile.Name))
 			var cmds []tea.Cmd
-			cmds = append(cmds, waitForTailData(m.tailChan))
+			cmds = append(cmds, waitForTailData(m.tailChan), waitForRotation(msg.Tailer))
 			if !m.spinnerTicking {
 				m.spinnerTicking = true
 				cmds = append(cmds, spinnerTickCmd())
 			}
 			return m, tea.Batch(cmds...)
 		}
-		return m, waitForTailData(m.tailChan)
+		if m.currentServer != nil && m.currentFolder != nil && m.multiFiles != nil {
+			for _, name := range m.multiFiles {
+				m.session.tailing(m.currentServer.Name, filepath.Join(m.currentFolder.Path, name))
+			}
+			m.setContext(i18n.T("\033[38;2;3;175;255mTailing\033[0m %s:%s: %s", m.currentServer.Name, m.currentFolder.Path, strings.Join(m.multiFiles, ", ")))
+			m.viewerPane.StartSpinner(i18n.T("Tailing: %s", m.multiLabel()))
+			cmds := []tea.Cmd{waitForTailData(m.tailChan)}
+			if !m.spinnerTicking {
+				m.spinnerTicking = true
+				cmds = append(cmds, spinnerTickCmd())
+			}
+			return m, tea.Batch(cmds...)
+		}
+		return m, tea.Batch(waitForTailData(m.tailChan), waitForRotation(msg.Tailer))
 
 	case TailDataMsg:
-		m.viewerPane.AppendTailData(msg.Data)
+		m.showLogData(msg.Data, m.folderEncoding())
 		return m, waitForTailData(m.tailChan)
 
+	case TailRotatedMsg:
+		if msg.Tailer != m.tailer {
+			return m, nil
+		}
+		m.fileRotated(msg.Rotation)
+		return m, waitForRotation(msg.Tailer)
+
+	case stdinDataMsg:
+		return m.onStdinData(msg)
+
+	case stdinClosedMsg:
+		return m.onStdinClosed()
+
+	case WarmTailMsg:
+		return m.onWarmTail(msg)
+
+	case searchHitsMsg:
+		return m.onSearchHits(msg)
+
+	case searchDoneMsg:
+		return m.onSearchDone(msg)
+
+	case fileActionDoneMsg:
+		return m.onFileActionDone(msg)
+
+	case patternListMsg:
+		return m.onPatternList(msg)
+
+	case traceDoneMsg:
+		return m.onTraceDone(msg)
+
 	case TailErrorMsg:
-		m.errorMsg = fmt.Sprintf("tail: %v", msg.Err)
+		if m.reconnectAttempt > 0 {
+			return m.scheduleReconnect(msg.Err)
+		}
+		m.errorMsg = i18n.T("tail: %v", msg.Err)
 		m.viewerPane.StopSpinner()
-		m.viewerPane.SetTitle(" Disconnected ")
+		m.viewerPane.SetTitle(i18n.T(" Disconnected "))
 		m.tailing = false
 		return m, nil
 
 	case TailStoppedMsg:
 		if m.tailing {
-			m.viewerPane.StopSpinner()
-			m.viewerPane.SetTitle(" Disconnected ")
-			m.errorMsg = "connection lost"
+			m.session.tailLosses++
+			var exit *ssh.TailExitError
+			stopped := m.tailer != nil && errors.As(m.tailer.Err(), &exit)
 			m.tailing = false
+			m.tailer = nil
+			m.tailCancel = nil
+			m.tailChan = nil
+			if stopped && exit.Reason != ssh.TailChannelClosed {
+				return m.tailExited(exit)
+			}
+			cause := fmt.Errorf("connection lost")
+			if m.currentServer != nil {
+				if lost := m.pool.LostReason(*m.currentServer); lost != nil {
+					cause = fmt.Errorf("connection lost: %w", lost)
+				}
+			}
+			return m.scheduleReconnect(cause)
 		}
 		return m, nil
 
+	case reconnectTickMsg:
+		if msg.gen != m.reconnectGen || m.reconnectAttempt == 0 || m.tailing {
+			return m, nil
+		}
+		if m.currentServer != nil && m.currentFolder != nil && m.multiFiles != nil {
+			ch := make(chan []byte, 64)
+			m.tailChan = ch
+			logger.Log("app", "reconnect attempt %d for %d files of %s", m.reconnectAttempt, len(m.multiFiles), m.currentFolder.Path)
+			return m, startMultiTailCmd(m.pool, *m.currentServer, m.currentFolder.Path, m.multiFiles, 0, ch)
+		}
+		if m.currentServer == nil || m.currentFolder == nil || m.currentFile == nil {
+			m.reconnectAttempt = 0
+			return m, nil
+		}
+		fullPath := filepath.Join(m.currentFolder.Path, m.currentFile.Name)
+		ch := make(chan []byte, 64)
+		m.tailChan = ch
+		logger.Log("app", "reconnect attempt %d for %s", m.reconnectAttempt, fullPath)
+		return m, m.followCmd(fullPath, ch)
+
+	case DownloadStatMsg:
+		if m.modal != modalDownload || m.downloadPhase != downloadPhaseChecking {
+			return m, nil
+		}
+		if msg.Err != nil {
+			m.downloadPhase = downloadPhaseError
+			m.downloadError = msg.Err.Error()
+			return m, nil
+		}
+		m.downloadTotalBytes = msg.Size
+		if limit := int64(m.cfg.Defaults.DownloadConfirmSize); limit > 0 && msg.Size > limit {
+			m.downloadPhase = downloadPhaseConfirm
+			return m, nil
+		}
+		return m.startDownload()
+
 	case DownloadProgressMsg:
 		if m.modal == modalDownload && m.downloadPhase == downloadPhaseProgress {
 			m.downloadBytesDownloaded = msg.BytesDownloaded
@@ -291,6 +869,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case DownloadDoneMsg:
+		m.session.downloads++
 		if m.modal == modalDownload && m.downloadPhase == downloadPhaseProgress {
 			m.downloadPhase = downloadPhaseDone
 			m.downloadLocalPath = msg.Path
@@ -303,7 +882,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.Size > 0 {
 			sizeStr = fmt.Sprintf(" (%s)", ssh.FormatSize(msg.Size))
 		}
-		m.setContext(fmt.Sprintf("\033[32mDownloaded\033[0m %s%s → %s", msg.Filename, sizeStr, msg.Path))
+		m.setContext(i18n.T("\033[32mDownloaded\033[0m %s%s → %s", msg.Filename, sizeStr, msg.Path))
 		return m, nil
 
 	case DownloadErrorMsg:
@@ -330,6 +909,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case autoFileSelectMsg:
 		return m.onFileSelected(msg.idx, msg.file)
+
+	case FoldersRefreshedMsg:
+		return m.onFoldersRefreshed(msg)
+
+	case newFilesFlashDoneMsg:
+		if msg.gen == m.flashGen {
+			m.filePane.SetNewFiles(nil)
+		}
+		return m, nil
 	}
 
 	return m, nil
@@ -360,9 +948,25 @@ func (m *Model) recalcSizes() {
 
 	m.serverPane.SetSize(serverWidth, paneHeight)
 	m.filePane.SetSize(fileWidth, paneHeight)
-	m.viewerPane.SetSize(viewerWidth, paneHeight)
+	viewerHeight := paneHeight - m.stackedHeight()
+	if m.chartShown() {
+		m.viewerPane.SetSize(viewerWidth, viewerHeight-chartPaneHeight)
+		m.chartPane.SetSize(viewerWidth, chartPaneHeight)
+	} else {
+		m.viewerPane.SetSize(viewerWidth, viewerHeight)
+	}
+}
+
+// chartShown reports whether the metric chart is on and there is room for
+// it below the viewer.
+func (m Model) chartShown() bool {
+	return m.chartPane.Visible() && m.height-1 >= chartPaneHeight+minViewerHeight
 }
 
+// minViewerHeight is the smallest viewer, borders included, that the chart
+// may leave.
+const minViewerHeight = 8
+
 func (m *Model) setContext(msg string) {
 	m.lastContext = msg
 	m.contextMsg = msg
@@ -378,14 +982,33 @@ func (m Model) View() string {
 	// Render three panes
 	serverView := m.serverPane.View(m.focused == paneServer)
 	fileView := m.filePane.View(m.focused == paneFile)
-	viewerView := m.viewerPane.View(m.focused == paneViewer)
+	viewerView := m.viewerColumn(m.viewerPane.View(m.focused == paneViewer))
 
 	// Join panes horizontally
 	panes := lipgloss.JoinHorizontal(lipgloss.Top, serverView, fileView, viewerView)
 
 	// Status bar
 	shortcuts := m.currentShortcuts()
-	statusBar := renderStatusBar(m.width, m.contextMsg, m.errorMsg, shortcuts)
+	contextMsg := m.contextMsg
+	if tmpl := m.cfg.Defaults.StatusTemplate; tmpl != "" {
+		contextMsg = expandStatus(tmpl, m.statusFields())
+	} else {
+		if note := m.currentNote(); note != "" {
+			contextMsg = i18n.T("\033[35mNote:\033[0m %s | %s", note, contextMsg)
+		}
+		if stats := m.viewerPane.FilterStats(); stats != "" && m.currentFile != nil {
+			contextMsg = fmt.Sprintf("\033[33m%s\033[0m | %s", stats, contextMsg)
+		}
+		if open := m.pool.Stats().Open; open > 0 {
+			contextMsg = fmt.Sprintf("\033[36m⇄ %d\033[0m | %s", open, contextMsg)
+		}
+		if m.currentServer != nil {
+			if rtt := m.pool.Latency(*m.currentServer); rtt > 0 {
+				contextMsg = fmt.Sprintf("\033[36m%s\033[0m | %s", formatLatency(rtt), contextMsg)
+			}
+		}
+	}
+	statusBar := renderStatusBar(m.width, contextMsg, m.errorMsg, shortcuts)
 
 	// Join vertically
 	result := lipgloss.JoinVertical(lipgloss.Left, panes, statusBar)
@@ -398,38 +1021,102 @@ func (m Model) View() string {
 	return result
 }
 
+// statusFields returns the values of the status_template placeholders;
+// those that don't apply are "".
+func (m Model) statusFields() map[string]string {
+	f := map[string]string{"context": m.contextMsg}
+	if note := m.currentNote(); note != "" {
+		f["note"] = "\033[35mNote:\033[0m " + note
+	}
+	if open := m.pool.Stats().Open; open > 0 {
+		f["conns"] = fmt.Sprintf("\033[36m⇄ %d\033[0m", open)
+	}
+	if m.currentServer != nil {
+		f["server"] = "\033[38;2;3;175;255m" + m.currentServer.Name + "\033[0m"
+		f["state"] = m.serverPane.ConnState(*m.currentServer).String()
+		if rtt := m.pool.Latency(*m.currentServer); rtt > 0 {
+			f["latency"] = formatLatency(rtt)
+		}
+	}
+	if m.currentFolder != nil {
+		f["folder"] = m.currentFolder.Path
+	}
+	if m.currentFile != nil {
+		f["file"] = m.currentFile.Name
+		f["lines"] = formatLineCount(m.viewerPane.FileLines()) + " lines"
+		if n := m.viewerPane.Spilled(); n > 0 {
+			f["lines"] += fmt.Sprintf(", %s on disk", formatLineCount(n))
+		}
+		if filter := m.viewerPane.GetTailFilter(); filter != "" {
+			f["filter"] = "\033[33mfilter:\033[0m " + filter
+			f["matches"] = "\033[33m" + m.viewerPane.FilterStats() + "\033[0m"
+		}
+		if m.tailing {
+			f["rate"] = fmt.Sprintf("%.1f lines/s", m.viewerPane.Rate())
+		}
+	}
+	return f
+}
+
 func (m *Model) currentShortcuts() string {
 	switch m.focused {
 	case paneServer:
-		return shortcutsListPane
+		if m.cfg.Catalog.Source != "" {
+			return i18n.T(shortcutsCatalogPane)
+		}
+		return i18n.T(shortcutsListPane)
 	case paneFile:
 		if m.filePane.IsInFolderMode() {
-			return shortcutsFolderPane
+			return i18n.T(shortcutsFolderPane)
 		}
-		return shortcutsFilePane
+		return i18n.T(shortcutsFilePane)
 	case paneViewer:
-		return shortcutsViewerPane
+		return i18n.T(shortcutsViewerPane)
+	}
+	if m.focusedPlugin() != nil {
+		return i18n.T(shortcutsPluginPane)
 	}
-	return shortcutsListPane
+	return i18n.T(shortcutsListPane)
 }
 
 // handleKey processes keyboard events.
 func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// The kill switch works everywhere, popups included
+	if msg.String() == "ctrl+x" {
+		return m.killAll()
+	}
+
 	// Modal input handling
+	if m.modal == modalHelp && msg.String() == "f1" {
+		m.modal = modalNone
+		return m, nil
+	}
 	if m.modal != modalNone {
 		return m.handleModalKey(msg)
 	}
 
+	// A focused plugin pane sees the keys first
+	if p := m.focusedPlugin(); p != nil {
+		switch k := msg.String(); {
+		case k == "tab" || k == "shift+tab" || k == "ctrl+c":
+		case k == "esc" && p.overlay:
+			m.focused = paneViewer
+			return m, nil
+		case p.HandleKey(k):
+			return m, nil
+		}
+	}
+
 	switch msg.String() {
 	case "ctrl+c":
 		return m, tea.Quit
 
 	case "tab":
-		m.focused = pane((int(m.focused) + 1) % 3)
+		m.focused = pane((int(m.focused) + 1) % m.paneCount())
 		return m, nil
 
 	case "shift+tab":
-		m.focused = pane((int(m.focused) + 2) % 3)
+		m.focused = pane((int(m.focused) + m.paneCount() - 1) % m.paneCount())
 		return m, nil
 
 	case "esc":
@@ -446,10 +1133,62 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				m.contextMsg = m.lastContext
 				return m, nil
 			}
+			if m.filePane.ClearMarks() {
+				m.contextMsg = m.lastContext
+				return m, nil
+			}
 		}
 		// Stop tail
 		return m.stopTail(), nil
 
+	case "f1":
+		m.modal = modalHelp
+		return m, nil
+
+	case "ctrl+s":
+		if m.listDenied {
+			return m.retryListingWithSudo()
+		}
+		return m, nil
+
+	case "f2":
+		return m.showHostInfo(), nil
+
+	case "f3":
+		return m.showSearchPrompt(), nil
+
+	case "f4":
+		return m.showNotePrompt(), nil
+
+	case "ctrl+w":
+		return m.lookupFileProcesses()
+
+	case "ctrl+u":
+		return m.showServiceStatus(false)
+
+	case "ctrl+t":
+		return m.showConnectionStats()
+
+	case "ctrl+g":
+		return m.toggleTailGroup()
+
+	case "ctrl+f":
+		return m.showTracePrompt(), nil
+
+	case "ctrl+p":
+		return m.showPatternsPrompt()
+
+	case "ctrl+d":
+		return m.showFileActions(), nil
+
+	case "f9":
+		if m.cfg.Catalog.Source == "" {
+			m.errorMsg = i18n.T("no shared catalog configured")
+			return m, nil
+		}
+		m.setContext(i18n.T("\033[33mRefreshing catalog...\033[0m"))
+		return m, loadCatalogCmd(m.cfg, false)
+
 	case "f5":
 		if m.focused == paneFile {
 			return m.showDownloadDialog()
@@ -459,12 +1198,18 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "f6":
 		return m.refreshFiles()
 
+	case "f18": // Shift-F6
+		return m.refreshAllFolders()
+
 	case "f7":
 		return m.showFilterPrompt(), nil
 
 	case "f8":
 		return m.resumeTail()
 
+	case "ctrl+r":
+		return m.restartTail()
+
 	case "enter":
 		return m.handleEnter()
 
@@ -474,6 +1219,28 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "down":
 		return m.handleDown(), nil
 
+	case "left":
+		if m.focused == paneFile {
+			m.filePane.MoveLeft()
+		}
+		return m, nil
+
+	case "right":
+		if m.focused == paneFile {
+			m.filePane.MoveRight()
+		}
+		return m, nil
+
+	case "alt+t":
+		if m.focused == paneFile {
+			if m.filePane.ToggleColumns() {
+				m.setContext(i18n.T("File list in columns (Alt-T for details)"))
+			} else {
+				m.setContext(i18n.T("File list with details"))
+			}
+		}
+		return m, nil
+
 	case "home":
 		if m.focused == paneViewer {
 			m.viewerPane.GotoTop()
@@ -539,6 +1306,8 @@ func (m Model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 				clickedPane = paneServer
 			} else if msg.X < m.serverPaneWidth+m.filePaneWidth {
 				clickedPane = paneFile
+			} else if p, ok := m.pluginAt(msg.Y); ok {
+				clickedPane = p
 			} else {
 				clickedPane = paneViewer
 			}
@@ -563,10 +1332,17 @@ func (m Model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 					return m.handleEnter()
 				}
 			case paneFile:
-				m.filePane.SetCursorFromY(msg.Y)
-				if isDoubleClick {
+				prev := m.filePane.cursor
+				m.filePane.SetCursorFromXY(msg.X-m.serverPaneWidth, msg.Y)
+				// Side by side in the multi-column layout: a click on
+				// another name on the same row isn't a double-click
+				if isDoubleClick && m.filePane.cursor == prev {
 					return m.handleEnter()
 				}
+			case paneViewer:
+				if msg.Y < m.viewerPane.height {
+					m.viewerPane.SetCursorFromY(msg.Y)
+				}
 			}
 		}
 
@@ -597,14 +1373,23 @@ func (m Model) handleRune(r rune) (tea.Model, tea.Cmd) {
 	case paneServer:
 		m.serverPane.HandleRune(r)
 		if m.serverPane.HasActiveFilter() {
-			m.contextMsg = fmt.Sprintf("\033[33mFilter:\033[0m %s", m.serverPane.FilterQuery())
+			m.contextMsg = i18n.T("\033[33mFilter:\033[0m %s", m.serverPane.FilterQuery())
 		}
 		return m, nil
 
 	case paneFile:
+		if r == ' ' && !m.filePane.IsInFolderMode() {
+			return m.toggleMark(), nil
+		}
 		m.filePane.HandleRune(r)
 		if m.filePane.HasActiveFilter() {
-			m.contextMsg = fmt.Sprintf("\033[33mFilter:\033[0m %s", m.filePane.FilterQuery())
+			m.contextMsg = i18n.T("\033[33mFilter:\033[0m %s", m.filePane.FilterQuery())
+		}
+		if m.cfg.Defaults.OpenSingleMatch {
+			// Narrowed down to one file: open it, unless it already is
+			if idx, file, ok := m.filePane.SingleMatch(); ok && !file.IsDir && (m.currentFile == nil || m.currentFile.Name != file.Name) {
+				return m.onFileSelected(idx, *file)
+			}
 		}
 		return m, nil
 
@@ -616,11 +1401,56 @@ func (m Model) handleRune(r rune) (tea.Model, tea.Cmd) {
 			m.viewerPane.GotoBottom()
 		case 'w':
 			m.viewerPane.ToggleWrap()
-		}
-	}
+		case 'a':
+			m.viewerPane.ToggleAlign()
+		case 'y':
+			return m.copyCursorLine(), nil
+		case 'e':
+			return m.exportViewer()
+		case 's':
+			return m.showMetrics(), nil
+		case 'c':
+			return m.cycleChart(), nil
+		case 'i':
+			return m.lookupCursorIPs()
+		case 'm':
+			if m.currentFile == nil && m.multiFiles == nil {
+				m.errorMsg = i18n.T("open a file to add a marker")
+				return m, nil
+			}
+			label := m.viewerPane.AddMarker(time.Now())
+			m.contextMsg = i18n.T("\033[35mAdded %s\033[0m — M: jump to last marker", label)
+		case 'M':
+			if label, ok := m.viewerPane.JumpToMarker(); ok {
+				m.contextMsg = i18n.T("\033[35mAt %s\033[0m — M: previous marker, G: bottom", label)
+			} else {
+				m.errorMsg = i18n.T("no markers yet (press m to add one)")
+			}
+		case 'r':
+			m.viewerPane.ToggleRaw()
+			if m.viewerPane.IsRawMode() {
+				m.contextMsg = i18n.T("\033[33mRaw mode\033[0m — showing bytes as received, r to return")
+			} else {
+				m.contextMsg = m.lastContext
+			}
+		}
+	}
 	return m, nil
 }
 
+// copyCursorLine copies the full original text of the line under the viewer
+// cursor, regardless of wrapping or colorization.
+func (m Model) copyCursorLine() Model {
+	text, num, ok := m.viewerPane.CursorLine()
+	if !ok {
+		m.errorMsg = i18n.T("click a line to select it first")
+		return m
+	}
+	copyToClipboard(text)
+	m.contextMsg = i18n.T("\033[32mCopied\033[0m line %d (%d chars)", num, len(text))
+	return m
+}
+
 func (m Model) handleBackspace() Model {
 	switch m.focused {
 	case paneServer:
@@ -628,14 +1458,14 @@ func (m Model) handleBackspace() Model {
 		if !m.serverPane.HasActiveFilter() {
 			m.contextMsg = m.lastContext
 		} else {
-			m.contextMsg = fmt.Sprintf("\033[33mFilter:\033[0m %s", m.serverPane.FilterQuery())
+			m.contextMsg = i18n.T("\033[33mFilter:\033[0m %s", m.serverPane.FilterQuery())
 		}
 	case paneFile:
 		m.filePane.HandleBackspace()
 		if !m.filePane.HasActiveFilter() {
 			m.contextMsg = m.lastContext
 		} else {
-			m.contextMsg = fmt.Sprintf("\033[33mFilter:\033[0m %s", m.filePane.FilterQuery())
+			m.contextMsg = i18n.T("\033[33mFilter:\033[0m %s", m.filePane.FilterQuery())
 		}
 	}
 	return m
@@ -648,7 +1478,11 @@ func (m Model) handleUp() Model {
 	case paneFile:
 		m.filePane.MoveUp()
 	case paneViewer:
-		m.viewerPane.ScrollUp(1)
+		if m.search != nil {
+			m.viewerPane.MoveCursor(-1)
+		} else {
+			m.viewerPane.ScrollUp(1)
+		}
 	}
 	return m
 }
@@ -660,7 +1494,11 @@ func (m Model) handleDown() Model {
 	case paneFile:
 		m.filePane.MoveDown()
 	case paneViewer:
-		m.viewerPane.ScrollDown(1)
+		if m.search != nil {
+			m.viewerPane.MoveCursor(1)
+		} else {
+			m.viewerPane.ScrollDown(1)
+		}
 	}
 	return m
 }
@@ -681,9 +1519,20 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 		if folder != nil {
 			return m.onFolderSelected(folderIdx, *folder)
 		}
+		if file != nil && file.IsDir {
+			return m.onSubdirSelected(*file)
+		}
+		if len(m.filePane.MarkedFiles()) > 0 {
+			return m.tailMarked()
+		}
 		if file != nil {
 			return m.onFileSelected(fileOrigIdx, *file)
 		}
+
+	case paneViewer:
+		if m.search != nil {
+			return m.openSearchHit()
+		}
 	}
 	return m, nil
 }
@@ -695,17 +1544,23 @@ func (m Model) onServerSelected(idx int, srv config.ServerConfig) (tea.Model, te
 	m.currentServer = &srv
 	m.currentFolder = nil
 	m.currentFile = nil
+	m.multiFiles = nil
 	m.viewerPane.Clear()
 	m.filePane.Clear()
 	m.serverPane.MarkSelected(idx)
-	setTerminalTitle(fmt.Sprintf("Log Monitor — %s", srv.Name))
+	m.updateTerminalTitle()
 
 	folders := srv.LogFolders
 
 	if len(folders) > 1 {
+		// The default folder opens straight away; ".." lists the others
+		if i := srv.FolderIndex(srv.DefaultFolder); srv.DefaultFolder != "" && i >= 0 {
+			m.focused = paneFile
+			return m.onFolderSelected(i, folders[i])
+		}
 		m.filePane.SetFolders(folders)
 		m.focused = paneFile
-		m.setContext(fmt.Sprintf("\033[38;2;3;175;255m%s\033[0m — select a folder", srv.Name))
+		m.setContext(i18n.T("\033[38;2;3;175;255m%s\033[0m — select a folder", srv.Name))
 		return m, nil
 	}
 
@@ -713,9 +1568,8 @@ func (m Model) onServerSelected(idx int, srv config.ServerConfig) (tea.Model, te
 	folder := folders[0]
 	m.currentFolder = &folder
 
-	if srv.Sudo && m.pool.GetSudoPassword(srv) == "" {
-		m = m.showSudoPrompt(srv)
-		return m, nil
+	if m.needsSudoCredentials(srv) {
+		return m, m.startSudoProbe(srv)
 	}
 
 	cmd := m.startConnection(srv)
@@ -731,14 +1585,14 @@ func (m Model) onFolderSelected(idx int, folder config.LogFolder) (tea.Model, te
 	}
 	m.currentFolder = &folder
 	m.currentFile = nil
+	m.multiFiles = nil
 	m.filePane.selectedFolderIdx = idx
 	m.viewerPane.Clear()
 
 	srv := *m.currentServer
 
-	if srv.Sudo && m.pool.GetSudoPassword(srv) == "" {
-		m = m.showSudoPrompt(srv)
-		return m, nil
+	if m.needsSudoCredentials(srv) {
+		return m, m.startSudoProbe(srv)
 	}
 
 	cmd := m.startConnection(srv)
@@ -750,17 +1604,42 @@ func (m Model) onFileSelected(idx int, file ssh.FileInfo) (tea.Model, tea.Cmd) {
 	if m.currentServer == nil || m.currentFolder == nil {
 		return m, nil
 	}
+	if m.tailing && m.currentFile != nil && m.currentFile.Name == file.Name {
+		// Already tailing it: a second session would only duplicate the
+		// stream, so switch to the existing view instead.
+		m.focused = paneViewer
+		m.setContext(i18n.T("\033[33mAlready tailing\033[0m %s — \033[90mshowing the existing view, Ctrl-R to restart\033[0m", file.Name))
+		return m, nil
+	}
 	m.stopTailInPlace()
 	m.currentFile = &file
+	m.multiFiles = nil
+	m.atLine = 0
 	srv := *m.currentServer
 	folderPath := m.currentFolder.Path
 	fullPath := filepath.Join(folderPath, file.Name)
 
+	var saveCmd tea.Cmd
+	if serverKey := ssh.ServerKey(srv); m.state.LastFile(serverKey, folderPath) != file.Name {
+		m.state.SetLastFile(serverKey, folderPath, file.Name)
+		saveCmd = saveStateCmd(m.state)
+	}
+
 	m.filePane.MarkSelected(idx)
 	m.setContext(fmt.Sprintf("\033[32m%s\033[0m %s", srv.Name, fullPath))
-	setTerminalTitle(fmt.Sprintf("Log Monitor — %s:%s", srv.Name, fullPath))
+	m.updateTerminalTitle()
 	m.viewerPane.Clear()
 
+	if m.currentFolder.IsKubectl() {
+		// kubectl logs prints the pod's last lines before following it
+		ch := make(chan []byte, 64)
+		m.tailChan = ch
+		return m, tea.Batch(
+			startPodLogsCmd(m.pool, srv, *m.currentFolder, file.Name, m.cfg.Defaults.TailLines, ch),
+			saveCmd,
+		)
+	}
+
 	if isBinaryExtension(file.Name) {
 		icon := lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Bold(true).Render("⚠")
 		title := lipgloss.NewStyle().Bold(true).Render("Binary File")
@@ -777,7 +1656,21 @@ func (m Model) onFileSelected(idx int, file ssh.FileInfo) (tea.Model, tea.Cmd) {
 			Render(content)
 
 		m.viewerPane.SetCenteredMessage(box)
-		return m, nil
+		return m, saveCmd
+	}
+
+	if ssh.Decompressor(file.Name) != "" {
+		// A rotated archive doesn't grow: decompress it on the server and
+		// show its last lines, without a tail.
+		m.setContext(i18n.T("\033[32m%s\033[0m %s — \033[90mcompressed, shown without following\033[0m", srv.Name, fullPath))
+		return m, tea.Batch(
+			countAndReadFileCmd(m.pool, srv, fullPath, m.cfg.Defaults.TailLines),
+			saveCmd,
+		)
+	}
+
+	if cmd, ok := m.adoptWarmTail(srv, fullPath); ok {
+		return m, tea.Batch(cmd, saveCmd)
 	}
 
 	// Start initial read and tail in parallel to avoid sequential sudo delays
@@ -786,6 +1679,7 @@ func (m Model) onFileSelected(idx int, file ssh.FileInfo) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(
 		countAndReadFileCmd(m.pool, srv, fullPath, m.cfg.Defaults.TailLines),
 		startTailCmd(m.pool, srv, fullPath, ch),
+		saveCmd,
 	)
 }
 
@@ -796,42 +1690,139 @@ var binaryExtensions = map[string]bool{
 	".tgz": true, ".tbz2": true, ".txz": true,
 }
 
+// isBinaryExtension reports whether name is an archive that can't be shown.
+// Compressed single logs (.gz, .bz2, .xz) are shown decompressed instead.
 func isBinaryExtension(name string) bool {
 	ext := strings.ToLower(filepath.Ext(name))
-	return binaryExtensions[ext]
+	return binaryExtensions[ext] && ssh.Decompressor(name) == ""
 }
 
-// onUpDir returns to folder view.
+// onSubdirSelected lists a subdirectory of the current folder in its place.
+// The folder's settings carry over to it.
+func (m Model) onSubdirSelected(dir ssh.FileInfo) (tea.Model, tea.Cmd) {
+	if m.currentServer == nil || m.currentFolder == nil {
+		return m, nil
+	}
+	sub := *m.currentFolder
+	if sub.Root == "" {
+		sub.Root = sub.Path
+	}
+	sub.Path = filepath.Join(sub.Path, dir.Name)
+	return m.browseTo(sub, "")
+}
+
+// browseTo lists folder, a subdirectory of a configured folder or the
+// folder itself, and puts the cursor on the entry named focus.
+func (m Model) browseTo(folder config.LogFolder, focus string) (tea.Model, tea.Cmd) {
+	logger.Log("app", "browsing %s", folder.Path)
+	m.stopTailInPlace()
+	m.currentFolder = &folder
+	m.currentFile = nil
+	m.multiFiles = nil
+	m.viewerPane.Clear()
+	m.updateTerminalTitle()
+	if focus != "" {
+		m.onFilesLoaded = func(model *Model) tea.Cmd {
+			for i, f := range model.filePane.GetFiles() {
+				if f.Name == focus {
+					model.filePane.SetFileCursor(i)
+					break
+				}
+			}
+			return nil
+		}
+	}
+	srv := *m.currentServer
+	if m.needsSudoCredentials(srv) {
+		return m, m.startSudoProbe(srv)
+	}
+	return m, m.startConnection(srv)
+}
+
+// onUpDir goes up to the parent of a subdirectory being browsed, otherwise
+// returns to folder view.
 func (m Model) onUpDir() (tea.Model, tea.Cmd) {
+	if m.currentServer != nil && m.currentFolder != nil && m.currentFolder.Root != "" {
+		parent := *m.currentFolder
+		parent.Path = filepath.Dir(parent.Path)
+		if parent.Path == filepath.Clean(parent.Root) {
+			parent.Path, parent.Root = parent.Root, ""
+		}
+		return m.browseTo(parent, filepath.Base(m.currentFolder.Path))
+	}
 	m.stopTailInPlace()
 	m.currentFolder = nil
 	m.currentFile = nil
+	m.multiFiles = nil
 	m.viewerPane.Clear()
 
 	if m.currentServer != nil {
 		m.filePane.SetFolders(m.currentServer.LogFolders)
-		m.setContext(fmt.Sprintf("\033[38;2;3;175;255m%s\033[0m — select a folder", m.currentServer.Name))
+		m.setContext(i18n.T("\033[38;2;3;175;255m%s\033[0m — select a folder", m.currentServer.Name))
 	}
 	return m, nil
 }
 
+// showLogData appends log data that arrived in encoding to the viewer and
+// feeds it to the metrics and plugins.
+func (m *Model) showLogData(raw []byte, encoding string) {
+	m.session.received(raw)
+	data := m.logDecoder.decode(raw, encoding)
+	m.viewerPane.AppendTailData(data)
+	m.feedMetrics(data)
+	m.feedPlugins(data)
+}
+
+// folderEncoding returns the configured text encoding of the current folder.
+func (m *Model) folderEncoding() string {
+	if m.currentFolder == nil {
+		return ""
+	}
+	return m.currentFolder.Encoding
+}
+
+// needsSudoCredentials reports whether sudo must be resolved (probed or
+// prompted for) before running commands on srv.
+func (m *Model) needsSudoCredentials(srv config.ServerConfig) bool {
+	return srv.Sudo && m.pool.GetSudoPassword(srv) == "" && !m.pool.IsSudoNoPasswd(srv)
+}
+
+// startSudoProbe checks for NOPASSWD sudo before falling back to the password prompt.
+func (m *Model) startSudoProbe(srv config.ServerConfig) tea.Cmd {
+	m.focused = paneFile
+	m.setContext(i18n.T("\033[33mChecking sudo on\033[0m %s...", srv.Name))
+	return probeSudoCmd(m.pool, srv)
+}
+
 func (m *Model) startConnection(srv config.ServerConfig) tea.Cmd {
 	folder := m.currentFolder
 	if folder == nil {
 		return nil
 	}
 	m.focused = paneFile
-	m.setContext(fmt.Sprintf("\033[33mConnecting to\033[0m %s...", srv.Name))
+	m.setContext(i18n.T("\033[33mConnecting to\033[0m %s...", srv.Name))
+	if m.onFilesLoaded == nil {
+		m.onFilesLoaded = enterFolder
+	}
 	return connectAndListCmd(m.pool, srv, *folder)
 }
 
 func (m Model) stopTail() Model {
+	wasStdin := m.stdinView
 	m.stopTailInPlace()
 	m.viewerPane.StopSpinner()
+	if wasStdin {
+		m.viewerPane.SetTitle(i18n.T(" Stopped: stdin "))
+		m.setContext(i18n.T("\033[33mstdin paused\033[0m — \033[90mstill read in the background, F8 to show it again\033[0m"))
+		return m
+	}
 	if m.currentServer != nil && m.currentFile != nil && m.currentFolder != nil {
 		fullPath := filepath.Join(m.currentFolder.Path, m.currentFile.Name)
-		m.viewerPane.SetTitle(fmt.Sprintf(" Stopped: %s ", m.currentFile.Name))
-		m.setContext(fmt.Sprintf("\033[33mTail stopped\033[0m %s:%s — \033[90mF8 to resume\033[0m", m.currentServer.Name, fullPath))
+		m.viewerPane.SetTitle(i18n.T(" Stopped: %s ", m.currentFile.Name))
+		m.setContext(i18n.T("\033[33mTail stopped\033[0m %s:%s — \033[90mF8 to resume\033[0m", m.currentServer.Name, fullPath))
+	} else if m.currentServer != nil && m.multiFiles != nil {
+		m.viewerPane.SetTitle(i18n.T(" Stopped: %s ", m.multiLabel()))
+		m.setContext(i18n.T("\033[33mTail stopped\033[0m %s:%s — \033[90mF8 to resume\033[0m", m.currentServer.Name, m.currentFolder.Path))
 	} else {
 		m.viewerPane.ResetTitle()
 	}
@@ -839,6 +1830,10 @@ func (m Model) stopTail() Model {
 }
 
 func (m *Model) stopTailInPlace() {
+	m.cancelReconnect()
+	m.stopSearch()
+	m.stopTrace()
+	m.stdinView = false
 	if m.tailCancel != nil {
 		m.tailCancel()
 		m.tailer = nil
@@ -848,26 +1843,154 @@ func (m *Model) stopTailInPlace() {
 	}
 }
 
+// killAll stops the tail without reconnecting and closes every remote
+// session and connection, for when a command runs away.
+func (m Model) killAll() (tea.Model, tea.Cmd) {
+	m.stopTailInPlace()
+	m.warm.stopAll()
+	m.warmPending = 0
+	m.viewerPane.StopSpinner()
+	m.viewerPane.SetTitle(i18n.T(" Killed "))
+	m.setContext(i18n.T("\033[31mKilling all remote sessions…\033[0m"))
+	return m, killAllCmd(m.pool)
+}
+
 func (m Model) refreshFiles() (tea.Model, tea.Cmd) {
 	if m.currentServer == nil || m.currentFolder == nil {
 		return m, nil
 	}
-	m.setContext(fmt.Sprintf("\033[33mRefreshing\033[0m %s...", m.currentServer.Name))
+	m.setContext(i18n.T("\033[33mRefreshing\033[0m %s...", m.currentServer.Name))
 	return m, connectAndListCmd(m.pool, *m.currentServer, *m.currentFolder)
 }
 
+// scheduleReconnect arranges the next attempt to resume a tail that lost its
+// connection, backing off exponentially. Esc (stop tail) cancels it.
+func (m Model) scheduleReconnect(cause error) (tea.Model, tea.Cmd) {
+	if m.currentServer == nil || m.currentFolder == nil || (m.currentFile == nil && m.multiFiles == nil) {
+		return m, nil
+	}
+	m.reconnectAttempt++
+	delay := time.Second << min(m.reconnectAttempt-1, 5)
+	if delay > maxReconnectDelay {
+		delay = maxReconnectDelay
+	}
+	m.viewerPane.StopSpinner()
+	m.viewerPane.SetTitle(i18n.T(" Reconnecting (attempt %d)… ", m.reconnectAttempt))
+	m.errorMsg = i18n.T("%v — reconnecting in %s (Esc to cancel)", cause, delay)
+	gen := m.reconnectGen
+	return m, tea.Tick(delay, func(time.Time) tea.Msg {
+		return reconnectTickMsg{gen: gen}
+	})
+}
+
+// tailExited explains why the remote tail ended on its own. Reconnecting
+// wouldn't help with any of these, so it is left to the user.
+func (m Model) tailExited(exit *ssh.TailExitError) (tea.Model, tea.Cmd) {
+	m.reconnectAttempt = 0
+	m.viewerPane.StopSpinner()
+	var title, advice string
+	switch exit.Reason {
+	case ssh.TailFileGone:
+		title = i18n.T("File gone")
+		advice = i18n.T("the file was deleted or moved away — F6 refreshes the file list")
+	case ssh.TailPermissionLost:
+		title = i18n.T("Permission lost")
+		advice = i18n.T("the file can no longer be read — check its owner and mode, or turn on sudo for the server")
+	case ssh.TailKilled:
+		title = i18n.T("Tail killed")
+		if exit.Signal == "KILL" {
+			advice = i18n.T("tail was killed on the server (OOM killer, or an admin?) — F8 resumes")
+		} else {
+			advice = i18n.T("tail was stopped by SIG%s on the server — F8 resumes", exit.Signal)
+		}
+	default:
+		title = i18n.T("Tail exited")
+		advice = i18n.T("tail ended on the server — F8 resumes")
+	}
+	logger.Log("app", "tail exited: %v", exit)
+	m.viewerPane.SetTitle(" " + title + " ")
+	m.viewerPane.AddNotice(fmt.Sprintf("%s: %v", title, exit))
+	m.errorMsg = advice
+	return m, nil
+}
+
+// fileRotated marks where the tailed file rotated in the viewer. The lines
+// after the mark are numbered from the start of the new file.
+func (m *Model) fileRotated(r ssh.TailRotation) {
+	if m.currentFile == nil {
+		return
+	}
+	name := m.currentFile.Name
+	at := time.Now().Format("15:04:05")
+	var text string
+	switch r {
+	case ssh.RotationReplaced:
+		text = i18n.T("%s rotated, new file from here · %s", name, at)
+	case ssh.RotationTruncated:
+		text = i18n.T("%s truncated, new content from here · %s", name, at)
+	case ssh.RotationGone:
+		text = i18n.T("%s gone, waiting for it to come back · %s", name, at)
+	case ssh.RotationAppeared:
+		text = i18n.T("%s is back, new file from here · %s", name, at)
+	}
+	logger.Log("app", "tail: %s", text)
+	m.viewerPane.AddNotice(text)
+	if r != ssh.RotationGone {
+		m.viewerPane.RestartNumbering()
+	}
+}
+
+// cancelReconnect abandons any pending tail reconnect.
+func (m *Model) cancelReconnect() {
+	if m.reconnectAttempt > 0 {
+		m.reconnectAttempt = 0
+		m.reconnectGen++
+	}
+}
+
 func (m Model) resumeTail() (tea.Model, tea.Cmd) {
+	if m.stdin != nil && !m.stdinView && m.currentFile == nil && m.multiFiles == nil && m.search == nil {
+		return m, m.showStdin()
+	}
+	if !m.tailing && m.currentServer != nil && m.currentFolder != nil && m.multiFiles != nil {
+		ch := make(chan []byte, 64)
+		m.tailChan = ch
+		m.setContext(i18n.T("\033[32mResuming tail\033[0m %s:%s: %s", m.currentServer.Name, m.currentFolder.Path, strings.Join(m.multiFiles, ", ")))
+		return m, startMultiTailCmd(m.pool, *m.currentServer, m.currentFolder.Path, m.multiFiles, 0, ch)
+	}
 	if m.tailing || m.currentServer == nil || m.currentFolder == nil || m.currentFile == nil {
 		return m, nil
 	}
-	if isBinaryExtension(m.currentFile.Name) {
+	if m.atLine > 0 {
+		// The viewer shows lines around a search hit, not the file's end
+		return m.restartTail()
+	}
+	if isBinaryExtension(m.currentFile.Name) || ssh.Decompressor(m.currentFile.Name) != "" {
 		return m, nil
 	}
 	fullPath := filepath.Join(m.currentFolder.Path, m.currentFile.Name)
 	ch := make(chan []byte, 64)
 	m.tailChan = ch
-	m.setContext(fmt.Sprintf("\033[32mResuming tail\033[0m %s:%s", m.currentServer.Name, fullPath))
-	return m, startTailCmd(m.pool, *m.currentServer, fullPath, ch)
+	m.setContext(i18n.T("\033[32mResuming tail\033[0m %s:%s", m.currentServer.Name, fullPath))
+	return m, m.followCmd(fullPath, ch)
+}
+
+// restartTail stops the current tail, re-reads the last N lines and starts
+// tailing the same file again, keeping the active tail filter.
+func (m Model) restartTail() (tea.Model, tea.Cmd) {
+	if m.currentServer != nil && m.currentFolder != nil && m.multiFiles != nil {
+		return m.restartMultiTail()
+	}
+	if m.currentServer == nil || m.currentFolder == nil || m.currentFile == nil {
+		return m, nil
+	}
+	filter := m.viewerPane.GetTailFilter()
+	// Stopped first, or onFileSelected would keep the running tail
+	m.stopTailInPlace()
+	m2, cmd := m.onFileSelected(m.filePane.selectedFileIdx, *m.currentFile)
+	m = m2.(Model)
+	m.viewerPane.SetTailFilter(filter)
+	return m, cmd
 }
 
 func (m Model) autoStart() (tea.Model, tea.Cmd) {
@@ -883,27 +2006,13 @@ func (m Model) autoStart() (tea.Model, tea.Cmd) {
 		}
 	}
 	if serverIdx < 0 {
-		m.errorMsg = fmt.Sprintf("Server %q not found", m.autoSelect.Server)
+		m.errorMsg = i18n.T("Server %q not found", m.autoSelect.Server)
 		return m, nil
 	}
 
 	// If --file is set, install callback
 	if m.autoSelect.File != "" {
-		autoFile := m.autoSelect.File
-		m.onFilesLoaded = func(model *Model) tea.Cmd {
-			files := model.filePane.GetFiles()
-			for i, f := range files {
-				if strings.EqualFold(f.Name, autoFile) {
-					// Return a command that will trigger file selection
-					fileCopy := f
-					return func() tea.Msg {
-						return autoFileSelectMsg{idx: i, file: fileCopy}
-					}
-				}
-			}
-			model.errorMsg = fmt.Sprintf("File %q not found", autoFile)
-			return nil
-		}
+		m.onFilesLoaded = selectFileCallback(m.autoSelect.File)
 	}
 
 	folders := srv.LogFolders
@@ -919,7 +2028,7 @@ func (m Model) autoStart() (tea.Model, tea.Cmd) {
 				return m.onFolderSelected(i, f)
 			}
 		}
-		m.errorMsg = fmt.Sprintf("Folder %q not found on %s", m.autoSelect.Folder, srv.Name)
+		m.errorMsg = i18n.T("Folder %q not found on %s", m.autoSelect.Folder, srv.Name)
 		return m, nil
 	}
 
@@ -931,8 +2040,98 @@ type autoFileSelectMsg struct {
 	file ssh.FileInfo
 }
 
+// selectFileCallback returns an onFilesLoaded callback that selects the file
+// with the given name once the listing arrives.
+func selectFileCallback(name string) func(*Model) tea.Cmd {
+	return func(model *Model) tea.Cmd {
+		files := model.filePane.GetFiles()
+		for i, f := range files {
+			if !f.IsDir && strings.EqualFold(f.Name, name) {
+				// Return a command that will trigger file selection
+				fileCopy := f
+				return func() tea.Msg {
+					return autoFileSelectMsg{idx: i, file: fileCopy}
+				}
+			}
+		}
+		model.errorMsg = i18n.T("File %q not found", name)
+		return nil
+	}
+}
+
+// enterFolder is the onFilesLoaded callback of a folder being entered: it
+// opens the file of a tail group running there, the newest file with
+// auto_open: newest, and otherwise goes back to the file last opened there.
+func enterFolder(model *Model) tea.Cmd {
+	if model.currentServer == nil || model.currentFolder == nil || model.currentFile != nil {
+		return nil
+	}
+	if name := model.warm.fileIn(*model.currentServer, model.currentFolder.Path); name != "" {
+		for i, f := range model.filePane.GetFiles() {
+			if f.Name == name {
+				model.filePane.SetFileCursor(i)
+				return func() tea.Msg {
+					return autoFileSelectMsg{idx: i, file: f}
+				}
+			}
+		}
+	}
+	if model.currentFolder.AutoOpen == "newest" {
+		return openNewestFile(model)
+	}
+	return restoreLastFile(model)
+}
+
+// openNewestFile opens the most recently modified file of the listing.
+func openNewestFile(model *Model) tea.Cmd {
+	newest := -1
+	files := model.filePane.GetFiles()
+	for i, f := range files {
+		if !f.IsDir && (newest < 0 || f.ModTime.After(files[newest].ModTime)) {
+			newest = i
+		}
+	}
+	if newest < 0 {
+		return nil
+	}
+	model.filePane.SetFileCursor(newest)
+	f := files[newest]
+	return func() tea.Msg {
+		return autoFileSelectMsg{idx: newest, file: f}
+	}
+}
+
+// restoreLastFile puts the cursor on the file last opened in the folder, or
+// opens it with defaults.reopen_last_file.
+func restoreLastFile(model *Model) tea.Cmd {
+	name := model.state.LastFile(ssh.ServerKey(*model.currentServer), model.currentFolder.Path)
+	if name == "" {
+		return nil
+	}
+	for i, f := range model.filePane.GetFiles() {
+		if f.Name != name {
+			continue
+		}
+		model.filePane.SetFileCursor(i)
+		if !model.cfg.Defaults.ReopenLastFile {
+			return nil
+		}
+		return func() tea.Msg {
+			return autoFileSelectMsg{idx: i, file: f}
+		}
+	}
+	return nil
+}
+
 // handleModalKey handles keyboard input when a modal is open.
 func (m Model) handleModalKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.pendingPaste != "" {
+		return m.confirmPaste(msg)
+	}
+	if msg.Paste && (m.modal == modalFilter || m.modal == modalNote || m.modal == modalSearch || m.modal == modalTrace || m.modal == modalPatterns) {
+		return m.pasteIntoPrompt(string(msg.Runes))
+	}
+
 	switch msg.String() {
 	case "ctrl+c":
 		m.dismissDownload()
@@ -948,14 +2147,31 @@ func (m Model) handleModalKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					m.downloadCancel()
 				}
 				return m, nil
-			case downloadPhaseDone, downloadPhaseError:
+			case downloadPhaseChecking, downloadPhaseConfirm, downloadPhaseDone, downloadPhaseError:
 				m.dismissDownload()
 				m.modal = modalNone
 				return m, nil
 			}
 		}
+		if m.modal == modalHostKey {
+			m.filePane.SetMessage("Host key not accepted\n\n" + m.hostKeyErr.Error())
+			m.focused = paneServer
+		}
+		if m.modal == modalPassphrase {
+			m.filePane.SetMessage("Unable to connect\n\n" + m.passphraseErr.Error())
+			m.focused = paneServer
+		}
+		if m.modal == modalChallenge {
+			// Aborts this login; queued prompts from other logins still show
+			return m.answerChallenge(nil), nil
+		}
 		m.modal = modalNone
 		m.sudoServer = nil
+		m.sudoRetryFile = nil
+		m.hostKeyErr = nil
+		m.hostKeyServer = nil
+		m.passphraseErr = nil
+		m.passphraseSrv = nil
 		return m, nil
 
 	case "enter":
@@ -968,6 +2184,14 @@ func (m Model) handleModalKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.submitModal()
 
 	case "tab":
+		if m.modal == modalSudo {
+			m.sudoRemember = !m.sudoRemember
+			return m, nil
+		}
+		if m.modal == modalTrace && m.currentServer != nil && m.currentServer.Group != "" {
+			m.traceGroup = !m.traceGroup
+			return m, nil
+		}
 		if m.modal == modalDownload && m.downloadPhase == downloadPhaseInput {
 			m.modalFocus = (m.modalFocus + 1) % 2
 			if m.modalFocus == 0 {
@@ -981,10 +2205,49 @@ func (m Model) handleModalKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	if m.modal == modalFileAction {
+		return m.fileActionKey(msg.String()), nil
+	}
+	if m.modal == modalPatterns && m.patternsSave {
+		switch msg.String() {
+		case "y":
+			return m.savePatterns(), nil
+		case "n":
+			m.modal = modalNone
+		}
+		return m, nil
+	}
+	if m.modal == modalMetrics {
+		if msg.String() == "e" {
+			m.modal = modalNone
+			return m.exportMetrics()
+		}
+		return m, nil
+	}
+	if m.modal == modalService {
+		switch k := msg.String(); {
+		case k == "j" && !m.service.Journal:
+			return m.showServiceStatus(true)
+		case k == "s" && m.service.Journal:
+			return m.showServiceStatus(false)
+		}
+		return m, nil
+	}
+	if m.modal == modalEnrich {
+		if msg.String() == "a" {
+			m.modal = modalNone
+			return m.annotateAll(), nil
+		}
+		return m, nil
+	}
+
 	// During progress/done/error phases, ignore other keys
 	if m.modal == modalDownload && m.downloadPhase != downloadPhaseInput {
 		return m, nil
 	}
+	if m.modal == modalInfo || m.modal == modalHostKey || m.modal == modalCatalog || m.modal == modalBanner || m.modal == modalHelp || m.modal == modalProcs || m.modal == modalStats {
+		return m, nil
+	}
 
 	// Forward to the focused text input
 	var cmd tea.Cmd
@@ -996,6 +2259,41 @@ func (m Model) handleModalKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// pasteIntoPrompt inserts a paste into a one-line prompt, flattened to a
+// single line. A paste too long for a sensible filter asks first.
+func (m Model) pasteIntoPrompt(text string) (tea.Model, tea.Cmd) {
+	text, lines := cleanPaste(text)
+	if text == "" {
+		return m, nil
+	}
+	if utf8.RuneCountInString(text) > maxPasteLen {
+		m.pendingPaste = text
+		m.pasteLines = lines
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.modalInput, cmd = m.modalInput.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text), Paste: true})
+	return m, cmd
+}
+
+// confirmPaste handles the keys of the oversized paste confirmation:
+// Enter inserts the paste, Esc discards it, anything else is ignored.
+func (m Model) confirmPaste(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		text := m.pendingPaste
+		m.pendingPaste = ""
+		var cmd tea.Cmd
+		m.modalInput, cmd = m.modalInput.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text), Paste: true})
+		return m, cmd
+	case "esc":
+		m.pendingPaste = ""
+	case "ctrl+c":
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
 // dismissDownload resets all download-related state.
 func (m *Model) dismissDownload() {
 	if m.downloadCancel != nil {
@@ -1013,6 +2311,68 @@ func (m *Model) dismissDownload() {
 
 func (m Model) submitModal() (tea.Model, tea.Cmd) {
 	switch m.modal {
+	case modalSearch:
+		m.modal = modalNone
+		return m.startSearch(strings.TrimSpace(m.modalInput.Value()))
+
+	case modalTrace:
+		m.modal = modalNone
+		return m.startTrace(strings.TrimSpace(m.modalInput.Value()))
+
+	case modalFileAction:
+		return m.runFileAction()
+
+	case modalPatterns:
+		if m.patternsSave {
+			return m.savePatterns(), nil
+		}
+		return m.applyPatterns()
+
+	case modalInfo, modalCatalog, modalMetrics, modalEnrich, modalBanner, modalHelp, modalProcs, modalService, modalStats:
+		m.modal = modalNone
+
+	case modalHostKey:
+		m.modal = modalNone
+		if m.hostKeyErr != nil && m.hostKeyServer != nil {
+			hkErr, srv := m.hostKeyErr, *m.hostKeyServer
+			m.hostKeyErr = nil
+			m.hostKeyServer = nil
+			return m, acceptHostKeyCmd(m.pool, srv, hkErr)
+		}
+
+	case modalPassphrase:
+		passphrase := m.modalInput.Value()
+		m.modal = modalNone
+		if m.passphraseErr != nil && m.passphraseSrv != nil {
+			ppErr, srv := m.passphraseErr, *m.passphraseSrv
+			m.passphraseErr = nil
+			m.passphraseSrv = nil
+			if passphrase == "" {
+				m.filePane.SetMessage("Unable to connect\n\n" + ppErr.Error())
+				m.focused = paneServer
+				return m, nil
+			}
+			m.setContext(i18n.T("\033[33mUnlocking\033[0m %s...", ppErr.KeyPath))
+			return m, unlockKeyCmd(m.pool, srv, ppErr, passphrase)
+		}
+
+	case modalChallenge:
+		if m.challenge != nil {
+			answers := append(m.challengeAnswers, m.modalInput.Value())
+			if len(answers) < len(m.challenge.Questions) {
+				m.challengeAnswers = answers
+				m = m.showChallengeQuestion()
+				return m, nil
+			}
+			return m.answerChallenge(answers), nil
+		}
+		m.modal = modalNone
+
+	case modalNote:
+		m.modal = modalNone
+		m.state.SetNote(m.noteServer, m.notePath, strings.TrimSpace(m.modalInput.Value()))
+		return m, saveStateCmd(m.state)
+
 	case modalSudo:
 		pw := m.modalInput.Value()
 		m.modal = modalNone
@@ -1020,14 +2380,26 @@ func (m Model) submitModal() (tea.Model, tea.Cmd) {
 			srv := *m.sudoServer
 			m.sudoServer = nil
 			if pw == "" {
-				m.setContext("\033[33mSudo password cancelled\033[0m")
+				m.sudoRetryFile = nil
+				m.setContext(i18n.T("\033[33mSudo password cancelled\033[0m"))
 				m.focused = paneServer
 				return m, nil
 			}
 			m.pool.SetSudoPassword(srv, pw)
+			// Stored once a listing shows sudo accepts it, not while a
+			// rejection could still delete it from the keychain
+			m.sudoToStore = nil
+			if m.sudoRemember {
+				m.sudoToStore = &srv
+			}
 			m.focused = paneFile
+			if m.sudoRetryFile != nil {
+				// Re-open the file whose read was rejected once the listing is back
+				m.onFilesLoaded = selectFileCallback(m.sudoRetryFile.Name)
+				m.sudoRetryFile = nil
+			}
 			if m.currentFolder != nil {
-				m.setContext(fmt.Sprintf("\033[33mConnecting to\033[0m %s...", srv.Name))
+				m.setContext(i18n.T("\033[33mConnecting to\033[0m %s...", srv.Name))
 				return m, connectAndListCmd(m.pool, srv, *m.currentFolder)
 			}
 			return m, nil
@@ -1036,7 +2408,15 @@ func (m Model) submitModal() (tea.Model, tea.Cmd) {
 	case modalFilter:
 		newFilter := m.modalInput.Value()
 		m.modal = modalNone
+		if m.stdinView {
+			return m.filterStdin(newFilter)
+		}
 		// Re-load with filter
+		if m.currentServer != nil && m.currentFolder != nil && m.currentFile != nil && m.currentFolder.IsKubectl() {
+			// A pod's log can't be re-read apart from following it
+			m.viewerPane.SetTailFilter(newFilter)
+			return m.restartTail()
+		}
 		if m.currentServer != nil && m.currentFolder != nil && m.currentFile != nil {
 			wasTailing := m.tailing
 			m.stopTailInPlace()
@@ -1045,7 +2425,7 @@ func (m Model) submitModal() (tea.Model, tea.Cmd) {
 			m.viewerPane.SetTailFilter(newFilter) // Clear resets it, set again
 			fullPath := filepath.Join(m.currentFolder.Path, m.currentFile.Name)
 			if newFilter != "" {
-				m.setContext(fmt.Sprintf("\033[32m%s\033[0m %s \033[33m[filter: %s]\033[0m", m.currentServer.Name, fullPath, newFilter))
+				m.setContext(i18n.T("\033[32m%s\033[0m %s \033[33m[filter: %s]\033[0m", m.currentServer.Name, fullPath, newFilter))
 			} else {
 				m.setContext(fmt.Sprintf("\033[32m%s\033[0m %s", m.currentServer.Name, fullPath))
 			}
@@ -1059,31 +2439,46 @@ func (m Model) submitModal() (tea.Model, tea.Cmd) {
 		}
 
 	case modalDownload:
-		dir := m.modalInput.Value()
-		name := m.modalInput2.Value()
-		if m.currentServer != nil && m.currentFolder != nil && m.downloadFile != nil {
-			remotePath := filepath.Join(m.currentFolder.Path, m.downloadFile.Name)
-
-			// Transition to progress phase
-			m.downloadPhase = downloadPhaseProgress
-			dlCtx, dlCancel := context.WithCancel(context.Background())
-			progressCh := make(chan int64, 1)
-			m.downloadCancel = dlCancel
-			m.downloadProgressCh = progressCh
-			m.downloadTotalBytes = m.downloadFile.Size
-			m.downloadBytesDownloaded = 0
-			m.downloadLocalPath = filepath.Join(dir, name)
-
-			return m, tea.Batch(
-				downloadFileCmd(m.pool, *m.currentServer, remotePath, dir, name, dlCtx, progressCh),
-				waitForDownloadProgress(progressCh, m.downloadFile.Size),
-			)
+		switch m.downloadPhase {
+		case downloadPhaseInput:
+			if m.currentServer != nil && m.currentFolder != nil && m.downloadFile != nil {
+				// Check the current size first: the listing may be stale
+				remotePath := filepath.Join(m.currentFolder.Path, m.downloadFile.Name)
+				m.downloadPhase = downloadPhaseChecking
+				m.downloadLocalPath = filepath.Join(m.modalInput.Value(), m.modalInput2.Value())
+				return m, statForDownloadCmd(m.pool, *m.currentServer, remotePath)
+			}
+		case downloadPhaseConfirm:
+			return m.startDownload()
 		}
 	}
 
 	return m, nil
 }
 
+// startDownload downloads the file checked by the stat, no further than the
+// size it had then.
+func (m Model) startDownload() (tea.Model, tea.Cmd) {
+	if m.currentServer == nil || m.currentFolder == nil || m.downloadFile == nil {
+		return m, nil
+	}
+	remotePath := filepath.Join(m.currentFolder.Path, m.downloadFile.Name)
+
+	// Transition to progress phase
+	m.downloadPhase = downloadPhaseProgress
+	dlCtx, dlCancel := context.WithCancel(context.Background())
+	progressCh := make(chan int64, 1)
+	m.downloadCancel = dlCancel
+	m.downloadProgressCh = progressCh
+	m.downloadBytesDownloaded = 0
+
+	dir, name := filepath.Dir(m.downloadLocalPath), filepath.Base(m.downloadLocalPath)
+	return m, tea.Batch(
+		downloadFileCmd(m.pool, *m.currentServer, remotePath, dir, name, m.downloadTotalBytes, dlCtx, progressCh),
+		waitForDownloadProgress(progressCh, m.downloadTotalBytes),
+	)
+}
+
 // modalInnerWidth is the usable text width inside the modal (Width - horizontal padding).
 const modalInnerWidth = 70 - 4 // modal Width(70) minus Padding(1, 2) = 2 left + 2 right
 
@@ -1108,6 +2503,284 @@ func (m Model) showSudoPrompt(srv config.ServerConfig) Model {
 	m.modal = modalSudo
 	m.modalInput = ti
 	m.sudoServer = &srv
+	m.sudoRemember = m.sudoInKeychain(srv)
+	return m
+}
+
+// sudoInKeychain reports whether the sudo password of srv is kept in the OS
+// keychain: with the keychain setting, or when it was put there from the
+// password prompt.
+func (m Model) sudoInKeychain(srv config.ServerConfig) bool {
+	return srv.UseKeychain() || m.state.SudoInKeychain(ssh.ServerKey(srv))
+}
+
+// storeSudoPassword saves the sudo password of srv, now that sudo accepted
+// it, in the OS keychain, noting in the state file that it is kept there
+// when keychain is off for srv.
+func (m Model) storeSudoPassword(srv config.ServerConfig) tea.Cmd {
+	cmd := keychainStoreCmd(srv, m.pool.GetSudoPassword(srv))
+	if srv.UseKeychain() {
+		return cmd
+	}
+	m.state.SetSudoInKeychain(ssh.ServerKey(srv), true)
+	return tea.Batch(cmd, saveStateCmd(m.state))
+}
+
+// showPassphrasePrompt asks for the passphrase of an encrypted private key.
+func (m Model) showPassphrasePrompt(srv config.ServerConfig, ppErr *ssh.PassphraseError) Model {
+	ti := styledInput()
+	ti.Placeholder = "Passphrase"
+	ti.EchoMode = textinput.EchoPassword
+	ti.EchoCharacter = '*'
+	ti.Focus()
+
+	m.modal = modalPassphrase
+	m.modalInput = ti
+	m.passphraseErr = ppErr
+	m.passphraseSrv = &srv
+	return m
+}
+
+// showChallenge opens the prompt for a keyboard-interactive challenge.
+func (m Model) showChallenge(c ssh.Challenge) Model {
+	m.challenge = &c
+	m.challengeAnswers = nil
+	return m.showChallengeQuestion()
+}
+
+// showChallengeQuestion shows the next unanswered question of the challenge.
+func (m Model) showChallengeQuestion() Model {
+	i := len(m.challengeAnswers)
+	ti := styledInput()
+	if i < len(m.challenge.Echos) && !m.challenge.Echos[i] {
+		ti.EchoMode = textinput.EchoPassword
+		ti.EchoCharacter = '*'
+	}
+	ti.Focus()
+
+	m.modal = modalChallenge
+	m.modalInput = ti
+	return m
+}
+
+// answerChallenge sends the answers (nil aborts the login) and moves on to
+// the next queued challenge, if any.
+func (m Model) answerChallenge(answers []string) Model {
+	if m.challenge != nil {
+		m.challenge.Reply <- answers
+	}
+	return m.showNextChallenge()
+}
+
+// showNextChallenge closes the current challenge and opens the next queued
+// one, if any.
+func (m Model) showNextChallenge() Model {
+	m.challenge = nil
+	m.challengeAnswers = nil
+	m.modal = modalNone
+	if len(m.challengeQueue) > 0 {
+		next := m.challengeQueue[0]
+		m.challengeQueue = m.challengeQueue[1:]
+		m = m.showChallenge(next)
+	}
+	return m
+}
+
+// expireChallenge closes or unqueues the challenge answered on reply, whose
+// login has given up waiting, such as on its connect timeout. Answered
+// challenges are gone already.
+func (m Model) expireChallenge(reply chan<- []string) Model {
+	queue := m.challengeQueue[:0]
+	for _, c := range m.challengeQueue {
+		if c.Reply != reply {
+			queue = append(queue, c)
+		}
+	}
+	m.challengeQueue = queue
+	if m.modal == modalChallenge && m.challenge != nil && m.challenge.Reply == reply {
+		logger.Log("app", "login to %s gave up waiting for the challenge answers", m.challenge.Server.Name)
+		m.errorMsg = i18n.T("%s: login timed out waiting for the answer", m.challenge.Server.Name)
+		m = m.showNextChallenge()
+	}
+	return m
+}
+
+// dropChallenges discards prompts from a failed login to srv or one of its
+// jump hosts; nobody is waiting for their answers anymore.
+func (m *Model) dropChallenges(srv config.ServerConfig) {
+	keys := map[string]bool{ssh.ServerKey(srv): true}
+	for _, hop := range srv.JumpHosts {
+		keys[ssh.ServerKey(hop)] = true
+	}
+	queue := m.challengeQueue[:0]
+	for _, c := range m.challengeQueue {
+		if !keys[ssh.ServerKey(c.Server)] {
+			queue = append(queue, c)
+		}
+	}
+	m.challengeQueue = queue
+	if m.modal == modalChallenge && m.challenge != nil && keys[ssh.ServerKey(m.challenge.Server)] {
+		*m = m.showNextChallenge()
+	}
+}
+
+// showHostInfo opens a popup describing the machine the current server's
+// connection reached: remote hostname and host key fingerprint.
+func (m Model) showHostInfo() Model {
+	if m.currentServer == nil {
+		return m
+	}
+	m.modal = modalInfo
+	return m
+}
+
+// onEventsLoaded records new events from the feed, marks those that apply
+// to the open file and schedules the next poll.
+func (m Model) onEventsLoaded(msg EventsLoadedMsg) (tea.Model, tea.Cmd) {
+	next := tea.Tick(m.cfg.Events.Interval, func(time.Time) tea.Msg {
+		return eventsTickMsg{}
+	})
+	if msg.Err != nil {
+		logger.Log("events", "%v", msg.Err)
+		m.errorMsg = i18n.T("events: %v", msg.Err)
+		return m, next
+	}
+
+	var fresh []events.Event
+	for _, ev := range msg.Events {
+		if key := ev.Key(); !m.eventKeys[key] {
+			m.eventKeys[key] = true
+			fresh = append(fresh, ev)
+		}
+	}
+	m.events = append(m.events, fresh...)
+	// The first poll only brings history; later ones bring what just happened
+	m.placeEvents(fresh, m.eventsPolled)
+	m.eventsPolled = true
+	return m, next
+}
+
+// eventHorizon is how far back events are placed in a freshly opened file.
+// Lines are matched by time of day only, so older events would land on the
+// wrong day.
+const eventHorizon = 24 * time.Hour
+
+// placeEvents adds markers for the events that apply to the open file.
+// Live events without a timestamped line to go by are appended at the end.
+func (m *Model) placeEvents(evs []events.Event, live bool) {
+	if m.currentServer == nil || m.currentFile == nil {
+		return
+	}
+	cutoff := time.Now().Add(-eventHorizon)
+	for _, ev := range evs {
+		if !ev.AppliesTo(m.currentServer.Name) || ev.Time.Before(cutoff) {
+			continue
+		}
+		label := fmt.Sprintf("%s · %s", ev.Text, ev.Time.Local().Format("15:04:05"))
+		m.viewerPane.InsertMarker(ev.Time, label, live)
+	}
+}
+
+// onCatalogLoaded merges freshly fetched catalog servers into the server
+// list. After an explicit refresh the changes are shown in a popup.
+func (m Model) onCatalogLoaded(msg CatalogLoadedMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	if msg.Initial {
+		cmd = func() tea.Msg { return eagerConnectMsg{} }
+		if m.autoSelect.Server != "" {
+			cmd = tea.Batch(cmd, func() tea.Msg { return autoStartMsg{} })
+		}
+	}
+	if msg.Err != nil {
+		m.errorMsg = i18n.T("catalog: %v", msg.Err)
+		return m, cmd
+	}
+
+	before := m.cfg.Servers
+	shadowed, err := m.cfg.MergeCatalog(msg.Servers)
+	if err != nil {
+		m.errorMsg = i18n.T("catalog: %v", err)
+		return m, cmd
+	}
+	selected := ""
+	if m.currentServer != nil {
+		selected = m.currentServer.Name
+	}
+	m.serverPane.SetServers(m.cfg.Servers, selected)
+
+	if !msg.Initial {
+		m.catalogDiff = config.DiffServers(before, m.cfg.Servers)
+		for _, name := range shadowed {
+			m.catalogDiff = append(m.catalogDiff, fmt.Sprintf("%s: local config takes precedence", name))
+		}
+		m.setContext(i18n.T("\033[32mCatalog refreshed\033[0m (%d changes)", len(m.catalogDiff)-len(shadowed)))
+		m.modal = modalCatalog
+	}
+	return m, cmd
+}
+
+// startEagerConnect dials every server with eager_connect in parallel, once
+// per connection (servers sharing user, host and port share it).
+func (m Model) startEagerConnect() (tea.Model, tea.Cmd) {
+	seen := make(map[string]bool)
+	var cmds []tea.Cmd
+	for _, srv := range m.cfg.Servers {
+		key := ssh.ServerKey(srv)
+		if !srv.ConnectEagerly() || seen[key] {
+			continue
+		}
+		seen[key] = true
+		cmds = append(cmds, eagerConnectCmd(m.pool, srv))
+	}
+	if len(cmds) == 0 {
+		return m, nil
+	}
+	logger.Log("app", "eager connect to %d servers", len(cmds))
+	m.eagerTotal, m.eagerDone = len(cmds), 0
+	m.serverPane.SetConnectProgress(0, m.eagerTotal)
+	return m, tea.Batch(cmds...)
+}
+
+// noteTarget returns the file a note applies to: the file under the cursor
+// in the file pane, otherwise the file open in the viewer.
+func (m Model) noteTarget() (serverKey, path string, ok bool) {
+	if m.currentServer == nil || m.currentFolder == nil {
+		return "", "", false
+	}
+	file := m.currentFile
+	if m.focused == paneFile {
+		_, _, _, _, file = m.filePane.SelectedItem()
+	}
+	if file == nil || file.IsDir {
+		return "", "", false
+	}
+	return ssh.ServerKey(*m.currentServer), filepath.Join(m.currentFolder.Path, file.Name), true
+}
+
+// currentNote returns the note attached to the file open in the viewer.
+func (m Model) currentNote() string {
+	if m.currentServer == nil || m.currentFolder == nil || m.currentFile == nil {
+		return ""
+	}
+	return m.state.Note(ssh.ServerKey(*m.currentServer), filepath.Join(m.currentFolder.Path, m.currentFile.Name))
+}
+
+// showNotePrompt opens the note editor for the targeted file.
+func (m Model) showNotePrompt() Model {
+	serverKey, path, ok := m.noteTarget()
+	if !ok {
+		return m
+	}
+	ti := styledInput()
+	ti.Placeholder = "e.g. rotates hourly, check -1.gz too"
+	ti.CharLimit = 500
+	ti.SetValue(m.state.Note(serverKey, path))
+	ti.Focus()
+
+	m.modal = modalNote
+	m.modalInput = ti
+	m.noteServer = serverKey
+	m.notePath = path
 	return m
 }
 
@@ -1122,8 +2795,32 @@ func (m Model) showFilterPrompt() Model {
 	return m
 }
 
+// downloadDir is where downloads and exports go by default.
+func (m Model) downloadDir() string {
+	if m.cfg.Defaults.DownloadDir != "" {
+		return m.cfg.Defaults.DownloadDir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "."
+	}
+	return filepath.Join(home, "Downloads")
+}
+
+// exportViewer saves the viewer's lines, without colors, to a file in the
+// download directory.
+func (m Model) exportViewer() (tea.Model, tea.Cmd) {
+	if m.currentFile == nil {
+		m.errorMsg = i18n.T("open a file to export it")
+		return m, nil
+	}
+	text, n := m.viewerPane.PlainText()
+	name := fmt.Sprintf("%s-%s.txt", m.currentFile.Name, time.Now().Format("20060102-150405"))
+	return m, exportTextCmd(filepath.Join(m.downloadDir(), name), text, n)
+}
+
 func (m Model) showDownloadDialog() (tea.Model, tea.Cmd) {
-	if m.currentServer == nil || m.currentFolder == nil {
+	if m.currentServer == nil || m.currentFolder == nil || m.kubectlRefused() {
 		return m, nil
 	}
 
@@ -1134,19 +2831,9 @@ func (m Model) showDownloadDialog() (tea.Model, tea.Cmd) {
 	}
 	m.downloadFile = file
 
-	defaultDir := m.cfg.Defaults.DownloadDir
-	if defaultDir == "" {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			defaultDir = filepath.Join(home, "Downloads")
-		} else {
-			defaultDir = "."
-		}
-	}
-
 	ti1 := styledInput()
 	ti1.Placeholder = "Local path"
-	ti1.SetValue(defaultDir)
+	ti1.SetValue(m.downloadDir())
 	ti1.Focus()
 
 	ti2 := styledInput()
@@ -1164,30 +2851,211 @@ func (m Model) showDownloadDialog() (tea.Model, tea.Cmd) {
 func (m Model) renderModal(background string) string {
 	var title, content string
 
-	buttonOK := modalButtonStyle.Render("[Enter] OK")
-	buttonCancel := modalButtonStyle.Render("[Esc] Cancel")
-	buttonTab := modalButtonStyle.Render("[Tab] Next")
+	buttonOK := modalButtonStyle.Render(i18n.T("[Enter] OK"))
+	buttonCancel := modalButtonStyle.Render(i18n.T("[Esc] Cancel"))
+	buttonTab := modalButtonStyle.Render(i18n.T("[Tab] Next"))
 
 	switch m.modal {
 	case modalSudo:
-		title = fmt.Sprintf("Sudo password for %s", m.currentServer.Name)
-		content = m.modalInput.View() + "\n\n" + buttonOK + "  " + buttonCancel
+		title = i18n.T("Sudo password for %s", m.currentServer.Name)
+		remember := "[ ]"
+		if m.sudoRemember {
+			remember = "[x]"
+		}
+		content = m.modalInput.View() + "\n\n" +
+			modalHintStyle.Render(remember+" "+i18n.T("Remember in the OS keychain (Tab)")) + "\n\n" +
+			buttonOK + "  " + buttonCancel
+
+	case modalPassphrase:
+		e := m.passphraseErr
+		title = i18n.T("Key passphrase")
+		hint := i18n.T("Key for %s:", e.Server.Name)
+		if e.Server.Name != m.passphraseSrv.Name {
+			hint = i18n.T("Key for jump host %s:", e.Server.Name)
+		}
+		content = modalHintStyle.Render(hint) + "\n" +
+			lipgloss.NewStyle().Foreground(lipgloss.Color("15")).Render(e.KeyPath) +
+			"\n\n" + m.modalInput.View() + "\n\n" + buttonOK + "  " + buttonCancel
+
+	case modalChallenge:
+		c := m.challenge
+		title = i18n.T("Login to %s", c.Server.Name)
+		if c.Name != "" {
+			title = c.Name
+		}
+		if text := strings.TrimSpace(c.Instruction); text != "" {
+			content = modalHintStyle.Render(text) + "\n\n"
+		}
+		question := strings.TrimSpace(c.Questions[len(m.challengeAnswers)])
+		content += lipgloss.NewStyle().Foreground(lipgloss.Color("15")).Render(question) +
+			"\n" + m.modalInput.View() + "\n\n" + buttonOK + "  " + buttonCancel
+
+	case modalInfo:
+		srv := *m.currentServer
+		info := m.pool.HostInfo(srv)
+		hostname := info.Hostname
+		if hostname == "" {
+			hostname = i18n.T("(not connected)")
+		}
+		fingerprint := info.Fingerprint
+		if fingerprint == "" {
+			fingerprint = i18n.T("(not connected)")
+		} else {
+			fingerprint = info.KeyType + " " + fingerprint
+		}
+		valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
+		title = i18n.T("Server %s", srv.Name)
+		content = modalHintStyle.Render(i18n.T("Configured address:")) + "\n" +
+			valueStyle.Render(fmt.Sprintf("%s@%s:%d", srv.User, srv.Host, srv.Port)) +
+			"\n\n" + modalHintStyle.Render(i18n.T("Remote hostname:")) + "\n" + valueStyle.Render(hostname) +
+			"\n\n" + modalHintStyle.Render(i18n.T("Host key:")) + "\n" + valueStyle.Render(fingerprint)
+		if banner := m.bannerSummary(); banner != "" {
+			content += "\n\n" + banner
+		}
+		content += "\n\n" + buttonOK
+
+	case modalHostKey:
+		e := m.hostKeyErr
+		valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
+		var warning string
+		if e.Mismatch {
+			title = i18n.T("Host key changed!")
+			warning = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true).Render(
+				i18n.T("The key differs from the one in known_hosts. This could mean someone is intercepting the connection, or the server was reinstalled.")) + "\n\n"
+		} else {
+			title = i18n.T("Unknown host key")
+		}
+		content = warning + modalHintStyle.Render(i18n.T("Server:")) + "\n" +
+			valueStyle.Render(fmt.Sprintf("%s (%s)", e.Server.Name, e.Address)) +
+			"\n\n" + modalHintStyle.Render(i18n.T("Host key:")) + "\n" + valueStyle.Render(e.Key.Type()+" "+e.Fingerprint()) +
+			"\n\n" + modalHintStyle.Render(i18n.T("Accept and remember this fingerprint in known_hosts?")) +
+			"\n\n" + modalButtonStyle.Render(i18n.T("[Enter] Accept")) + "  " + buttonCancel
+
+	case modalMetrics:
+		title = i18n.T("Metrics for %s", m.currentFile.Name)
+		content = m.metricsSummary() + "\n\n" +
+			buttonOK + "  " + modalButtonStyle.Render(i18n.T("[e] Export CSV"))
+
+	case modalHelp:
+		title = i18n.T("Shortcuts: %s", i18n.T(m.paneTitle()))
+		if m.focused == paneFile && m.filePane.IsInFolderMode() {
+			title = i18n.T("Shortcuts: %s", i18n.T("Folders"))
+		}
+		content = renderHelp(paneBindings(m.focused, m.filePane.IsInFolderMode(), m.cfg.Catalog.Source != "")) +
+			"\n\n" + modalButtonStyle.Render(i18n.T("[F1/Esc] Close"))
+
+	case modalBanner:
+		title = i18n.T("Message from %s", m.currentServer.Name)
+		content = m.bannerSummary() + "\n\n" + modalButtonStyle.Render(i18n.T("[Enter] Acknowledge"))
+
+	case modalProcs:
+		title = i18n.T("Processes using %s", filepath.Base(m.procs.Path))
+		content = m.procsSummary() + "\n\n" + buttonOK
+
+	case modalStats:
+		title = i18n.T("Connection stats")
+		content = m.statsSummary() + "\n\n" + buttonOK
+
+	case modalService:
+		if m.service.Journal {
+			title = i18n.T("Journal of %s", m.service.Unit)
+			content = m.serviceSummary() + "\n\n" + buttonOK + "  " + modalButtonStyle.Render(i18n.T("[s] Status"))
+		} else {
+			title = i18n.T("Status of %s", m.service.Unit)
+			content = m.serviceSummary() + "\n\n" + buttonOK + "  " + modalButtonStyle.Render(i18n.T("[j] Journal"))
+		}
+
+	case modalEnrich:
+		title = i18n.T("IP addresses")
+		content = m.enrichSummary() + "\n\n" +
+			buttonOK + "  " + modalButtonStyle.Render(i18n.T("[a] Annotate all"))
+
+	case modalCatalog:
+		title = i18n.T("Catalog refreshed")
+		if len(m.catalogDiff) == 0 {
+			content = modalHintStyle.Render(i18n.T("No changes"))
+		} else {
+			lines := m.catalogDiff
+			const maxLines = 15
+			if len(lines) > maxLines {
+				lines = append(lines[:maxLines:maxLines], i18n.T("… and %d more", len(m.catalogDiff)-maxLines))
+			}
+			var b strings.Builder
+			for i, l := range lines {
+				if i > 0 {
+					b.WriteByte('\n')
+				}
+				switch {
+				case strings.HasPrefix(l, "+"):
+					b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render(l))
+				case strings.HasPrefix(l, "-"):
+					b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render(l))
+				case strings.HasPrefix(l, "~"):
+					b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render(l))
+				default:
+					b.WriteString(modalHintStyle.Render(l))
+				}
+			}
+			content = b.String()
+		}
+		content += "\n\n" + buttonOK
+
+	case modalNote:
+		title = i18n.T("Note for %s", filepath.Base(m.notePath))
+		content = m.modalInput.View() + "\n\n" +
+			modalHintStyle.Render(i18n.T("Leave empty to remove the note")) + "\n\n" + buttonOK + "  " + buttonCancel
 
 	case modalFilter:
-		title = "Tail Filter"
+		title = i18n.T("Tail Filter")
 		content = m.modalInput.View() + "\n\n" + buttonOK + "  " + buttonCancel
 
+	case modalSearch:
+		title = i18n.T("Search Folder")
+		content = modalHintStyle.Render(m.searchHint()) + "\n\n" + m.modalInput.View() + "\n\n" + buttonOK + "  " + buttonCancel
+
+	case modalTrace:
+		title = i18n.T("Trace Request ID")
+		content = modalHintStyle.Render(m.traceHint()) + "\n\n" + m.modalInput.View() + "\n\n" + buttonOK + "  " + buttonCancel
+
+	case modalFileAction:
+		title = i18n.T("Truncate or Delete")
+		content = m.fileActionSummary()
+
+	case modalPatterns:
+		title = i18n.T("File Patterns of %s", filepath.Base(m.currentFolder.Path))
+		if m.patternsSave {
+			content = m.patternsSaveHint() + "\n\n" +
+				modalButtonStyle.Render(i18n.T("[y] Save")) + "  " + modalButtonStyle.Render(i18n.T("[n] Only this session"))
+			break
+		}
+		content = modalHintStyle.Render(i18n.T("Glob patterns separated by spaces or commas; empty lists every file")) +
+			"\n\n" + m.modalInput.View() + "\n\n" + m.patternsPreview() + "\n\n" + buttonOK + "  " + buttonCancel
+
 	case modalDownload:
 		switch m.downloadPhase {
 		case downloadPhaseInput:
-			title = "Download File"
-			content = modalHintStyle.Render("Download remote file to local machine") +
-				"\n\n" + modalHintStyle.Render("Local path:") + "\n" + m.modalInput.View() +
-				"\n\n" + modalHintStyle.Render("Filename:") + "\n" + m.modalInput2.View() +
+			title = i18n.T("Download File")
+			content = modalHintStyle.Render(i18n.T("Download remote file to local machine")) +
+				"\n\n" + modalHintStyle.Render(i18n.T("Local path:")) + "\n" + m.modalInput.View() +
+				"\n\n" + modalHintStyle.Render(i18n.T("Filename:")) + "\n" + m.modalInput2.View() +
 				"\n\n" + buttonOK + "  " + buttonTab + "  " + buttonCancel
 
+		case downloadPhaseChecking:
+			title = i18n.T("Download File")
+			content = modalHintStyle.Render(i18n.T("Checking the size of %s…", m.downloadFile.Name)) +
+				"\n\n" + buttonCancel
+
+		case downloadPhaseConfirm:
+			title = i18n.T("Large File")
+			valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
+			content = valueStyle.Render(i18n.T("%s is %s.", m.downloadFile.Name, ssh.FormatSize(m.downloadTotalBytes))) +
+				"\n" + modalHintStyle.Render(i18n.T("That is more than download_confirm_size (%s).",
+				ssh.FormatSize(int64(m.cfg.Defaults.DownloadConfirmSize)))) +
+				"\n\n" + modalHintStyle.Render(i18n.T("Download it anyway?")) +
+				"\n\n" + modalButtonStyle.Render(i18n.T("[Enter] Download")) + "  " + buttonCancel
+
 		case downloadPhaseProgress:
-			title = "Downloading..."
+			title = i18n.T("Downloading...")
 			fileName := filepath.Base(m.downloadLocalPath)
 			fileHint := modalHintStyle.Render(fileName)
 
@@ -1211,25 +3079,34 @@ func (m Model) renderModal(background string) string {
 				modalHintStyle.Render(counter) + "\n\n" + buttonCancel
 
 		case downloadPhaseDone:
-			title = "Download Complete"
+			title = i18n.T("Download Complete")
 			successStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Bold(true)
-			content = successStyle.Render("✓ Download complete") +
-				"\n\n" + modalHintStyle.Render("Saved to:") + "\n" +
+			content = successStyle.Render(i18n.T("✓ Download complete")) +
+				"\n\n" + modalHintStyle.Render(i18n.T("Saved to:")) + "\n" +
 				lipgloss.NewStyle().Foreground(lipgloss.Color("15")).Render(m.downloadLocalPath) +
-				"\n\n" + modalHintStyle.Render("Size: "+ssh.FormatSize(m.downloadBytesDownloaded)) +
+				"\n\n" + modalHintStyle.Render(i18n.T("Size: %s", ssh.FormatSize(m.downloadBytesDownloaded))) +
 				"\n\n" + buttonOK
 
 		case downloadPhaseError:
 			if strings.Contains(m.downloadError, "cancelled") {
-				title = "Download Cancelled"
+				title = i18n.T("Download Cancelled")
 			} else {
-				title = "Download Failed"
+				title = i18n.T("Download Failed")
 			}
 			errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true)
 			content = errStyle.Render(m.downloadError) + "\n\n" + buttonOK
 		}
 	}
 
+	if m.pendingPaste != "" {
+		// Oversized paste confirmation, shown over the filter or note prompt
+		title = i18n.T("Large paste")
+		content = modalHintStyle.Render(i18n.T("The clipboard holds %d characters on %d line(s):",
+			utf8.RuneCountInString(m.pendingPaste), m.pasteLines)) + "\n" +
+			pastePreview(m.pendingPaste, modalInnerWidth) + "\n\n" +
+			modalButtonStyle.Render(i18n.T("[Enter] Paste it")) + "  " + modalButtonStyle.Render(i18n.T("[Esc] Discard"))
+	}
+
 	modalBox := modalStyle.Width(70).Render(
 		modalTitleStyle.Render(title) + "\n\n" + content,
 	)
@@ -1318,6 +3195,38 @@ func renderProgressBar(width int, percent float64) string {
 	return bar + lipgloss.NewStyle().Foreground(lipgloss.Color("15")).Render(pctStr)
 }
 
+// serverLabel returns the current server's name, followed by the hostname the
+// remote side reports when it is known and differs from the configured one.
+func (m *Model) serverLabel() string {
+	if m.currentServer == nil {
+		return ""
+	}
+	srv := m.currentServer
+	hostname := m.pool.HostInfo(*srv).Hostname
+	if hostname == "" || strings.EqualFold(hostname, srv.Name) || strings.EqualFold(hostname, srv.Host) {
+		return srv.Name
+	}
+	return fmt.Sprintf("%s (%s)", srv.Name, hostname)
+}
+
+// updateTerminalTitle shows the current server and file in the terminal title.
+func (m *Model) updateTerminalTitle() {
+	if m.currentServer == nil {
+		setTerminalTitle("Log Monitor")
+		return
+	}
+	if m.currentFolder != nil && m.currentFile != nil {
+		fullPath := filepath.Join(m.currentFolder.Path, m.currentFile.Name)
+		setTerminalTitle(fmt.Sprintf("Log Monitor — %s:%s", m.serverLabel(), fullPath))
+		return
+	}
+	if m.currentFolder != nil && m.multiFiles != nil {
+		setTerminalTitle(fmt.Sprintf("Log Monitor — %s:%s: %s", m.serverLabel(), m.currentFolder.Path, m.multiLabel()))
+		return
+	}
+	setTerminalTitle(fmt.Sprintf("Log Monitor — %s", m.serverLabel()))
+}
+
 // setTerminalTitle sets the terminal window/tab title via OSC escape.
 func setTerminalTitle(title string) {
 	fmt.Fprintf(os.Stdout, "\033]0;%s\007", title)
@@ -1331,6 +3240,10 @@ func (m *Model) Shutdown() {
 		m.downloadCancel()
 	}
 	m.pool.CloseAll()
+	m.viewerPane.DropHistory()
+	if m.geo != nil {
+		m.geo.Close()
+	}
 	setTerminalTitle("")
 	logger.Log("app", "shutdown: done")
 }
@@ -1338,10 +3251,22 @@ func (m *Model) Shutdown() {
 // Run creates a tea.Program, runs it, and performs cleanup.
 func Run(cfg *config.Config, autoSelect AutoSelect) error {
 	m := NewModel(cfg, autoSelect)
-	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
+	opts := []tea.ProgramOption{tea.WithAltScreen(), tea.WithMouseCellMotion()}
+	if autoSelect.Stdin != nil {
+		// Keys come from the terminal, stdin being the log
+		opts = append(opts, tea.WithInputTTY())
+	}
+	p := tea.NewProgram(m, opts...)
 	finalModel, err := p.Run()
 	if fm, ok := finalModel.(Model); ok {
 		fm.Shutdown()
+		if err == nil && cfg.Defaults.ExitSummary {
+			names := make(map[string]string, len(fm.cfg.Servers))
+			for _, srv := range fm.cfg.Servers {
+				names[ssh.ServerKey(srv)] = srv.Name
+			}
+			fm.session.writeSummary(os.Stdout, fm.pool.Stats().Servers, names, time.Now())
+		}
 	}
 	return err
 }
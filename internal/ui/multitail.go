@@ -0,0 +1,178 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/i18n"
+	"log-monitor/internal/logger"
+	"log-monitor/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// tailPrefixer turns the output of `tail -F a b` into lines that start with
+// the name of their file in brackets, dropping tail's "==> a <==" headers.
+type tailPrefixer struct {
+	w       io.Writer
+	partial []byte
+	name    string
+	blank   bool // a blank line held back: tail prints one before each header
+}
+
+// tailHeader returns the path of a "==> path <==" header line.
+func tailHeader(line string) (string, bool) {
+	rest, ok := strings.CutPrefix(line, "==> ")
+	if !ok {
+		return "", false
+	}
+	return strings.CutSuffix(rest, " <==")
+}
+
+func (p *tailPrefixer) Write(b []byte) (int, error) {
+	p.partial = append(p.partial, b...)
+	var out strings.Builder
+	for {
+		i := strings.IndexByte(string(p.partial), '\n')
+		if i < 0 {
+			break
+		}
+		line := string(p.partial[:i])
+		p.partial = p.partial[i+1:]
+		if path, ok := tailHeader(line); ok {
+			p.name = filepath.Base(path)
+			p.blank = false
+			continue
+		}
+		if p.blank {
+			fmt.Fprintf(&out, "[%s] \n", p.name)
+			p.blank = false
+		}
+		if line == "" {
+			p.blank = true
+			continue
+		}
+		fmt.Fprintf(&out, "[%s] %s\n", p.name, line)
+	}
+	if out.Len() == 0 {
+		return len(b), nil
+	}
+	if _, err := io.WriteString(p.w, out.String()); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// toggleMark marks the file under the cursor to tail it with others, or
+// unmarks it.
+func (m Model) toggleMark() Model {
+	if m.kubectlRefused() || !m.filePane.ToggleMark() {
+		return m
+	}
+	marked := m.filePane.MarkedFiles()
+	if len(marked) == 0 {
+		m.contextMsg = m.lastContext
+		return m
+	}
+	names := make([]string, len(marked))
+	for i, f := range marked {
+		names[i] = f.Name
+	}
+	m.contextMsg = i18n.T("\033[33m%d marked:\033[0m %s — \033[90mEnter tails them together, Esc unmarks\033[0m", len(names), strings.Join(names, ", "))
+	return m
+}
+
+// multiLabel names the files tailed together, for titles.
+func (m Model) multiLabel() string {
+	return strings.Join(m.multiFiles, " + ")
+}
+
+// tailMarked tails the marked files of the folder together in the viewer,
+// each line prefixed with its file's name.
+func (m Model) tailMarked() (tea.Model, tea.Cmd) {
+	if m.currentServer == nil || m.currentFolder == nil {
+		return m, nil
+	}
+	var names, skipped []string
+	for _, f := range m.filePane.MarkedFiles() {
+		if isBinaryExtension(f.Name) || ssh.Decompressor(f.Name) != "" {
+			skipped = append(skipped, f.Name)
+			continue
+		}
+		names = append(names, f.Name)
+	}
+	switch len(names) {
+	case 0:
+		m.errorMsg = i18n.T("none of the marked files can be tailed")
+		return m, nil
+	case 1:
+		for i, f := range m.filePane.GetFiles() {
+			if f.Name == names[0] {
+				return m.onFileSelected(i, f)
+			}
+		}
+		return m, nil
+	}
+
+	m.stopTailInPlace()
+	m.currentFile = nil
+	m.atLine = 0
+	m.multiFiles = names
+	srv := *m.currentServer
+	dir := m.currentFolder.Path
+	logger.Log("app", "tailing %d files of %s:%s together", len(names), srv.Name, dir)
+
+	m.filePane.MarkSelected(-1)
+	m.focused = paneViewer
+	m.updateTerminalTitle()
+	m.viewerPane.Clear()
+	m.viewerPane.SetSources(names)
+	m.viewerPane.SetTitle(fmt.Sprintf(" %s ", m.multiLabel()))
+	m.setContext(fmt.Sprintf("\033[32m%s\033[0m %s: %s", srv.Name, dir, strings.Join(names, ", ")))
+	if len(skipped) > 0 {
+		m.errorMsg = i18n.T("not tailing compressed or binary %s", strings.Join(skipped, ", "))
+	}
+
+	ch := make(chan []byte, 64)
+	m.tailChan = ch
+	return m, startMultiTailCmd(m.pool, srv, dir, names, m.cfg.Defaults.TailLines, ch)
+}
+
+// restartMultiTail tails the files last tailed together again from their
+// last lines, keeping the active tail filter.
+func (m Model) restartMultiTail() (tea.Model, tea.Cmd) {
+	names := m.multiFiles
+	filter := m.viewerPane.GetTailFilter()
+	m.stopTailInPlace()
+	m.multiFiles = names
+	m.viewerPane.Clear()
+	m.viewerPane.SetSources(names)
+	m.viewerPane.SetTailFilter(filter)
+	m.viewerPane.SetTitle(fmt.Sprintf(" %s ", m.multiLabel()))
+	ch := make(chan []byte, 64)
+	m.tailChan = ch
+	return m, startMultiTailCmd(m.pool, *m.currentServer, m.currentFolder.Path, names, m.cfg.Defaults.TailLines, ch)
+}
+
+// startMultiTailCmd tails the named files of dir in one session, sending
+// their last lines and then new ones, prefixed with their names, to ch.
+func startMultiTailCmd(pool *ssh.Pool, srv config.ServerConfig, dir string, names []string, lines int, ch chan<- []byte) tea.Cmd {
+	return func() tea.Msg {
+		paths := make([]string, len(names))
+		for i, name := range names {
+			paths[i] = filepath.Join(dir, name)
+		}
+		w := &tailPrefixer{w: &chanWriter{ch: ch}}
+
+		tailer, cancel, err := startTailer(pool, srv, paths, lines, w)
+		if err != nil {
+			return TailErrorMsg{Err: err}
+		}
+		tailer.SetErrCallback(func(error) { close(ch) })
+
+		return TailStartedMsg{Tailer: tailer, Cancel: cancel}
+	}
+}
@@ -0,0 +1,31 @@
+package ui
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxPasteLen is the longest paste put into a prompt without asking first.
+const maxPasteLen = 200
+
+// cleanPaste turns pasted text into a single line for a one-line prompt:
+// every line is trimmed, blank lines are dropped and the rest are joined
+// with a space. It also returns the number of non-blank lines pasted.
+func cleanPaste(s string) (string, int) {
+	var parts []string
+	for _, line := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			parts = append(parts, line)
+		}
+	}
+	return strings.Join(parts, " "), len(parts)
+}
+
+// pastePreview shortens a long paste for display in the confirmation.
+func pastePreview(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:width-1]) + "…"
+}
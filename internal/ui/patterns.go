@@ -0,0 +1,228 @@
+package ui
+
+import (
+	"cmp"
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/i18n"
+	"log-monitor/internal/logger"
+	"log-monitor/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// maxPatternMatches is how many matching files the pattern editor names.
+const maxPatternMatches = 10
+
+// patternListMsg carries the current folder listed without its patterns,
+// for the pattern editor to match as the user types.
+type patternListMsg struct {
+	dir   string
+	files []ssh.FileInfo
+	err   error
+}
+
+// showPatternsPrompt opens the editor of the current folder's
+// file_patterns, listing the folder unfiltered to show what they match.
+func (m Model) showPatternsPrompt() (tea.Model, tea.Cmd) {
+	if m.currentServer == nil || m.currentFolder == nil || m.filePane.IsInFolderMode() {
+		m.errorMsg = i18n.T("open a folder to edit its file patterns")
+		return m, nil
+	}
+	if m.kubectlRefused() {
+		return m, nil
+	}
+	ti := styledInput()
+	ti.Placeholder = "*.log *.log.*"
+	ti.SetValue(strings.Join(m.currentFolder.FilePatterns, " "))
+	ti.Focus()
+
+	m.modal = modalPatterns
+	m.modalInput = ti
+	m.patternFiles = nil
+	m.patternErr = nil
+	m.patternsSave = false
+	folder := *m.currentFolder
+	folder.FilePatterns = nil
+	return m, listUnfilteredCmd(m.pool, *m.currentServer, folder)
+}
+
+// listUnfilteredCmd lists a folder, which is passed without patterns.
+func listUnfilteredCmd(pool *ssh.Pool, srv config.ServerConfig, folder config.LogFolder) tea.Cmd {
+	return func() tea.Msg {
+		var client *gossh.Client
+		opts := ssh.CommandOpts{}
+		if !srv.Local {
+			ctx, cancel := context.WithTimeout(context.Background(), srv.ConnectTimeout)
+			defer cancel()
+			var err error
+			if client, err = pool.GetClient(ctx, srv); err != nil {
+				return patternListMsg{dir: folder.Path, err: err}
+			}
+			opts = commandOpts(pool, srv)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), srv.CommandTimeout)
+		defer cancel()
+		files, err := listFolder(ctx, client, srv, folder, opts)
+		if _, rest := splitPartial(err); rest == nil {
+			err = nil
+		}
+		return patternListMsg{dir: folder.Path, files: files, err: err}
+	}
+}
+
+// onPatternList keeps the unfiltered listing while the pattern editor is
+// open on its folder.
+func (m Model) onPatternList(msg patternListMsg) (tea.Model, tea.Cmd) {
+	if m.modal != modalPatterns || m.currentFolder == nil || m.currentFolder.Path != msg.dir {
+		return m, nil
+	}
+	if msg.err != nil {
+		m.patternErr = msg.err
+		return m, nil
+	}
+	m.patternFiles = msg.files
+	if m.patternFiles == nil {
+		m.patternFiles = []ssh.FileInfo{}
+	}
+	return m, nil
+}
+
+// parsePatterns splits the editor's input into patterns, separated by
+// spaces or commas.
+func parsePatterns(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// badPattern returns the first pattern filepath.Match rejects, or "".
+func badPattern(patterns []string) string {
+	for _, p := range patterns {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// patternsPreview shows which files of the folder the typed patterns
+// match.
+func (m Model) patternsPreview() string {
+	failStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	patterns := parsePatterns(m.modalInput.Value())
+	if p := badPattern(patterns); p != "" {
+		return failStyle.Render(i18n.T("invalid pattern %s", p))
+	}
+	if m.patternErr != nil {
+		return failStyle.Render(m.patternErr.Error())
+	}
+	if m.patternFiles == nil {
+		return modalHintStyle.Render(i18n.T("Listing %s…", m.currentFolder.Path))
+	}
+
+	files := m.patternFiles
+	if len(patterns) > 0 {
+		files = ssh.FilterByPatterns(files, patterns)
+	}
+	var names []string
+	for _, f := range files {
+		if !f.IsDir {
+			names = append(names, f.Name)
+		}
+	}
+	total := 0
+	for _, f := range m.patternFiles {
+		if !f.IsDir {
+			total++
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(modalHintStyle.Render(i18n.T("%d of %d file(s) match", len(names), total)))
+	for i, name := range names {
+		if i == maxPatternMatches {
+			b.WriteString("\n" + modalHintStyle.Render(i18n.T("… and %d more", len(names)-maxPatternMatches)))
+			break
+		}
+		b.WriteString("\n  " + name)
+	}
+	return b.String()
+}
+
+// applyPatterns gives the current folder the typed patterns for this
+// session and lists it again, then offers to save them to the config file.
+func (m Model) applyPatterns() (tea.Model, tea.Cmd) {
+	patterns := parsePatterns(m.modalInput.Value())
+	if badPattern(patterns) != "" || m.currentServer == nil || m.currentFolder == nil {
+		return m, nil
+	}
+	if slices.Equal(patterns, m.currentFolder.FilePatterns) {
+		m.modal = modalNone
+		return m, nil
+	}
+
+	root := cmp.Or(m.currentFolder.Root, m.currentFolder.Path)
+	m.currentFolder.FilePatterns = patterns
+	setFolderPatterns(m.currentServer, root, patterns)
+	for i := range m.cfg.Servers {
+		if m.cfg.Servers[i].Name == m.currentServer.Name {
+			setFolderPatterns(&m.cfg.Servers[i], root, patterns)
+		}
+	}
+	logger.Log("app", "file patterns of %s on %s: %q", root, m.currentServer.Name, patterns)
+
+	m.modal = modalNone
+	if m.cfg.Path != "" && !m.currentServer.FromCatalog {
+		m.modal = modalPatterns
+		m.patternsSave = true
+	}
+	m.setContext(i18n.T("\033[33mRefreshing\033[0m %s...", m.currentServer.Name))
+	return m, connectAndListCmd(m.pool, *m.currentServer, *m.currentFolder)
+}
+
+// setFolderPatterns sets the file_patterns of a server's folder at root,
+// in a copy of its folders: the old ones may be shared with other copies
+// of the server.
+func setFolderPatterns(srv *config.ServerConfig, root string, patterns []string) {
+	i := srv.FolderIndex(root)
+	if i < 0 {
+		return
+	}
+	srv.LogFolders = slices.Clone(srv.LogFolders)
+	srv.LogFolders[i].FilePatterns = patterns
+}
+
+// savePatterns writes the current folder's patterns to the config file.
+func (m Model) savePatterns() Model {
+	m.modal = modalNone
+	m.patternsSave = false
+	if m.currentServer == nil || m.currentFolder == nil {
+		return m
+	}
+	root := cmp.Or(m.currentFolder.Root, m.currentFolder.Path)
+	if err := m.cfg.SaveFilePatterns(m.currentServer.Name, root, m.currentFolder.FilePatterns); err != nil {
+		logger.Log("app", "%v", err)
+		m.errorMsg = err.Error()
+		return m
+	}
+	m.setContext(i18n.T("\033[32mSaved\033[0m the file patterns of %s to %s", root, filepath.Base(m.cfg.Path)))
+	return m
+}
+
+// patternsSaveHint asks whether to save the applied patterns.
+func (m Model) patternsSaveHint() string {
+	patterns := strings.Join(m.currentFolder.FilePatterns, " ")
+	if patterns == "" {
+		patterns = i18n.T("(none, every file is listed)")
+	}
+	return modalHintStyle.Render(i18n.T("%s now lists %s for this session.", m.currentFolder.Path, patterns)) +
+		"\n\n" + modalHintStyle.Render(i18n.T("Save the file patterns to %s?", m.cfg.Path))
+}
@@ -0,0 +1,169 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/plugin"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// pluginPaneHeight is the height of a plugin pane below the viewer when
+// the config doesn't set one, borders included.
+const pluginPaneHeight = 10
+
+// pluginPane is a plugin's pane as placed in the layout. Plugin panes take
+// the focus after the viewer, in config order.
+type pluginPane struct {
+	plugin.Pane
+	overlay bool // drawn over the viewer while focused
+	height  int  // rows below the viewer, borders included
+}
+
+// newPluginPanes creates the panes of the configured plugins. Unknown
+// plugins are skipped and reported.
+func newPluginPanes(cfgs []config.PluginConfig) ([]pluginPane, error) {
+	var panes []pluginPane
+	var errs []error
+	for _, c := range cfgs {
+		p, err := plugin.New(c.Name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		height := c.Height
+		if height == 0 {
+			height = pluginPaneHeight
+		}
+		panes = append(panes, pluginPane{Pane: p, overlay: c.Overlay, height: height})
+	}
+	return panes, errors.Join(errs...)
+}
+
+// paneCount is the number of panes the focus cycles through.
+func (m Model) paneCount() int {
+	return int(panePlugin) + len(m.plugins)
+}
+
+// focusedPlugin returns the plugin pane with the focus, or nil.
+func (m Model) focusedPlugin() *pluginPane {
+	i := int(m.focused - panePlugin)
+	if i < 0 || i >= len(m.plugins) {
+		return nil
+	}
+	return &m.plugins[i]
+}
+
+// paneTitle names the focused pane, for the F1 overlay.
+func (m Model) paneTitle() string {
+	if p := m.focusedPlugin(); p != nil {
+		return p.Title()
+	}
+	return paneNames[m.focused]
+}
+
+// feedPlugins hands newly received log text to the plugin panes.
+func (m Model) feedPlugins(text []byte) {
+	if len(m.plugins) == 0 {
+		return
+	}
+	lines := strings.Split(strings.TrimSuffix(string(text), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = plainText(line)
+	}
+	for _, p := range m.plugins {
+		p.Feed(lines)
+	}
+}
+
+// resetPlugins clears the plugin panes for another file.
+func (m Model) resetPlugins() {
+	for _, p := range m.plugins {
+		p.Reset()
+	}
+}
+
+// stackedPlugins returns the indexes of the plugin panes shown below the
+// viewer: those that aren't overlays, as far as the viewer keeps
+// minViewerHeight rows.
+func (m Model) stackedPlugins() []int {
+	room := m.height - 1 - minViewerHeight
+	if m.chartShown() {
+		room -= chartPaneHeight
+	}
+	var shown []int
+	for i, p := range m.plugins {
+		if p.overlay || p.height > room {
+			continue
+		}
+		room -= p.height
+		shown = append(shown, i)
+	}
+	return shown
+}
+
+// stackedHeight is the number of rows the plugin panes below the viewer
+// take.
+func (m Model) stackedHeight() int {
+	h := 0
+	for _, i := range m.stackedPlugins() {
+		h += m.plugins[i].height
+	}
+	return h
+}
+
+// pluginAt returns the plugin pane below the viewer at screen row y.
+func (m Model) pluginAt(y int) (pane, bool) {
+	top := m.viewerPane.height
+	if m.chartShown() {
+		top += chartPaneHeight
+	}
+	for _, i := range m.stackedPlugins() {
+		if y >= top && y < top+m.plugins[i].height {
+			return panePlugin + pane(i), true
+		}
+		top += m.plugins[i].height
+	}
+	return 0, false
+}
+
+// viewerColumn renders the right-hand column: the viewer, the chart and
+// the plugin panes below them, or the focused plugin pane over all of it
+// when it is an overlay or doesn't fit below.
+func (m Model) viewerColumn(viewerView string) string {
+	width := m.viewerPane.width
+	stacked := m.stackedPlugins()
+	if p := m.focusedPlugin(); p != nil {
+		i := int(m.focused - panePlugin)
+		below := false
+		for _, s := range stacked {
+			below = below || s == i
+		}
+		if !below {
+			return renderPluginPane(*p, width, max(m.height-1, 3), true)
+		}
+	}
+	parts := []string{viewerView}
+	if m.chartShown() {
+		parts = append(parts, m.chartPane.View(m.metrics.Series(m.chartPane.Metric()), m.focused == paneViewer))
+	}
+	for _, i := range stacked {
+		parts = append(parts, renderPluginPane(m.plugins[i], width, m.plugins[i].height, m.focused == panePlugin+pane(i)))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, parts...)
+}
+
+// renderPluginPane draws a plugin pane with a border and its title.
+func renderPluginPane(p pluginPane, width, height int, focused bool) string {
+	paneStyle, titleStyle := unfocusedPaneStyle, unfocusedTitleStyle
+	if focused {
+		paneStyle, titleStyle = focusedPaneStyle, focusedTitleStyle
+	}
+	innerW, innerH := max(width-2, 1), max(height-2, 1)
+	body := lipgloss.NewStyle().MaxWidth(innerW).MaxHeight(innerH).Render(p.View(innerW, innerH))
+	box := paneStyle.Width(innerW).Height(innerH).Render(body)
+	return placeTitleInBorder(box, titleStyle.Render(fmt.Sprintf(" %s ", p.Title())))
+}
@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/i18n"
+	"log-monitor/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// lookupFileProcesses asks the server which processes have the targeted
+// file open: the one under the cursor in the file pane, otherwise the open
+// one.
+func (m Model) lookupFileProcesses() (tea.Model, tea.Cmd) {
+	if m.kubectlRefused() {
+		return m, nil
+	}
+	_, path, ok := m.noteTarget()
+	if !ok {
+		m.errorMsg = i18n.T("select a file first")
+		return m, nil
+	}
+	m.contextMsg = i18n.T("\033[36mLooking for processes using\033[0m %s…", path)
+	return m, fileProcessesCmd(m.pool, *m.currentServer, path)
+}
+
+// fileProcessesCmd runs lsof (or fuser) on a remote file.
+func fileProcessesCmd(pool *ssh.Pool, srv config.ServerConfig, path string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), srv.ConnectTimeout)
+		defer cancel()
+
+		client, err := pool.GetClient(ctx, srv)
+		if err != nil {
+			return FileProcessesMsg{Path: path, Err: err}
+		}
+		cmdCtx, cmdCancel := context.WithTimeout(context.Background(), srv.CommandTimeout)
+		defer cmdCancel()
+
+		procs, err := ssh.FileProcesses(cmdCtx, client, path, commandOpts(pool, srv))
+		return FileProcessesMsg{Path: path, Procs: procs, Sudo: srv.Sudo, Err: err}
+	}
+}
+
+// procsSummary renders the processes using a file for their popup, writers
+// first.
+func (m Model) procsSummary() string {
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
+	writeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+
+	var b strings.Builder
+	b.WriteString(modalHintStyle.Render(m.procs.Path))
+	if len(m.procs.Procs) == 0 {
+		b.WriteString("\n\n" + valueStyle.Render(i18n.T("No process has the file open.")))
+	} else {
+		b.WriteString("\n\n" + modalHintStyle.Render(fmt.Sprintf("%-8s %-12s %-16s %s", "PID", "USER", "COMMAND", "ACCESS")))
+		for _, writing := range []bool{true, false} {
+			for _, p := range m.procs.Procs {
+				if p.Writing != writing {
+					continue
+				}
+				access := i18n.T("reading")
+				if p.Writing {
+					access = writeStyle.Render(i18n.T("writing"))
+				}
+				row := fmt.Sprintf("%-8d %-12s %-16s ", p.PID, truncateString(p.User, 12), truncateString(p.Command, 16))
+				b.WriteString("\n" + valueStyle.Render(row) + access)
+			}
+		}
+	}
+	if !m.procs.Sudo {
+		b.WriteString("\n\n" + modalHintStyle.Render(i18n.T("Without sudo, other users' processes may be missing.")))
+	}
+	return b.String()
+}
@@ -0,0 +1,263 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/i18n"
+	"log-monitor/internal/logger"
+	"log-monitor/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxSearchHits caps the hits of a folder search, for queries that match
+// nearly every line.
+const maxSearchHits = 1000
+
+// folderSearch is a grep over the listed files of a folder, whose hits the
+// viewer shows as "file:line:text".
+type folderSearch struct {
+	server config.ServerConfig
+	dir    string
+	query  string
+	files  []string    // names searched, relative to dir
+	hits   []searchHit // by viewer line number, from 1
+	hitsCh chan []byte // hits as they are found, closed when the search ends
+	err    error       // why the search failed, set before hitsCh closes
+}
+
+// searchHit is where a hit shown in the viewer was found. The viewer's
+// text can't tell, as file names may hold colons.
+type searchHit struct {
+	name string
+	line int
+}
+
+// searchHitsMsg carries hits of a running search.
+type searchHitsMsg struct {
+	search *folderSearch
+	data   []byte
+}
+
+// searchDoneMsg signals that a search has ended.
+type searchDoneMsg struct {
+	search *folderSearch
+}
+
+// searchableFiles returns the names of the listed files grep can read,
+// compressed logs included.
+func searchableFiles(files []ssh.FileInfo) []string {
+	var names []string
+	for _, f := range files {
+		if !f.IsDir && !isBinaryExtension(f.Name) {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+// showSearchPrompt asks for the expression to search the current folder for.
+func (m Model) showSearchPrompt() Model {
+	if m.currentServer == nil || m.currentFolder == nil || m.filePane.IsInFolderMode() {
+		m.errorMsg = i18n.T("open a folder to search it")
+		return m
+	}
+	if m.kubectlRefused() {
+		return m
+	}
+	ti := styledInput()
+	ti.Placeholder = "Regular expression"
+	ti.SetValue(m.lastSearch)
+	ti.Focus()
+
+	m.modal = modalSearch
+	m.modalInput = ti
+	return m
+}
+
+// searchHint describes what the search prompt will search.
+func (m Model) searchHint() string {
+	n := len(searchableFiles(m.filePane.GetFiles()))
+	return i18n.T("grep -E over the %d listed file(s) of %s, compressed ones included", n, m.currentFolder.Path)
+}
+
+// startSearch greps the listed files of the current folder for query on
+// the server and streams the hits into the viewer.
+func (m Model) startSearch(query string) (tea.Model, tea.Cmd) {
+	if query == "" || m.currentServer == nil || m.currentFolder == nil {
+		return m, nil
+	}
+	files := searchableFiles(m.filePane.GetFiles())
+	if len(files) == 0 {
+		m.errorMsg = i18n.T("no files to search in %s", m.currentFolder.Path)
+		return m, nil
+	}
+	m.stopTailInPlace()
+	m.currentFile = nil
+	m.multiFiles = nil
+	m.lastSearch = query
+	s := &folderSearch{server: *m.currentServer, dir: m.currentFolder.Path, query: query, files: files,
+		hitsCh: make(chan []byte, 64)}
+	m.search = s
+	ctx, cancel := context.WithCancel(context.Background())
+	m.searchCancel = cancel
+	logger.Log("app", "searching %s:%s for %q in %d files", s.server.Name, s.dir, query, len(files))
+
+	m.viewerPane.Clear()
+	m.viewerPane.StartSpinner(i18n.T("Search: %s", query))
+	m.focused = paneViewer
+	m.updateTerminalTitle()
+	m.setContext(i18n.T("\033[33mSearching\033[0m %s:%s for %s…", s.server.Name, s.dir, query))
+
+	cmds := []tea.Cmd{searchCmd(ctx, m.pool, s), waitForSearchHits(s)}
+	if !m.spinnerTicking {
+		m.spinnerTicking = true
+		cmds = append(cmds, spinnerTickCmd())
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// stopSearch cancels the running search, if any, and forgets its hits.
+func (m *Model) stopSearch() {
+	if m.searchCancel != nil {
+		m.searchCancel()
+		m.searchCancel = nil
+	}
+	m.search = nil
+}
+
+// onSearchHits shows hits as they arrive.
+func (m Model) onSearchHits(msg searchHitsMsg) (tea.Model, tea.Cmd) {
+	if msg.search != m.search {
+		// Drain a search given up on until it has stopped
+		return m, waitForSearchHits(msg.search)
+	}
+	var shown bytes.Buffer
+	for _, hit := range strings.SplitAfter(string(msg.data), "\n") {
+		if hit == "" {
+			continue
+		}
+		name, line, _, _ := ssh.ParseGrepHit(hit)
+		m.search.hits = append(m.search.hits, searchHit{name: name, line: line})
+		shown.WriteString(strings.Replace(hit, "\x00", ":", 1))
+	}
+	m.viewerPane.AppendTailData(shown.Bytes())
+	return m, waitForSearchHits(msg.search)
+}
+
+// onSearchDone reports how a search went.
+func (m Model) onSearchDone(msg searchDoneMsg) (tea.Model, tea.Cmd) {
+	s := msg.search
+	if s != m.search {
+		return m, nil
+	}
+	m.searchCancel = nil
+	m.viewerPane.StopSpinner()
+	m.viewerPane.SetTitle(i18n.T(" Search: %s ", s.query))
+	if s.err != nil {
+		m.errorMsg = i18n.T("search: %v", s.err)
+	}
+	limited := ""
+	if len(s.hits) >= maxSearchHits {
+		limited = i18n.T(" (first %d)", maxSearchHits)
+	}
+	m.setContext(i18n.T("\033[32m%d hit(s)\033[0m%s for %s in %s:%s — \033[90m↑/↓ and Enter open a hit\033[0m",
+		len(s.hits), limited, s.query, s.server.Name, s.dir))
+	return m, nil
+}
+
+// openSearchHit opens the file of the hit under the viewer cursor at its line.
+func (m Model) openSearchHit() (tea.Model, tea.Cmd) {
+	_, num, ok := m.viewerPane.CursorLine()
+	if !ok {
+		m.errorMsg = i18n.T("put the cursor on a hit with ↑/↓ or a click")
+		return m, nil
+	}
+	if num < 1 || num > len(m.search.hits) || m.search.hits[num-1].name == "" {
+		return m, nil
+	}
+	hit := m.search.hits[num-1]
+	for i, f := range m.filePane.GetFiles() {
+		if f.Name == hit.name {
+			return m.openAtLine(i, f, hit.line)
+		}
+	}
+	m.errorMsg = i18n.T("%s is no longer listed", hit.name)
+	return m, nil
+}
+
+// openAtLine shows the lines of a file around line, with the cursor on it,
+// without tailing the file.
+func (m Model) openAtLine(idx int, file ssh.FileInfo, line int) (tea.Model, tea.Cmd) {
+	m.stopTailInPlace()
+	m.currentFile = &file
+	m.multiFiles = nil
+	m.atLine = line
+	srv := *m.currentServer
+	fullPath := filepath.Join(m.currentFolder.Path, file.Name)
+
+	m.filePane.MarkSelected(idx)
+	m.filePane.SetFileCursor(idx)
+	m.setContext(i18n.T("\033[32m%s\033[0m %s \033[33m[line %d]\033[0m — \033[90mCtrl-R to tail it\033[0m", srv.Name, fullPath, line))
+	m.updateTerminalTitle()
+	m.viewerPane.Clear()
+	m.viewerPane.SetTitle(fmt.Sprintf(" %s:%d ", file.Name, line))
+
+	from := max(line-m.cfg.Defaults.TailLines/2, 1)
+	return m, readLinesCmd(m.pool, srv, fullPath, from, from+m.cfg.Defaults.TailLines-1, line)
+}
+
+// searchCmd runs a search, sending its hits to s.hitsCh.
+func searchCmd(ctx context.Context, pool *ssh.Pool, s *folderSearch) tea.Cmd {
+	return func() tea.Msg {
+		defer close(s.hitsCh)
+		connCtx, cancel := context.WithTimeout(ctx, s.server.ConnectTimeout)
+		defer cancel()
+		client, err := pool.GetClient(connCtx, s.server)
+		if err != nil {
+			s.err = err
+			return nil
+		}
+		release := pool.Hold(s.server)
+		defer release()
+		w := &chanWriter{ch: s.hitsCh}
+		if err := ssh.Grep(ctx, client, s.dir, s.files, s.query, maxSearchHits, w, commandOpts(pool, s.server)); err != nil && ctx.Err() == nil {
+			s.err = err
+		}
+		return nil
+	}
+}
+
+// waitForSearchHits waits for the next hits of a search, or its end.
+func waitForSearchHits(s *folderSearch) tea.Cmd {
+	return func() tea.Msg {
+		data, ok := <-s.hitsCh
+		if !ok {
+			return searchDoneMsg{search: s}
+		}
+		return searchHitsMsg{search: s, data: data}
+	}
+}
+
+// readLinesCmd reads lines from through to of a file, to show them with the
+// cursor on line.
+func readLinesCmd(pool *ssh.Pool, srv config.ServerConfig, fullPath string, from, to, line int) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), srv.ConnectTimeout+srv.CommandTimeout)
+		defer cancel()
+		client, err := pool.GetClient(ctx, srv)
+		if err != nil {
+			return FileReadErrorMsg{Err: err}
+		}
+		content, err := ssh.ReadFileLines(ctx, client, fullPath, from, to, commandOpts(pool, srv))
+		if err != nil {
+			return FileReadErrorMsg{Err: err}
+		}
+		return FileContentMsg{Content: content, StartLine: from, Cursor: line}
+	}
+}
@@ -3,9 +3,11 @@ package ui
 import (
 	"fmt"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"log-monitor/internal/config"
+	"log-monitor/internal/ssh"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -21,6 +23,13 @@ type ServerPaneModel struct {
 	// Fuzzy filter
 	filterQuery    string
 	filteredIdxMap []int // maps display index -> original server index
+
+	states  map[string]ssh.ConnState // connection state by ssh.ServerKey
+	latency map[string]time.Duration // keepalive round trip by ssh.ServerKey
+
+	// Startup connections in progress, shown in the header
+	connectDone  int
+	connectTotal int
 }
 
 // NewServerPaneModel creates a new server pane model.
@@ -28,6 +37,8 @@ func NewServerPaneModel(servers []config.ServerConfig) ServerPaneModel {
 	sp := ServerPaneModel{
 		servers:     servers,
 		selectedIdx: -1,
+		states:      make(map[string]ssh.ConnState),
+		latency:     make(map[string]time.Duration),
 	}
 	sp.rebuildFilter()
 	return sp
@@ -160,11 +171,66 @@ func (sp *ServerPaneModel) SelectedServer() (int, *config.ServerConfig) {
 	return origIdx, &sp.servers[origIdx]
 }
 
+// SetServers replaces the server list, keeping the active marker on the
+// server named selected if it is still present.
+func (sp *ServerPaneModel) SetServers(servers []config.ServerConfig, selected string) {
+	sp.servers = servers
+	sp.selectedIdx = -1
+	for i, s := range servers {
+		if s.Name == selected {
+			sp.selectedIdx = i
+			break
+		}
+	}
+	sp.rebuildFilter()
+}
+
+// SetConnState records the connection state shown next to the servers
+// with the given pool key.
+func (sp *ServerPaneModel) SetConnState(key string, state ssh.ConnState) {
+	sp.states[key] = state
+	if state != ssh.StateConnected {
+		delete(sp.latency, key)
+	}
+}
+
+// SetLatency records the round trip time shown next to the servers with
+// the given pool key.
+func (sp *ServerPaneModel) SetLatency(key string, rtt time.Duration) {
+	sp.latency[key] = rtt
+}
+
+// ConnState returns the connection state of a server.
+func (sp *ServerPaneModel) ConnState(srv config.ServerConfig) ssh.ConnState {
+	return sp.states[ssh.ServerKey(srv)]
+}
+
+// connGlyph returns the state glyph for a server and its color.
+func (sp *ServerPaneModel) connGlyph(srv config.ServerConfig) (string, lipgloss.Color) {
+	switch sp.states[ssh.ServerKey(srv)] {
+	case ssh.StateConnecting:
+		return "◌", warnColor
+	case ssh.StateConnected:
+		return "●", infoColor
+	case ssh.StateError:
+		return "✖", errorColor
+	default:
+		return "○", lipgloss.Color("8")
+	}
+}
+
 // MarkSelected sets the "active" server marker.
 func (sp *ServerPaneModel) MarkSelected(idx int) {
 	sp.selectedIdx = idx
 }
 
+// SetConnectProgress shows how many of the startup connections have
+// finished. A total of 0 hides it.
+func (sp *ServerPaneModel) SetConnectProgress(done, total int) {
+	sp.connectDone = done
+	sp.connectTotal = total
+}
+
 // SetSize updates the available dimensions.
 func (sp *ServerPaneModel) SetSize(w, h int) {
 	sp.width = w
@@ -190,6 +256,9 @@ func (sp *ServerPaneModel) View(focused bool) string {
 	// Header
 	if sp.filterQuery != "" {
 		b.WriteString(lipgloss.NewStyle().Foreground(headerColor).Render(fmt.Sprintf("Filter: %s", sp.filterQuery)))
+	} else if sp.connectTotal > 0 {
+		b.WriteString(lipgloss.NewStyle().Foreground(headerColor).Render(
+			fmt.Sprintf("Connecting %d/%d…", sp.connectDone, sp.connectTotal)))
 	} else {
 		b.WriteString(lipgloss.NewStyle().Foreground(headerColor).Render("Please select"))
 	}
@@ -219,24 +288,40 @@ func (sp *ServerPaneModel) View(focused bool) string {
 	for di := startIdx; di < endIdx; di++ {
 		origIdx := sp.filteredIdxMap[di]
 		name := sp.servers[origIdx].Name
+		glyph, glyphColor := sp.connGlyph(sp.servers[origIdx])
+
+		// The round trip goes at the right edge when there is room
+		lat := ""
+		if rtt, ok := sp.latency[ssh.ServerKey(sp.servers[origIdx])]; ok && lineWidth >= 16 {
+			lat = formatLatency(rtt)
+		}
+		withLatency := func(display string, width int, style lipgloss.Style) string {
+			if lat == "" {
+				return truncateString(display, width)
+			}
+			nameW := width - len(lat) - 1
+			return padRight(truncateString(display, nameW), nameW+1) + style.Render(lat)
+		}
 
 		if di == sp.cursor {
 			// Cursor row — full-width highlight
-			display := name
+			display := glyph + " " + name
 			if origIdx == sp.selectedIdx {
 				display = "› " + display
 			}
-			display = truncateString(display, lineWidth)
+			display = withLatency(display, lineWidth, lipgloss.NewStyle())
 			display = padRight(display, lineWidth)
 			b.WriteString(selectedRowStyle.Render(display))
 		} else if origIdx == sp.selectedIdx {
 			// Active server (not cursor) — blue marker
 			marker := activeMarkerStyle.Render("› ")
-			display := truncateString(name, lineWidth-2)
-			b.WriteString(marker + display)
+			state := lipgloss.NewStyle().Foreground(glyphColor).Render(glyph)
+			display := withLatency(name, lineWidth-4, dimStyle)
+			b.WriteString(marker + state + " " + display)
 		} else {
-			display := truncateString(name, lineWidth)
-			b.WriteString(display)
+			state := lipgloss.NewStyle().Foreground(glyphColor).Render(glyph)
+			display := withLatency(name, lineWidth-2, dimStyle)
+			b.WriteString(state + " " + display)
 		}
 		if di < endIdx-1 {
 			b.WriteByte('\n')
@@ -249,6 +334,19 @@ func (sp *ServerPaneModel) View(focused bool) string {
 	return placeTitleInBorder(content, title)
 }
 
+// formatLatency renders a round trip time compactly, e.g. "4.2ms",
+// "38ms" or "1.2s".
+func formatLatency(d time.Duration) string {
+	switch {
+	case d < 10*time.Millisecond:
+		return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+	case d < time.Second:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	default:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+}
+
 // placeTitleInBorder overlays a title string onto the top border of a bordered box.
 func placeTitleInBorder(box, title string) string {
 	lines := strings.Split(box, "\n")
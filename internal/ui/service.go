@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"context"
+	"strings"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/i18n"
+	"log-monitor/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// serviceJournalLines is how many journal lines the service popup fetches;
+// it shows the last ones that fit.
+const serviceJournalLines = 200
+
+// showServiceStatus asks the server about the systemd unit of the current
+// folder: its status, or its journal when journal is set.
+func (m Model) showServiceStatus(journal bool) (tea.Model, tea.Cmd) {
+	if m.currentServer == nil || m.currentFolder == nil {
+		m.errorMsg = i18n.T("select a folder first")
+		return m, nil
+	}
+	unit := m.currentFolder.Unit
+	if unit == "" {
+		m.errorMsg = i18n.T("no systemd unit set for this folder (unit in log_folders)")
+		return m, nil
+	}
+	m.contextMsg = i18n.T("\033[36mAsking systemd about\033[0m %s…", unit)
+	return m, serviceCmd(m.pool, *m.currentServer, unit, journal)
+}
+
+// serviceCmd runs systemctl status, or journalctl, for a unit.
+func serviceCmd(pool *ssh.Pool, srv config.ServerConfig, unit string, journal bool) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), srv.ConnectTimeout)
+		defer cancel()
+
+		client, err := pool.GetClient(ctx, srv)
+		if err != nil {
+			return ServiceStatusMsg{Unit: unit, Journal: journal, Err: err}
+		}
+		cmdCtx, cmdCancel := context.WithTimeout(context.Background(), srv.CommandTimeout)
+		defer cmdCancel()
+
+		opts := commandOpts(pool, srv)
+		var out string
+		if journal {
+			out, err = ssh.ServiceJournal(cmdCtx, client, unit, serviceJournalLines, opts)
+		} else {
+			out, err = ssh.ServiceStatus(cmdCtx, client, unit, opts)
+		}
+		return ServiceStatusMsg{Unit: unit, Output: out, Journal: journal, Err: err}
+	}
+}
+
+// serviceSummary renders the systemd output for the popup, as many lines as
+// fit: the top of the status, the end of the journal.
+func (m Model) serviceSummary() string {
+	lines := strings.Split(strings.TrimRight(m.service.Output, "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return modalHintStyle.Render(i18n.T("No output."))
+	}
+	rows := max(m.height-12, 5)
+	switch {
+	case len(lines) <= rows:
+	case m.service.Journal:
+		lines = lines[len(lines)-rows:]
+	default:
+		lines = lines[:rows]
+	}
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
+	for i, line := range lines {
+		lines[i] = valueStyle.Render(truncateString(sanitizeLine(plainText(line), 8, "strip"), modalInnerWidth))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"log-monitor/internal/logger"
+)
+
+// spillPage is how many lines are paged back in at a time when scrolling
+// above the lines held in memory.
+const spillPage = 1000
+
+// spillRecord is a viewer line as kept on disk. Its decorated content is
+// made again when it is paged back in.
+type spillRecord struct {
+	Num    int       `json:"n"`
+	Raw    string    `json:"r,omitempty"`
+	Marker string    `json:"m,omitempty"`
+	Event  bool      `json:"e,omitempty"`
+	At     time.Time `json:"t,omitzero"`
+}
+
+// historySpill keeps the viewer lines beyond maxViewerLines in a temporary
+// file, oldest first, as a stack: lines dropped from the top of the viewer
+// are pushed, and scrolling above the top pops them back.
+type historySpill struct {
+	f       *os.File
+	offsets []int64 // file offset of each line, in order
+	size    int64
+}
+
+// newHistorySpill creates the temporary file of a spill, readable only by
+// the user since it holds log lines.
+func newHistorySpill() (*historySpill, error) {
+	f, err := os.CreateTemp("", "log-monitor-history-*.jsonl")
+	if err != nil {
+		return nil, fmt.Errorf("creating history spill: %w", err)
+	}
+	logger.Log("viewer", "spilling history to %s", f.Name())
+	return &historySpill{f: f}, nil
+}
+
+// Len returns the number of lines on disk.
+func (s *historySpill) Len() int {
+	return len(s.offsets)
+}
+
+// push appends lines, which come after those already on disk.
+func (s *historySpill) push(lines []viewerLine) error {
+	var buf bytes.Buffer
+	offsets := make([]int64, 0, len(lines))
+	for _, l := range lines {
+		offsets = append(offsets, s.size+int64(buf.Len()))
+		rec, err := json.Marshal(spillRecord{Num: l.num, Raw: l.raw, Marker: l.marker, Event: l.event, At: l.at})
+		if err != nil {
+			return fmt.Errorf("encoding line: %w", err)
+		}
+		buf.Write(rec)
+		buf.WriteByte('\n')
+	}
+	if _, err := s.f.WriteAt(buf.Bytes(), s.size); err != nil {
+		return fmt.Errorf("writing history spill: %w", err)
+	}
+	s.offsets = append(s.offsets, offsets...)
+	s.size += int64(buf.Len())
+	return nil
+}
+
+// pop removes the last n lines from disk and returns them in order.
+func (s *historySpill) pop(n int) ([]spillRecord, error) {
+	n = min(n, len(s.offsets))
+	if n == 0 {
+		return nil, nil
+	}
+	start := s.offsets[len(s.offsets)-n]
+	buf := make([]byte, s.size-start)
+	if _, err := s.f.ReadAt(buf, start); err != nil {
+		return nil, fmt.Errorf("reading history spill: %w", err)
+	}
+	recs := make([]spillRecord, 0, n)
+	for _, line := range bytes.SplitAfter(buf, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var rec spillRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, fmt.Errorf("decoding history spill: %w", err)
+		}
+		recs = append(recs, rec)
+	}
+	if err := s.f.Truncate(start); err != nil {
+		return nil, fmt.Errorf("truncating history spill: %w", err)
+	}
+	s.offsets = s.offsets[:len(s.offsets)-n]
+	s.size = start
+	return recs, nil
+}
+
+// each calls fn with every line on disk, in order.
+func (s *historySpill) each(fn func(spillRecord)) error {
+	sc := bufio.NewScanner(io.NewSectionReader(s.f, 0, s.size))
+	sc.Buffer(nil, 16<<20)
+	for sc.Scan() {
+		var rec spillRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return fmt.Errorf("decoding history spill: %w", err)
+		}
+		fn(rec)
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("reading history spill: %w", err)
+	}
+	return nil
+}
+
+// close removes the temporary file.
+func (s *historySpill) close() {
+	s.f.Close()
+	os.Remove(s.f.Name())
+}
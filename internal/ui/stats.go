@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"log-monitor/internal/i18n"
+	"log-monitor/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// showConnectionStats opens the popup with the pool's per-server counters.
+func (m Model) showConnectionStats() (tea.Model, tea.Cmd) {
+	m.modal = modalStats
+	return m, nil
+}
+
+// statsSummary renders the pool's counters for their popup, one row per
+// server dialed so far. It is built on every render, so the numbers move
+// while the popup is open.
+func (m Model) statsSummary() string {
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
+	failStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+
+	names := make(map[string]string, len(m.cfg.Servers))
+	for _, srv := range m.cfg.Servers {
+		names[ssh.ServerKey(srv)] = srv.Name
+	}
+
+	stats := m.pool.Stats()
+	var b strings.Builder
+	b.WriteString(modalHintStyle.Render(i18n.T("%d connection(s) open, %d busy", stats.Open, stats.Busy)))
+	if len(stats.Servers) == 0 {
+		b.WriteString("\n\n" + valueStyle.Render(i18n.T("No server dialed yet.")))
+		return b.String()
+	}
+	b.WriteString("\n\n" + modalHintStyle.Render(fmt.Sprintf("%-16s %5s %5s %9s %9s %4s %7s",
+		"SERVER", "DIALS", "FAIL", "SENT", "RECEIVED", "SESS", "RTT")))
+	for _, s := range stats.Servers {
+		name, ok := names[s.Key]
+		if !ok {
+			name = s.Key // a jump host
+		}
+		if !s.Connected {
+			name = "-" + name
+		}
+		rtt := "-"
+		if s.Connected && s.Latency > 0 {
+			rtt = formatLatency(s.Latency)
+		}
+		row := fmt.Sprintf("%-16s %5d ", truncateString(name, 16), s.Dials)
+		fail := fmt.Sprintf("%5d", s.Failures)
+		if s.Failures > 0 {
+			fail = failStyle.Render(fail)
+		}
+		rest := fmt.Sprintf(" %9s %9s %4d %7s", ssh.FormatSize(s.BytesSent), ssh.FormatSize(s.BytesReceived), s.Sessions, rtt)
+		b.WriteString("\n" + valueStyle.Render(row) + fail + valueStyle.Render(rest))
+	}
+	b.WriteString("\n\n" + modalHintStyle.Render(i18n.T("A leading - marks a server that isn't connected now. Traffic through a jump host counts for it too.")))
+	return b.String()
+}
@@ -2,16 +2,45 @@ package ui
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
+	"log-monitor/internal/i18n"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
+// statusFieldRe matches a placeholder of defaults.status_template.
+var statusFieldRe = regexp.MustCompile(`%([a-z]+)%`)
+
+// expandStatus fills in the placeholders of a status template. Segments
+// separated by " | " that come out empty are dropped along with their
+// separator, so unset fields don't leave gaps.
+func expandStatus(tmpl string, fields map[string]string) string {
+	var segments []string
+	for _, seg := range strings.Split(tmpl, " | ") {
+		hasField, filled := false, false
+		seg = statusFieldRe.ReplaceAllStringFunc(seg, func(p string) string {
+			hasField = true
+			v := fields[strings.Trim(p, "%")]
+			filled = filled || v != ""
+			return v
+		})
+		if hasField && !filled {
+			continue
+		}
+		if seg = strings.TrimSpace(seg); seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	return strings.Join(segments, " | ")
+}
+
 // renderStatusBar renders the status bar with context on the left and shortcuts on the right.
 func renderStatusBar(width int, contextMsg, errorMsg, shortcuts string) string {
 	left := ""
 	if errorMsg != "" {
-		left = lipgloss.NewStyle().Foreground(errorColor).Render("Error: ") + errorMsg
+		left = lipgloss.NewStyle().Foreground(errorColor).Render(i18n.T("Error: ")) + errorMsg
 	} else if contextMsg != "" {
 		left = contextMsg
 	}
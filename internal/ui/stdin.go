@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"log-monitor/internal/i18n"
+	"log-monitor/internal/logger"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// stdinSource is standard input shown in the viewer with -stdin, like a
+// tailed file. It keeps being read while a remote file is open.
+type stdinSource struct {
+	r       io.Reader
+	ch      chan []byte
+	backlog []string // last lines read, to filter them again
+	lines   int      // lines read
+	closed  bool     // the input ended
+	err     error    // why reading failed, set before ch closes
+}
+
+// stdinDataMsg carries lines read from standard input.
+type stdinDataMsg struct {
+	data []byte
+}
+
+// stdinClosedMsg signals that standard input has ended.
+type stdinClosedMsg struct{}
+
+func newStdinSource(r io.Reader) *stdinSource {
+	return &stdinSource{r: r, ch: make(chan []byte, 64)}
+}
+
+// keep adds lines to the backlog, dropping the oldest beyond what the
+// viewer holds.
+func (s *stdinSource) keep(data []byte) {
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	s.lines += len(lines)
+	s.backlog = append(s.backlog, lines...)
+	if over := len(s.backlog) - maxViewerLines; over > 0 {
+		s.backlog = append([]string(nil), s.backlog[over:]...)
+	}
+}
+
+// readStdinCmd copies standard input to the source's channel in lines and
+// closes it at the end of the input.
+func readStdinCmd(s *stdinSource) tea.Cmd {
+	return func() tea.Msg {
+		w := &chanWriter{ch: s.ch}
+		if _, err := io.Copy(w, s.r); err != nil {
+			s.err = fmt.Errorf("reading stdin: %w", err)
+		}
+		// A last line without its newline
+		if w.buf.Len() > 0 {
+			s.ch <- append(w.buf.Bytes(), '\n')
+		}
+		close(s.ch)
+		return nil
+	}
+}
+
+// waitForStdin waits for the next lines of standard input.
+func waitForStdin(s *stdinSource) tea.Cmd {
+	return func() tea.Msg {
+		data, ok := <-s.ch
+		if !ok {
+			return stdinClosedMsg{}
+		}
+		return stdinDataMsg{data: data}
+	}
+}
+
+// showStdin puts standard input in the viewer, with the lines read so far.
+func (m *Model) showStdin() tea.Cmd {
+	m.stdinView = true
+	m.logDecoder = logDecoder{}
+	m.multiFiles = nil
+	m.focused = paneViewer
+	filter := m.viewerPane.GetTailFilter()
+	m.viewerPane.Clear()
+	m.viewerPane.SetTailFilter(filter)
+	m.metrics.Reset()
+	m.resetPlugins()
+	if len(m.stdin.backlog) > 0 {
+		data := decodeLog([]byte(strings.Join(m.stdin.backlog, "")), "auto")
+		m.viewerPane.AppendTailData(data)
+		m.feedMetrics(data)
+		m.feedPlugins(data)
+	}
+	if m.stdin.closed {
+		m.viewerPane.SetTitle(i18n.T(" stdin (ended) "))
+		m.setContext(i18n.T("\033[33mstdin ended\033[0m after %d lines", m.stdin.lines))
+		return nil
+	}
+	m.viewerPane.StartSpinner("stdin")
+	m.setContext(i18n.T("\033[38;2;3;175;255mReading\033[0m stdin"))
+	if !m.spinnerTicking {
+		m.spinnerTicking = true
+		return spinnerTickCmd()
+	}
+	return nil
+}
+
+// onStdinData shows lines of standard input if it is in the viewer.
+func (m Model) onStdinData(msg stdinDataMsg) (tea.Model, tea.Cmd) {
+	m.stdin.keep(msg.data)
+	if m.stdinView {
+		// Not the open folder's: stdin has nothing to do with it
+		m.showLogData(msg.data, "auto")
+	}
+	return m, waitForStdin(m.stdin)
+}
+
+// onStdinClosed reports the end of standard input.
+func (m Model) onStdinClosed() (tea.Model, tea.Cmd) {
+	m.stdin.closed = true
+	logger.Log("app", "stdin ended after %d lines", m.stdin.lines)
+	if !m.stdinView {
+		return m, nil
+	}
+	m.viewerPane.StopSpinner()
+	m.viewerPane.SetTitle(i18n.T(" stdin (ended) "))
+	m.setContext(i18n.T("\033[33mstdin ended\033[0m after %d lines", m.stdin.lines))
+	if m.stdin.err != nil {
+		m.errorMsg = m.stdin.err.Error()
+	}
+	return m, nil
+}
+
+// filterStdin applies a new tail filter to the lines of standard input
+// kept so far, which can't be read again.
+func (m Model) filterStdin(filter string) (tea.Model, tea.Cmd) {
+	m.viewerPane.SetTailFilter(filter)
+	cmd := m.showStdin()
+	if filter != "" {
+		m.setContext(i18n.T("%s \033[33m[filter: %s]\033[0m", m.lastContext, filter))
+	}
+	return m, cmd
+}
@@ -49,6 +49,10 @@ var (
 				Foreground(focusedColor).
 				Bold(true)
 
+	markedFileStyle = lipgloss.NewStyle().
+			Foreground(warnColor).
+			Bold(true)
+
 	// Dim style for secondary columns (size, date)
 	dimStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("8"))
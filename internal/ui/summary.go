@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"log-monitor/internal/ssh"
+)
+
+// sessionStats counts what was done in a run, for the summary printed on
+// exit with defaults.exit_summary. It is shared by the copies of the model.
+type sessionStats struct {
+	start      time.Time
+	files      []string // server:path of each file tailed, in order
+	tailed     map[string]bool
+	lines      int
+	bytes      int64
+	tailLosses int // tails that lost their connection or ended on the server
+	downloads  int
+}
+
+func newSessionStats() *sessionStats {
+	return &sessionStats{start: time.Now(), tailed: make(map[string]bool)}
+}
+
+// tailing records a file being tailed.
+func (s *sessionStats) tailing(server, path string) {
+	key := server + ":" + path
+	if !s.tailed[key] {
+		s.tailed[key] = true
+		s.files = append(s.files, key)
+	}
+}
+
+// received counts log data that arrived, from the first read or the tail.
+func (s *sessionStats) received(data []byte) {
+	s.lines += bytes.Count(data, []byte("\n"))
+	s.bytes += int64(len(data))
+}
+
+// writeSummary writes the session summary to w. servers are the pool's
+// counters, names the configured server names by ServerKey.
+func (s *sessionStats) writeSummary(w io.Writer, servers []ssh.ServerStats, names map[string]string, end time.Time) {
+	var connected []string
+	for _, srv := range servers {
+		if srv.Dials > srv.Failures {
+			name, ok := names[srv.Key]
+			if !ok {
+				name = srv.Key
+			}
+			connected = append(connected, name)
+		}
+	}
+	slices.Sort(connected)
+
+	fmt.Fprintf(w, "Session %s – %s (%s)\n", s.start.Format("2006-01-02 15:04"), end.Format("15:04"),
+		end.Sub(s.start).Round(time.Second))
+	fmt.Fprintf(w, "  Servers connected: %d", len(connected))
+	if len(connected) > 0 {
+		fmt.Fprintf(w, " (%s)", strings.Join(connected, ", "))
+	}
+	fmt.Fprintf(w, "\n  Files tailed:      %d\n", len(s.files))
+	for _, f := range s.files {
+		fmt.Fprintf(w, "    %s\n", f)
+	}
+	fmt.Fprintf(w, "  Received:          %s lines, %s\n", formatLineCount(s.lines), ssh.FormatSize(s.bytes))
+	fmt.Fprintf(w, "  Tail losses:       %d (tails that lost their connection or ended)\n", s.tailLosses)
+	fmt.Fprintf(w, "  Downloads:         %d\n", s.downloads)
+}
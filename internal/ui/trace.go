@@ -0,0 +1,336 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"log-monitor/internal/config"
+	"log-monitor/internal/i18n"
+	"log-monitor/internal/logger"
+	"log-monitor/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// traceIDRes find the ID worth tracing in a line: the value of a field
+// named like a request ID, or else a UUID.
+var traceIDRes = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(?:x-)?(?:request|req|trace|correlation|transaction|span)[-_ ]?id["']?\s*[=:]\s*["']?([\w.:-]+)`),
+	regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`),
+}
+
+// guessTraceID returns the request ID a line seems to carry, or "".
+func guessTraceID(line string) string {
+	for _, re := range traceIDRes {
+		if m := re.FindStringSubmatch(line); m != nil {
+			return m[len(m)-1]
+		}
+	}
+	return ""
+}
+
+// traceHit is a line mentioning the traced ID.
+type traceHit struct {
+	at    time.Time // when it was logged; zero if the file gave no timestamp yet
+	label string    // file it is from, as shown
+	text  string
+}
+
+// trace is a search of all log folders of a server, or of the servers of
+// its group, for an ID, whose hits are shown merged in time order.
+type trace struct {
+	servers []config.ServerConfig
+	scope   string // what is searched, for messages: a server or a group
+	id      string
+	hits    []traceHit
+	files   int     // files searched
+	errs    []error // why servers or folders could not be searched
+}
+
+// traceDoneMsg carries the hits of a trace.
+type traceDoneMsg struct {
+	trace *trace
+}
+
+// showTracePrompt asks for the ID to trace, suggesting the one on the line
+// under the viewer cursor. Tab switches between the server and its group.
+func (m Model) showTracePrompt() Model {
+	if m.currentServer == nil {
+		m.errorMsg = i18n.T("select a server first")
+		return m
+	}
+	ti := styledInput()
+	ti.Placeholder = "Request ID"
+	if text, _, ok := m.viewerPane.CursorLine(); ok {
+		ti.SetValue(guessTraceID(text))
+	}
+	ti.Focus()
+
+	m.modal = modalTrace
+	m.modalInput = ti
+	m.traceGroup = false
+	return m
+}
+
+// traceHint describes what the trace prompt will search.
+func (m Model) traceHint() string {
+	group := m.currentServer.Group
+	if m.traceGroup {
+		return i18n.T("grep all log folders of the servers of %s for the ID and merge the hits in time order", group) +
+			"\n" + i18n.T("Tab: only %s", m.currentServer.Name)
+	}
+	hint := i18n.T("grep all log folders of %s for the ID and merge the hits in time order", m.currentServer.Name)
+	if group != "" {
+		hint += "\n" + i18n.T("Tab: all servers of %s", group)
+	}
+	return hint
+}
+
+// traceServers returns the servers a trace searches, and those of the group
+// it skips because nobody is there to answer their prompts.
+func (m Model) traceServers() (servers []config.ServerConfig, skipped []string) {
+	if !m.traceGroup {
+		return []config.ServerConfig{*m.currentServer}, nil
+	}
+	current := ssh.ServerKey(*m.currentServer)
+	for _, srv := range m.groupServers(m.currentServer.Group) {
+		if ssh.ServerKey(srv) != current && (m.needsSudoCredentials(srv) || srv.Auth.Method == "keyboard-interactive") {
+			skipped = append(skipped, srv.Name)
+			continue
+		}
+		servers = append(servers, srv)
+	}
+	return servers, skipped
+}
+
+// startTrace searches the log folders of the current server for id.
+func (m Model) startTrace(id string) (tea.Model, tea.Cmd) {
+	if id == "" || m.currentServer == nil {
+		return m, nil
+	}
+	servers, skipped := m.traceServers()
+	m.stopTailInPlace()
+	m.currentFile = nil
+	m.multiFiles = nil
+	t := &trace{servers: servers, scope: m.currentServer.Name, id: id}
+	if m.traceGroup {
+		t.scope = m.currentServer.Group
+	}
+	m.trace = t
+	ctx, cancel := context.WithCancel(context.Background())
+	m.traceCancel = cancel
+	logger.Log("app", "tracing %q on %d server(s) of %s", id, len(servers), t.scope)
+
+	m.viewerPane.Clear()
+	m.viewerPane.StartSpinner(i18n.T("Trace: %s", id))
+	m.focused = paneViewer
+	m.updateTerminalTitle()
+	note := ""
+	if len(skipped) > 0 {
+		note = fmt.Sprintf(", skipping %s (needs a password)", strings.Join(skipped, ", "))
+	}
+	m.setContext(i18n.T("\033[33mTracing\033[0m %s on %s%s…", id, t.scope, note))
+
+	cmds := []tea.Cmd{traceCmd(ctx, m.pool, t)}
+	if !m.spinnerTicking {
+		m.spinnerTicking = true
+		cmds = append(cmds, spinnerTickCmd())
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// stopTrace cancels the running trace, if any, and forgets its hits.
+func (m *Model) stopTrace() {
+	if m.traceCancel != nil {
+		m.traceCancel()
+		m.traceCancel = nil
+	}
+	m.trace = nil
+}
+
+// onTraceDone shows the hits of a trace in time order, each prefixed with
+// its file.
+func (m Model) onTraceDone(msg traceDoneMsg) (tea.Model, tea.Cmd) {
+	t := msg.trace
+	if t != m.trace {
+		return m, nil
+	}
+	m.traceCancel = nil
+	m.viewerPane.StopSpinner()
+	m.viewerPane.SetTitle(i18n.T(" Trace: %s ", t.id))
+	if len(t.errs) > 0 {
+		m.errorMsg = i18n.T("trace: %v", errors.Join(t.errs...))
+	}
+
+	var labels []string
+	seen := make(map[string]bool)
+	var b strings.Builder
+	for _, h := range t.hits {
+		if !seen[h.label] {
+			seen[h.label] = true
+			labels = append(labels, h.label)
+		}
+		fmt.Fprintf(&b, "[%s] %s\n", h.label, h.text)
+	}
+	m.viewerPane.SetSources(labels)
+	m.viewerPane.AppendTailData([]byte(b.String()))
+	m.setContext(i18n.T("\033[32m%d hit(s)\033[0m for %s in %d of %d file(s) on %s — \033[90min time order\033[0m",
+		len(t.hits), t.id, len(labels), t.files, t.scope))
+	return m, nil
+}
+
+// traceCmd greps every log folder of the trace's servers for its ID, on all
+// of them at once, and orders the hits by time.
+func traceCmd(ctx context.Context, pool *ssh.Pool, t *trace) tea.Cmd {
+	return func() tea.Msg {
+		type result struct {
+			hits  []traceHit
+			files int
+			errs  []error
+		}
+		results := make([]result, len(t.servers))
+		var wg sync.WaitGroup
+		for i, srv := range t.servers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				prefix := ""
+				if len(t.servers) > 1 {
+					prefix = srv.Name + ":"
+				}
+				r := &results[i]
+				r.hits, r.files, r.errs = traceServer(ctx, pool, srv, t.id, prefix)
+			}()
+		}
+		wg.Wait()
+
+		for _, r := range results {
+			t.hits = append(t.hits, r.hits...)
+			t.files += r.files
+			t.errs = append(t.errs, r.errs...)
+		}
+		sort.SliceStable(t.hits, func(i, j int) bool {
+			return t.hits[i].at.Before(t.hits[j].at)
+		})
+		return traceDoneMsg{trace: t}
+	}
+}
+
+// traceServer greps every log folder of srv for id. The hits are labeled
+// with their file, after prefix.
+func traceServer(ctx context.Context, pool *ssh.Pool, srv config.ServerConfig, id, prefix string) (hits []traceHit, files int, errs []error) {
+	fail := func(err error) {
+		if ctx.Err() == nil {
+			errs = append(errs, fmt.Errorf("%s: %w", srv.Name, err))
+		}
+	}
+	connCtx, cancel := context.WithTimeout(ctx, srv.ConnectTimeout)
+	defer cancel()
+	client, err := pool.GetClient(connCtx, srv)
+	if err != nil {
+		fail(err)
+		return nil, 0, errs
+	}
+	release := pool.Hold(srv)
+	defer release()
+	opts := commandOpts(pool, srv)
+
+	// The same name in two folders is told apart by the folder
+	type folderFiles struct {
+		dir   string
+		names []string
+	}
+	var folders []folderFiles
+	count := make(map[string]int)
+	for _, folder := range srv.LogFolders {
+		if folder.IsKubectl() {
+			continue
+		}
+		listCtx, cancel := context.WithTimeout(ctx, srv.CommandTimeout)
+		listed, err := ssh.ListFiles(listCtx, client, folder.Path, folder.FilePatterns, opts)
+		cancel()
+		if err != nil {
+			fail(err)
+			continue
+		}
+		names := searchableFiles(listed)
+		for _, name := range names {
+			count[name]++
+		}
+		folders = append(folders, folderFiles{dir: folder.Path, names: names})
+	}
+
+	now := time.Now()
+	for _, f := range folders {
+		if len(f.names) == 0 || ctx.Err() != nil {
+			continue
+		}
+		files += len(f.names)
+		var out bytes.Buffer
+		if err := ssh.Grep(ctx, client, f.dir, f.names, regexp.QuoteMeta(id), maxSearchHits, &out, opts); err != nil {
+			fail(err)
+		}
+		last := make(map[string]time.Time)
+		sc := bufio.NewScanner(&out)
+		sc.Buffer(nil, 1<<20)
+		for sc.Scan() {
+			name, _, text, ok := ssh.ParseGrepHit(sc.Text())
+			if !ok {
+				continue
+			}
+			label := name
+			if count[name] > 1 {
+				label = filepath.Join(filepath.Base(f.dir), name)
+			}
+			label = prefix + label
+			// Lines without a timestamp go with the file's line before
+			if at, ok := hitTime(plainText(text), now); ok {
+				last[label] = at
+			}
+			hits = append(hits, traceHit{at: last[label], label: label, text: text})
+		}
+	}
+	return hits, files, errs
+}
+
+// hitTimeLayouts are the full timestamps a hit may start with; Go takes
+// fractional seconds after the seconds without them being in the layout.
+var hitTimeLayouts = []string{
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05-0700",
+	"2006-01-02 15:04:05",
+}
+
+// hitTime returns when a line was logged, from its leading timestamp. Lines
+// with a full date are placed on it, syslog-style ones in the current year,
+// and times of day alone on the last day they could be from.
+func hitTime(line string, now time.Time) (time.Time, bool) {
+	ts, _, _, ok := splitLogPrefix(line)
+	if !ok {
+		return time.Time{}, false
+	}
+	ts = strings.Trim(ts, "[]")
+	iso := strings.Replace(strings.Replace(ts, "T", " ", 1), ",", ".", 1)
+	for _, layout := range hitTimeLayouts {
+		if at, err := time.ParseInLocation(layout, iso, now.Location()); err == nil {
+			return at, true
+		}
+	}
+	if at, err := time.ParseInLocation("Jan _2 15:04:05", ts, now.Location()); err == nil {
+		at = at.AddDate(now.Year(), 0, 0)
+		if at.After(now.Add(24 * time.Hour)) {
+			at = at.AddDate(-1, 0, 0)
+		}
+		return at, true
+	}
+	return lineTime(line, now)
+}
@@ -3,24 +3,35 @@ package ui
 import (
 	"fmt"
 	"strings"
+	"time"
+	"unicode/utf8"
+
+	"log-monitor/internal/logger"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/ansi"
+	"github.com/mattn/go-runewidth"
 )
 
 const defaultViewerTitle = " Log Viewer "
 const maxViewerLines = 10000
+const rateWindow = 10 // seconds the tail rate is averaged over
 const gutterWidth = 8 // "NNNNN | " = 5 digits + space + pipe + space
 const gutterFmt = "\033[90m%5d |\033[0m "
+const cursorGutterFmt = "\033[1;36m%5d ▶\033[0m "
 
 var blankGutter = strings.Repeat(" ", gutterWidth)
 var spinnerFrames = []rune{'⠋', '⠙', '⠹', '⠸', '⠼', '⠴', '⠦', '⠧', '⠇', '⠏'}
 
 // viewerLine stores a line's number separately from its colorized content.
 type viewerLine struct {
-	num     int    // original file line number
-	content string // colorized content (without line number prefix)
+	num     int       // original file line number
+	raw     string    // line as received, before sanitizing and colorization
+	content string    // colorized content (without line number prefix)
+	marker  string    // label of a checkpoint divider; such lines have no file content
+	event   bool      // the marker comes from the event feed rather than the user
+	at      time.Time // when the line was logged, when dimming old lines; zero = unknown
 }
 
 // ViewerPaneModel holds the state for the log viewer pane.
@@ -36,7 +47,10 @@ type ViewerPaneModel struct {
 	nextLineNum  int // next line number to assign from tail data
 
 	// Tail filter
-	tailFilter string
+	tailFilter   string
+	matchCount   int    // lines that passed the filter since the last reset
+	firstMatchAt string // time of day of the first timestamped match
+	lastMatchAt  string // time of day of the latest timestamped match
 
 	// Spinner
 	spinning     bool
@@ -48,6 +62,48 @@ type ViewerPaneModel struct {
 
 	// Word wrap
 	wrapEnabled bool
+
+	// Column alignment
+	alignEnabled    bool
+	alignTsWidth    int  // widest leading timestamp seen
+	alignLevelWidth int  // widest level token seen
+	alignDirty      bool // columns grew; stored lines need re-padding
+
+	// Raw mode: show bytes as received, without any decoration
+	rawMode bool
+
+	// Dimming of lines logged longer ago than dimAfter; 0 = off
+	dimAfter time.Duration
+	lastAt   time.Time // time of the last timestamped line, for the lines after it
+	dimmed   int       // lines dimmed when the content was last built
+
+	// Lines beyond maxViewerLines, kept on disk when spillHistory is on
+	// instead of being dropped, and the lines paged back in from there
+	// that stay in memory while the view is scrolled up
+	spillHistory bool
+	spill        *historySpill
+	pagedIn      int
+
+	// Files whose lines start with a "[name] " prefix when several are
+	// tailed together, in the order their prefix colors are given out
+	sources []string
+
+	// Display of tabs and other control characters
+	tabWidth     int
+	controlChars string // "strip", "symbols" or "caret"
+
+	// Line cursor (set by clicking a line), -1 = none
+	cursorLine int
+	rowLines   []int // maps rendered viewport row -> index into lines
+
+	markerJump int // index into lines of the marker last jumped to, -1 = none
+
+	annotations map[string]string // IP address -> label shown after each occurrence
+
+	// Lines received per second over the last rateWindow seconds, by
+	// Unix second modulo rateWindow
+	rateCounts [rateWindow]int
+	rateSecs   [rateWindow]int64
 }
 
 // NewViewerPaneModel creates a new viewer pane model.
@@ -56,12 +112,30 @@ func NewViewerPaneModel() ViewerPaneModel {
 		title:        defaultViewerTitle,
 		startLineNum: 1,
 		nextLineNum:  1,
+		cursorLine:   -1,
+		markerJump:   -1,
+		tabWidth:     8,
+		controlChars: "strip",
 	}
 	vp.viewport = viewport.New(0, 0)
 	vp.viewport.SetContent("")
 	return vp
 }
 
+// SetDisplayOptions sets how tabs are expanded and how other control
+// characters are shown, and re-renders the stored lines.
+func (vp *ViewerPaneModel) SetDisplayOptions(tabWidth int, controlChars string) {
+	vp.tabWidth = tabWidth
+	vp.controlChars = controlChars
+	vp.redecorate()
+	vp.rebuildContent()
+}
+
+// sanitize applies the display options to a raw line.
+func (vp *ViewerPaneModel) sanitize(raw string) string {
+	return sanitizeLine(raw, vp.tabWidth, vp.controlChars)
+}
+
 // SetSize updates dimensions and the internal viewport.
 func (vp *ViewerPaneModel) SetSize(w, h int) {
 	vp.width = w
@@ -83,9 +157,16 @@ func (vp *ViewerPaneModel) SetSize(w, h int) {
 // SetText replaces all content with initial file content.
 func (vp *ViewerPaneModel) SetText(text string, startLine int) {
 	vp.lines = nil
+	vp.DropHistory()
 	vp.startLineNum = startLine
 	vp.nextLineNum = startLine
 	vp.lineCount = 0
+	vp.alignTsWidth = 0
+	vp.alignLevelWidth = 0
+	vp.cursorLine = -1
+	vp.markerJump = -1
+	vp.lastAt = time.Time{}
+	vp.resetMatches()
 
 	if text == "" {
 		vp.rebuildContent()
@@ -94,23 +175,10 @@ func (vp *ViewerPaneModel) SetText(text string, startLine int) {
 
 	rawLines := strings.Split(strings.TrimRight(text, "\n"), "\n")
 	for _, line := range rawLines {
-		line = sanitizeLine(line)
-		origNum := vp.nextLineNum
-		vp.nextLineNum++
-
-		// Apply filter
-		if vp.tailFilter != "" && !strings.Contains(strings.ToLower(line), strings.ToLower(vp.tailFilter)) {
-			continue
-		}
-
-		colorized := ColorizeLine(line)
-		if vp.tailFilter != "" {
-			colorized = highlightFilterANSI(colorized, vp.tailFilter)
-		}
-		vp.lines = append(vp.lines, viewerLine{num: origNum, content: colorized})
-		vp.lineCount++
+		vp.appendLine(line)
 	}
 
+	vp.redecorateIfAligned()
 	vp.rebuildContent()
 	vp.viewport.GotoBottom()
 }
@@ -119,6 +187,7 @@ func (vp *ViewerPaneModel) SetText(text string, startLine int) {
 func (vp *ViewerPaneModel) AppendTailData(data []byte) {
 	text := string(data)
 	rawLines := strings.Split(text, "\n")
+	now := time.Now().Unix()
 
 	for i, line := range rawLines {
 		// Skip trailing empty from split
@@ -126,53 +195,536 @@ func (vp *ViewerPaneModel) AppendTailData(data []byte) {
 			break
 		}
 
-		line = sanitizeLine(line)
-		origNum := vp.nextLineNum
-		vp.nextLineNum++
+		vp.countRate(now)
+		vp.appendLine(line)
+	}
+	vp.redecorateIfAligned()
 
-		// Apply filter
-		if vp.tailFilter != "" && !strings.Contains(strings.ToLower(line), strings.ToLower(vp.tailFilter)) {
-			continue
+	wasAtBottom := vp.viewport.AtBottom()
+	vp.capLines(wasAtBottom)
+	vp.rebuildContent()
+	if wasAtBottom {
+		vp.viewport.GotoBottom()
+	}
+}
+
+// capLines keeps at most maxViewerLines lines in memory, plus those paged
+// back in while the view is scrolled up, spilling the oldest to disk when
+// spillHistory is on and dropping them otherwise.
+func (vp *ViewerPaneModel) capLines(atBottom bool) {
+	if atBottom {
+		vp.pagedIn = 0
+	}
+	excess := len(vp.lines) - maxViewerLines - vp.pagedIn
+	if excess <= 0 {
+		return
+	}
+	if vp.spillHistory {
+		vp.spillLines(vp.lines[:excess])
+	}
+	vp.lines = vp.lines[excess:]
+	if vp.cursorLine >= 0 {
+		vp.cursorLine = max(-1, vp.cursorLine-excess)
+	}
+	if vp.markerJump >= 0 {
+		vp.markerJump = max(-1, vp.markerJump-excess)
+	}
+}
+
+// spillLines writes lines leaving the top of the viewer to the history
+// spill, starting one if needed. On failure spilling is turned off and
+// lines are dropped as without it.
+func (vp *ViewerPaneModel) spillLines(lines []viewerLine) {
+	if vp.spill == nil {
+		spill, err := newHistorySpill()
+		if err != nil {
+			logger.Log("viewer", "%v", err)
+			vp.spillHistory = false
+			return
+		}
+		vp.spill = spill
+	}
+	if err := vp.spill.push(lines); err != nil {
+		logger.Log("viewer", "%v", err)
+		vp.DropHistory()
+		vp.spillHistory = false
+	}
+}
+
+// pageIn puts the last spillPage lines spilled back on top of the viewer,
+// keeping the view on the lines it shows. Returns whether there were any.
+func (vp *ViewerPaneModel) pageIn() bool {
+	if vp.spill == nil || vp.spill.Len() == 0 {
+		return false
+	}
+	recs, err := vp.spill.pop(spillPage)
+	if err != nil {
+		logger.Log("viewer", "%v", err)
+		return false
+	}
+	lines := make([]viewerLine, 0, len(recs)+len(vp.lines))
+	for _, r := range recs {
+		l := viewerLine{num: r.Num, raw: r.Raw, marker: r.Marker, event: r.Event, at: r.At}
+		if l.marker == "" {
+			if vp.alignEnabled {
+				_, body := vp.sourcePrefix(l.raw)
+				if vp.measureColumns(vp.sanitize(body)) {
+					vp.alignDirty = true
+				}
+			}
+			l.content = vp.decorate(l.raw)
+		}
+		lines = append(lines, l)
+	}
+	n := len(recs)
+	vp.lines = append(lines, vp.lines...)
+	vp.pagedIn += n
+	if vp.cursorLine >= 0 {
+		vp.cursorLine += n
+	}
+	if vp.markerJump >= 0 {
+		vp.markerJump += n
+	}
+	vp.redecorateIfAligned()
+
+	offset := vp.viewport.YOffset
+	vp.rebuildContent()
+	for row, i := range vp.rowLines {
+		if i == n {
+			vp.viewport.SetYOffset(offset + row)
+			break
+		}
+	}
+	return true
+}
+
+// SetSpillHistory turns on keeping the lines beyond maxViewerLines in a
+// temporary file, from which scrolling above the top pages them back in.
+func (vp *ViewerPaneModel) SetSpillHistory(on bool) {
+	vp.spillHistory = on
+}
+
+// Spilled returns the number of lines kept on disk above the viewer.
+func (vp *ViewerPaneModel) Spilled() int {
+	if vp.spill == nil {
+		return 0
+	}
+	return vp.spill.Len()
+}
+
+// DropHistory removes the lines spilled to disk, along with their file.
+func (vp *ViewerPaneModel) DropHistory() {
+	if vp.spill != nil {
+		vp.spill.close()
+		vp.spill = nil
+	}
+	vp.pagedIn = 0
+}
+
+// appendLine assigns the next line number to a raw line and, if it passes the
+// tail filter, stores it with its decorated content.
+func (vp *ViewerPaneModel) appendLine(raw string) {
+	origNum := vp.nextLineNum
+	vp.nextLineNum++
+
+	line := vp.sanitize(raw)
+
+	// Apply filter
+	if vp.tailFilter != "" && !strings.Contains(strings.ToLower(line), strings.ToLower(vp.tailFilter)) {
+		return
+	}
+
+	if vp.tailFilter != "" {
+		vp.recordMatch(line)
+	}
+	if vp.alignEnabled {
+		_, body := vp.sourcePrefix(raw)
+		if vp.measureColumns(vp.sanitize(body)) {
+			vp.alignDirty = true
+		}
+	}
+	vp.lines = append(vp.lines, viewerLine{num: origNum, raw: raw, content: vp.decorate(raw), at: vp.loggedAt(raw)})
+	vp.lineCount++
+}
+
+// countRate counts a line received in second sec for the tail rate.
+func (vp *ViewerPaneModel) countRate(sec int64) {
+	i := sec % rateWindow
+	if vp.rateSecs[i] != sec {
+		vp.rateSecs[i] = sec
+		vp.rateCounts[i] = 0
+	}
+	vp.rateCounts[i]++
+}
+
+// Rate returns the average number of lines received per second over the
+// last rateWindow seconds.
+func (vp *ViewerPaneModel) Rate() float64 {
+	now := time.Now().Unix()
+	total := 0
+	for i, sec := range vp.rateSecs {
+		if now-sec < rateWindow {
+			total += vp.rateCounts[i]
+		}
+	}
+	return float64(total) / rateWindow
+}
+
+// FileLines returns the number of the last line read from the file, i.e.
+// its length so far, filtered lines included.
+func (vp *ViewerPaneModel) FileLines() int {
+	return vp.nextLineNum - 1
+}
+
+// recordMatch updates the filter match statistics with a matching line.
+func (vp *ViewerPaneModel) recordMatch(line string) {
+	vp.matchCount++
+	if at, ok := lineTimeOfDay(line); ok {
+		if vp.firstMatchAt == "" {
+			vp.firstMatchAt = at
+		}
+		vp.lastMatchAt = at
+	}
+}
+
+// resetMatches clears the filter match statistics.
+func (vp *ViewerPaneModel) resetMatches() {
+	vp.matchCount = 0
+	vp.firstMatchAt = ""
+	vp.lastMatchAt = ""
+}
+
+// FilterStats summarizes the lines matched by the tail filter, e.g.
+// "132 matches, first at 09:12:03, last at 09:40:11". Returns "" when no
+// filter is active.
+func (vp *ViewerPaneModel) FilterStats() string {
+	if vp.tailFilter == "" {
+		return ""
+	}
+	noun := "matches"
+	if vp.matchCount == 1 {
+		noun = "match"
+	}
+	stats := fmt.Sprintf("%s %s", formatLineCount(vp.matchCount), noun)
+	if vp.firstMatchAt != "" {
+		stats += fmt.Sprintf(", first at %s, last at %s", vp.firstMatchAt, vp.lastMatchAt)
+	}
+	return stats
+}
+
+// decorate turns a raw line into its on-screen form: sanitizing, column
+// alignment, colorization and filter highlighting. In raw mode the line is
+// only escaped byte-for-byte.
+func (vp *ViewerPaneModel) decorate(raw string) string {
+	if vp.rawMode {
+		return rawEscape(raw)
+	}
+	prefix, raw := vp.sourcePrefix(raw)
+	line := vp.sanitize(raw)
+	if vp.alignEnabled {
+		line = alignColumns(line, vp.alignTsWidth, vp.alignLevelWidth)
+	}
+	colorized := prefix + ColorizeLine(line)
+	if vp.tailFilter != "" {
+		colorized = highlightFilterANSI(colorized, vp.tailFilter)
+	}
+	if len(vp.annotations) > 0 {
+		colorized = annotateANSI(colorized, vp.annotations)
+	}
+	return colorized
+}
+
+// SetDimAfter dims the lines logged longer ago than d, going by their
+// timestamps, so fresh output stands out. 0 turns it off.
+func (vp *ViewerPaneModel) SetDimAfter(d time.Duration) {
+	vp.dimAfter = d
+}
+
+// loggedAt returns when a line was logged, when dimming old lines. Lines
+// without a timestamp, like continuations, take the time of the line before.
+func (vp *ViewerPaneModel) loggedAt(raw string) time.Time {
+	if vp.dimAfter <= 0 {
+		return time.Time{}
+	}
+	_, body := vp.sourcePrefix(raw)
+	if t, ok := lineTime(plainText(vp.sanitize(body)), time.Now()); ok {
+		vp.lastAt = t
+	}
+	return vp.lastAt
+}
+
+// shown returns a line's content as rendered: dimmed if it was logged
+// before cutoff.
+func (vp *ViewerPaneModel) shown(line viewerLine, cutoff time.Time) string {
+	if cutoff.IsZero() || line.at.IsZero() || !line.at.Before(cutoff) || vp.rawMode {
+		return line.content
+	}
+	vp.dimmed++
+	return ansiDarkGray + ansi.Strip(line.content) + ansiReset
+}
+
+// dimCutoff returns the time lines logged before are dimmed, or zero.
+func (vp *ViewerPaneModel) dimCutoff() time.Time {
+	if vp.dimAfter <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-vp.dimAfter)
+}
+
+// RefreshDimming dims the lines that have grown old since the content was
+// last built, for a tail that has gone quiet.
+func (vp *ViewerPaneModel) RefreshDimming() {
+	cutoff := vp.dimCutoff()
+	if cutoff.IsZero() || vp.rawMode {
+		return
+	}
+	n := 0
+	for _, l := range vp.lines {
+		if l.marker == "" && !l.at.IsZero() && l.at.Before(cutoff) {
+			n++
+		}
+	}
+	if n == vp.dimmed {
+		return
+	}
+	wasAtBottom := vp.viewport.AtBottom()
+	vp.rebuildContent()
+	if wasAtBottom {
+		vp.viewport.GotoBottom()
+	}
+}
+
+// sourceColors tell apart the files tailed together.
+var sourceColors = []string{"\033[1;36m", "\033[1;35m", "\033[1;33m", "\033[1;32m", "\033[1;34m", "\033[1;31m"}
+
+// SetSources makes the viewer color the "[name] " prefix that starts each
+// line of files tailed together, in a color per file. nil turns it off.
+func (vp *ViewerPaneModel) SetSources(names []string) {
+	vp.sources = names
+	vp.redecorate()
+	vp.rebuildContent()
+}
+
+// sourcePrefix splits the colored "[name] " prefix off a line of files
+// tailed together.
+func (vp *ViewerPaneModel) sourcePrefix(raw string) (prefix, rest string) {
+	if len(vp.sources) == 0 || !strings.HasPrefix(raw, "[") {
+		return "", raw
+	}
+	for i, name := range vp.sources {
+		if rest, ok := strings.CutPrefix(raw, "["+name+"] "); ok {
+			color := sourceColors[i%len(sourceColors)]
+			return color + "[" + name + "]" + ansiReset + " ", rest
+		}
+	}
+	return "", raw
+}
+
+// Annotate adds labels shown after every occurrence of the given IP
+// addresses, in the stored lines and those still to come.
+func (vp *ViewerPaneModel) Annotate(labels map[string]string) {
+	if vp.annotations == nil {
+		vp.annotations = make(map[string]string)
+	}
+	for ip, label := range labels {
+		vp.annotations[ip] = label
+	}
+	vp.redecorate()
+	vp.rebuildContent()
+}
+
+// measureColumns widens the alignment columns to fit the line's timestamp
+// and level token. Returns true if either column grew.
+func (vp *ViewerPaneModel) measureColumns(line string) bool {
+	ts, level, _, ok := splitLogPrefix(line)
+	if !ok {
+		return false
+	}
+	grew := false
+	if w := len(ts); w > vp.alignTsWidth {
+		vp.alignTsWidth = w
+		grew = true
+	}
+	if w := len(level); w > vp.alignLevelWidth {
+		vp.alignLevelWidth = w
+		grew = true
+	}
+	return grew
+}
+
+// redecorate recomputes the decorated content of every stored line.
+func (vp *ViewerPaneModel) redecorate() {
+	for i := range vp.lines {
+		if vp.lines[i].marker == "" {
+			vp.lines[i].content = vp.decorate(vp.lines[i].raw)
 		}
+	}
+}
 
-		colorized := ColorizeLine(line)
-		if vp.tailFilter != "" {
-			colorized = highlightFilterANSI(colorized, vp.tailFilter)
+// AddMarker appends a checkpoint divider stamped with the given time after
+// the last line received. Markers ignore the tail filter and stay until the
+// file is reloaded.
+func (vp *ViewerPaneModel) AddMarker(at time.Time) string {
+	label := fmt.Sprintf("marker %s", at.Format("15:04:05"))
+	wasAtBottom := vp.viewport.AtBottom()
+	vp.lines = append(vp.lines, viewerLine{num: -1, marker: label})
+	vp.markerJump = -1
+	vp.rebuildContent()
+	if wasAtBottom {
+		vp.viewport.GotoBottom()
+	}
+	return label
+}
+
+// AddNotice appends a divider saying what happened to the tail, such as why
+// it stopped or that the file rotated, drawn like a feed event.
+func (vp *ViewerPaneModel) AddNotice(text string) {
+	wasAtBottom := vp.viewport.AtBottom()
+	vp.lines = append(vp.lines, viewerLine{num: -1, marker: text, event: true})
+	vp.rebuildContent()
+	if wasAtBottom {
+		vp.viewport.GotoBottom()
+	}
+}
+
+// RestartNumbering numbers the lines that follow from 1, for a new file
+// taking over the tail.
+func (vp *ViewerPaneModel) RestartNumbering() {
+	vp.nextLineNum = 1
+}
+
+// JumpToMarker scrolls the latest marker to the top of the view; pressed
+// repeatedly it walks back through older markers, then wraps around to the
+// latest. Returns the marker's label, or false if there are no markers.
+func (vp *ViewerPaneModel) JumpToMarker() (string, bool) {
+	latest, target := -1, -1
+	for i, l := range vp.lines {
+		if l.marker == "" {
+			continue
+		}
+		latest = i
+		if i < vp.markerJump {
+			target = i
 		}
-		vp.lines = append(vp.lines, viewerLine{num: origNum, content: colorized})
-		vp.lineCount++
 	}
+	if latest < 0 {
+		return "", false
+	}
+	if target < 0 {
+		target = latest
+	}
+	vp.markerJump = target
+	for row, i := range vp.rowLines {
+		if i == target {
+			vp.viewport.SetYOffset(row)
+			break
+		}
+	}
+	return vp.lines[target].marker, true
+}
 
-	// Cap at max lines
-	if len(vp.lines) > maxViewerLines {
-		excess := len(vp.lines) - maxViewerLines
-		vp.lines = vp.lines[excess:]
+// InsertMarker places an event marker after the last line logged at or
+// before at, going by the lines' leading time of day (in the local time
+// zone). With no timestamped lines to go by, the marker is appended if live
+// and dropped otherwise; it is also dropped if it predates every line.
+// Returns whether the marker was placed.
+func (vp *ViewerPaneModel) InsertMarker(at time.Time, label string, live bool) bool {
+	tod := at.Local().Format("15:04:05")
+	pos, dated := -1, false
+	next := len(vp.lines) // the earliest later timestamped line seen so far
+	for i := len(vp.lines) - 1; i >= 0; i-- {
+		if vp.lines[i].marker != "" {
+			continue
+		}
+		lineTod, ok := lineTimeOfDay(vp.sanitize(vp.lines[i].raw))
+		if !ok {
+			continue
+		}
+		dated = true
+		if lineTod <= tod {
+			// After the line's continuation lines (e.g. a stack trace)
+			pos = next
+			break
+		}
+		next = i
+	}
+	switch {
+	case pos >= 0:
+	case !dated && live:
+		pos = len(vp.lines)
+	default:
+		return false
 	}
 
 	wasAtBottom := vp.viewport.AtBottom()
+	vp.lines = append(vp.lines, viewerLine{})
+	copy(vp.lines[pos+1:], vp.lines[pos:])
+	vp.lines[pos] = viewerLine{num: -1, marker: label, event: true}
+	if vp.cursorLine >= pos {
+		vp.cursorLine++
+	}
+	if vp.markerJump >= pos {
+		vp.markerJump++
+	}
 	vp.rebuildContent()
 	if wasAtBottom {
 		vp.viewport.GotoBottom()
 	}
+	return true
+}
+
+// markerRow renders a marker label as a divider across width columns:
+// magenta for the user's markers, cyan for feed events.
+func markerRow(line viewerLine, width int) string {
+	text := fmt.Sprintf("──── %s ", line.marker)
+	if fill := width - runewidth.StringWidth(text); fill > 0 {
+		text += strings.Repeat("─", fill)
+	}
+	color := "\033[1;35m"
+	if line.event {
+		color = "\033[1;36m"
+	}
+	return color + text + "\033[0m"
+}
+
+// redecorateIfAligned re-pads already stored lines when a wider timestamp or
+// level token arrived after them.
+func (vp *ViewerPaneModel) redecorateIfAligned() {
+	if vp.alignDirty {
+		vp.alignDirty = false
+		vp.redecorate()
+	}
 }
 
 // Clear resets the viewer.
 func (vp *ViewerPaneModel) Clear() {
 	vp.lines = nil
+	vp.DropHistory()
 	vp.title = defaultViewerTitle
 	vp.tailFilter = ""
+	vp.resetMatches()
 	vp.lineCount = 0
 	vp.startLineNum = 1
 	vp.nextLineNum = 1
 	vp.spinning = false
+	vp.alignTsWidth = 0
+	vp.alignLevelWidth = 0
+	vp.cursorLine = -1
+	vp.markerJump = -1
+	vp.lastAt = time.Time{}
+	vp.sources = nil
+	vp.rateCounts = [rateWindow]int{}
+	vp.rateSecs = [rateWindow]int64{}
 	vp.rebuildContent()
 }
 
 // SetMessage displays a message (plain, top-aligned).
 func (vp *ViewerPaneModel) SetMessage(msg string) {
 	vp.lines = nil
+	vp.DropHistory()
 	vp.title = defaultViewerTitle
 	vp.tailFilter = ""
+	vp.resetMatches()
 	vp.lineCount = 0
 	vp.spinning = false
 	vp.startLineNum = 1
@@ -183,8 +735,10 @@ func (vp *ViewerPaneModel) SetMessage(msg string) {
 // SetCenteredMessage displays a pre-rendered block centered in the viewport.
 func (vp *ViewerPaneModel) SetCenteredMessage(block string) {
 	vp.lines = nil
+	vp.DropHistory()
 	vp.title = defaultViewerTitle
 	vp.tailFilter = ""
+	vp.resetMatches()
 	vp.lineCount = 0
 	vp.spinning = false
 	vp.startLineNum = 1
@@ -259,8 +813,12 @@ func (vp *ViewerPaneModel) GotoBottom() {
 	vp.viewport.GotoBottom()
 }
 
-// ScrollUp scrolls up by one line.
+// ScrollUp scrolls up by one line. Above the top, lines spilled to disk
+// are paged back in.
 func (vp *ViewerPaneModel) ScrollUp(n int) {
+	if vp.viewport.YOffset < n {
+		vp.pageIn()
+	}
 	vp.viewport.LineUp(n)
 }
 
@@ -280,7 +838,43 @@ func (vp *ViewerPaneModel) IsWrapEnabled() bool {
 	return vp.wrapEnabled
 }
 
+// ToggleAlign toggles timestamp/level column alignment and rebuilds content.
+func (vp *ViewerPaneModel) ToggleAlign() {
+	vp.alignEnabled = !vp.alignEnabled
+	vp.alignTsWidth = 0
+	vp.alignLevelWidth = 0
+	if vp.alignEnabled {
+		for _, l := range vp.lines {
+			_, body := vp.sourcePrefix(l.raw)
+			vp.measureColumns(vp.sanitize(body))
+		}
+	}
+	vp.redecorate()
+	vp.rebuildContent()
+}
+
+// IsAlignEnabled returns whether column alignment is active.
+func (vp *ViewerPaneModel) IsAlignEnabled() bool {
+	return vp.alignEnabled
+}
+
+// ToggleRaw switches between decorated output and the raw bytes received.
+// Lines dropped by the tail filter were never stored, so the filter still
+// applies in raw mode.
+func (vp *ViewerPaneModel) ToggleRaw() {
+	vp.rawMode = !vp.rawMode
+	vp.redecorate()
+	vp.rebuildContent()
+}
+
+// IsRawMode returns whether the viewer shows raw, undecorated lines.
+func (vp *ViewerPaneModel) IsRawMode() bool {
+	return vp.rawMode
+}
+
 func (vp *ViewerPaneModel) rebuildContent() {
+	vp.rowLines = vp.rowLines[:0]
+	vp.dimmed = 0
 	if len(vp.lines) == 0 {
 		vp.viewport.SetContent("")
 		return
@@ -288,46 +882,183 @@ func (vp *ViewerPaneModel) rebuildContent() {
 
 	var b strings.Builder
 
+	contentWidth := vp.viewport.Width - gutterWidth
+	if contentWidth < 1 {
+		contentWidth = 1
+	}
+	cutoff := vp.dimCutoff()
+
 	if !vp.wrapEnabled {
 		for i, line := range vp.lines {
 			if i > 0 {
 				b.WriteByte('\n')
 			}
-			fmt.Fprintf(&b, gutterFmt, line.num)
-			b.WriteString(line.content)
+			if line.marker != "" {
+				b.WriteString(blankGutter)
+				b.WriteString(markerRow(line, contentWidth))
+			} else {
+				vp.writeGutter(&b, i)
+				b.WriteString(vp.shown(line, cutoff))
+			}
+			vp.rowLines = append(vp.rowLines, i)
 		}
 		vp.viewport.SetContent(b.String())
 		return
 	}
 
 	// Wrapping enabled: wrap content at (viewportWidth - gutterWidth)
-	contentWidth := vp.viewport.Width - gutterWidth
-	if contentWidth < 1 {
-		contentWidth = 1
-	}
-
 	for i, line := range vp.lines {
 		if i > 0 {
 			b.WriteByte('\n')
 		}
-		wrapped := ansi.Hardwrap(line.content, contentWidth, true)
+		if line.marker != "" {
+			b.WriteString(blankGutter)
+			b.WriteString(markerRow(line, contentWidth))
+			vp.rowLines = append(vp.rowLines, i)
+			continue
+		}
+		wrapped := ansi.Hardwrap(vp.shown(line, cutoff), contentWidth, true)
 		parts := strings.Split(wrapped, "\n")
 		for j, part := range parts {
 			if j > 0 {
 				b.WriteByte('\n')
 			}
 			if j == 0 {
-				fmt.Fprintf(&b, gutterFmt, line.num)
+				vp.writeGutter(&b, i)
 			} else {
 				b.WriteString(blankGutter)
 			}
 			b.WriteString(part)
+			vp.rowLines = append(vp.rowLines, i)
 		}
 	}
 
 	vp.viewport.SetContent(b.String())
 }
 
+// writeGutter writes the line-number gutter for lines[i], marking the cursor line.
+func (vp *ViewerPaneModel) writeGutter(b *strings.Builder, i int) {
+	if i == vp.cursorLine {
+		fmt.Fprintf(b, cursorGutterFmt, vp.lines[i].num)
+		return
+	}
+	fmt.Fprintf(b, gutterFmt, vp.lines[i].num)
+}
+
+// SetCursorFromY places the line cursor on the logical line rendered at the
+// given mouse Y coordinate (row 0 = border). Wrapped continuation rows select
+// the line they belong to.
+func (vp *ViewerPaneModel) SetCursorFromY(y int) {
+	row := vp.viewport.YOffset + y - 1
+	if row < 0 || row >= len(vp.rowLines) {
+		return
+	}
+	vp.cursorLine = vp.rowLines[row]
+	vp.rebuildContent()
+}
+
+// MoveCursor moves the line cursor by delta lines and scrolls it into view.
+// Without a cursor it starts on the first line shown.
+func (vp *ViewerPaneModel) MoveCursor(delta int) {
+	if len(vp.lines) == 0 {
+		return
+	}
+	if vp.cursorLine >= 0 && vp.cursorLine+delta < 0 {
+		// Paging in moves the cursor down with the line it is on
+		vp.pageIn()
+	}
+	i := vp.cursorLine + delta
+	if vp.cursorLine < 0 {
+		i = 0
+		if row := vp.viewport.YOffset; row < len(vp.rowLines) {
+			i = vp.rowLines[row]
+		}
+	}
+	vp.cursorLine = min(max(i, 0), len(vp.lines)-1)
+	vp.rebuildContent()
+	vp.scrollToCursor()
+}
+
+// CursorTo puts the line cursor on file line num, if it is shown, and
+// scrolls it to the middle of the pane.
+func (vp *ViewerPaneModel) CursorTo(num int) {
+	for i, l := range vp.lines {
+		if l.num == num && l.marker == "" {
+			vp.cursorLine = i
+			vp.rebuildContent()
+			for row, li := range vp.rowLines {
+				if li == i {
+					vp.viewport.SetYOffset(row - vp.viewport.Height/2)
+					break
+				}
+			}
+			return
+		}
+	}
+}
+
+// scrollToCursor scrolls the least needed to show the cursor line.
+func (vp *ViewerPaneModel) scrollToCursor() {
+	for row, i := range vp.rowLines {
+		if i != vp.cursorLine {
+			continue
+		}
+		switch {
+		case row < vp.viewport.YOffset:
+			vp.viewport.SetYOffset(row)
+		case row >= vp.viewport.YOffset+vp.viewport.Height:
+			vp.viewport.SetYOffset(row - vp.viewport.Height + 1)
+		}
+		return
+	}
+}
+
+// CursorLine returns the original text (see plainText) and line number of
+// the line under the cursor. ok is false when no line is selected.
+func (vp *ViewerPaneModel) CursorLine() (text string, num int, ok bool) {
+	if vp.cursorLine < 0 || vp.cursorLine >= len(vp.lines) {
+		return "", 0, false
+	}
+	l := vp.lines[vp.cursorLine]
+	if l.marker != "" {
+		return "", 0, false
+	}
+	return plainText(l.raw), l.num, true
+}
+
+// PlainText returns the stored lines, those spilled to disk first, as text
+// for exporting: the lines as received without escape sequences, and markers as plain dividers. Only
+// lines that passed the tail filter are included. n counts log lines.
+func (vp *ViewerPaneModel) PlainText() (text string, n int) {
+	var b strings.Builder
+	write := func(raw, marker string) {
+		if marker != "" {
+			fmt.Fprintf(&b, "──── %s ────\n", marker)
+			return
+		}
+		b.WriteString(plainText(raw))
+		b.WriteByte('\n')
+		n++
+	}
+	if vp.spill != nil {
+		if err := vp.spill.each(func(r spillRecord) { write(r.Raw, r.Marker) }); err != nil {
+			logger.Log("viewer", "%v", err)
+		}
+	}
+	for _, l := range vp.lines {
+		write(l.raw, l.marker)
+	}
+	return b.String(), n
+}
+
+// plainText returns a line as received, minus the color and other escape
+// sequences some programs log and a CRLF line's trailing CR, so copies and
+// exports hold clean text. Our own colorization and highlights only exist
+// in the rendered content and never reach it.
+func plainText(raw string) string {
+	return strings.TrimRight(ansi.Strip(raw), "\r")
+}
+
 // View renders the viewer pane.
 func (vp *ViewerPaneModel) View(focused bool) string {
 	var paneStyle, titleStyle lipgloss.Style
@@ -346,13 +1077,14 @@ func (vp *ViewerPaneModel) View(focused bool) string {
 	return placeTitleInBorder(content, title)
 }
 
-// sanitizeLine strips control characters (except tab) from a line to prevent
-// binary data from corrupting the terminal display.
-func sanitizeLine(s string) string {
+// sanitizeLine makes a line safe to print and keeps its columns intact:
+// tabs are expanded to the next multiple of tabWidth, and other control
+// characters are dropped ("strip"), shown as control pictures like ␛ and ␍
+// ("symbols") or in caret notation like ^[ and ^M ("caret").
+func sanitizeLine(s string, tabWidth int, controlChars string) string {
 	clean := true
 	for i := 0; i < len(s); i++ {
-		b := s[i]
-		if b < 0x20 && b != '\t' || b == 0x7F {
+		if b := s[i]; b < 0x20 || b == 0x7F {
 			clean = false
 			break
 		}
@@ -360,14 +1092,67 @@ func sanitizeLine(s string) string {
 	if clean {
 		return s
 	}
-	buf := make([]byte, 0, len(s))
-	for i := 0; i < len(s); i++ {
-		b := s[i]
-		if b >= 0x20 && b != 0x7F || b == '\t' {
-			buf = append(buf, b)
+	if tabWidth < 1 {
+		tabWidth = 1
+	}
+
+	var b strings.Builder
+	col := 0
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '\t':
+			n := tabWidth - col%tabWidth
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+		case r < 0x20 || r == 0x7F:
+			switch controlChars {
+			case "symbols":
+				if r == 0x7F {
+					b.WriteRune('\u2421') // ␡
+				} else {
+					b.WriteRune(0x2400 + r) // ␀ … ␟
+				}
+				col++
+			case "caret":
+				b.WriteByte('^')
+				b.WriteByte(byte(r) ^ 0x40)
+				col += 2
+			}
+		default:
+			// Copy the original bytes so invalid UTF-8 is left as received
+			b.WriteString(s[i : i+size])
+			col += runewidth.RuneWidth(r)
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// rawEscape renders a line byte-for-byte: printable text is kept, and every
+// other byte (control characters, invalid UTF-8, backslashes) is shown as a
+// Go-style escape so nothing is hidden and the terminal can't be corrupted.
+func rawEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size <= 1:
+			fmt.Fprintf(&b, `\x%02x`, s[i])
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20 || r == 0x7F || (r >= 0x80 && r < 0xA0):
+			fmt.Fprintf(&b, `\x%02x`, r)
+		default:
+			b.WriteRune(r)
 		}
+		i += size
 	}
-	return string(buf)
+	return b.String()
 }
 
 // highlightFilterANSI wraps occurrences of query with ANSI highlight (yellow background).
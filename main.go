@@ -1,12 +1,18 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"os"
+	"runtime/pprof"
+	"strings"
 
 	"log-monitor/internal/config"
 	"log-monitor/internal/logger"
+	"log-monitor/internal/ssh"
+	"log-monitor/internal/state"
 	"log-monitor/internal/ui"
 )
 
@@ -16,6 +22,12 @@ func main() {
 	autoServer := flag.String("server", "", "auto-select server by name")
 	autoFolder := flag.String("folder", "", "auto-select folder by path (requires -server)")
 	autoFile := flag.String("file", "", "auto-select file by name (requires -server)")
+	bench := flag.String("bench", "", "replay a local log file through the display pipeline and report its speed")
+	benchFilter := flag.String("bench-filter", "", "tail filter applied during -bench")
+	cpuProfile := flag.String("cpuprofile", "", "write a CPU profile of -bench to this file")
+	exportState := flag.String("export-state", "", "write the file notes to this YAML file to share, then exit")
+	importState := flag.String("import-state", "", "merge file notes from a YAML file written by -export-state, then exit")
+	stdin := flag.Bool("stdin", false, "show standard input in the viewer, e.g. some-command | log-monitor -stdin")
 	flag.Parse()
 
 	if *debugLog != "" {
@@ -26,7 +38,26 @@ func main() {
 		defer logger.Close()
 	}
 
+	if *bench != "" {
+		if err := runBench(*bench, *benchFilter, *cpuProfile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *stdin {
+		if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+			fmt.Fprintln(os.Stderr, "Error: -stdin reads piped input, e.g. journalctl -f | log-monitor -stdin")
+			os.Exit(1)
+		}
+	}
+
 	cfg, err := config.Load(*configPath)
+	if err != nil && *stdin && errors.Is(err, fs.ErrNotExist) {
+		// Without a config, only stdin can be viewed
+		cfg, err = config.Default(), nil
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 		os.Exit(1)
@@ -34,13 +65,103 @@ func main() {
 
 	logger.Log("main", "config loaded, %d servers", len(cfg.Servers))
 
-	if err := ui.Run(cfg, ui.AutoSelect{
+	if *exportState != "" || *importState != "" {
+		if err := runStateTransfer(cfg, *exportState, *importState); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	auto := ui.AutoSelect{
 		Server: *autoServer,
 		Folder: *autoFolder,
 		File:   *autoFile,
-	}); err != nil {
+	}
+	if *stdin {
+		auto.Stdin = os.Stdin
+	}
+	if err := ui.Run(cfg, auto); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 	logger.Log("main", "app exited cleanly")
 }
+
+// runBench runs the display pipeline benchmark, profiling it when
+// profilePath is set.
+func runBench(path, filter, profilePath string) error {
+	if profilePath != "" {
+		f, err := os.Create(profilePath)
+		if err != nil {
+			return fmt.Errorf("creating CPU profile: %w", err)
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			return fmt.Errorf("starting CPU profile: %w", err)
+		}
+		defer pprof.StopCPUProfile()
+	}
+	return ui.Bench(path, filter, os.Stdout)
+}
+
+// runStateTransfer exports the shareable state to exportPath and/or merges
+// the one in importPath, naming servers as in the config so the file works
+// for users who log in under other names.
+func runStateTransfer(cfg *config.Config, exportPath, importPath string) error {
+	statePath := cfg.Defaults.StateFile
+	if statePath == "" {
+		statePath = state.DefaultPath()
+	}
+	store, err := state.Load(statePath)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]string)
+	keys := make(map[string]string)
+	for _, srv := range cfg.Servers {
+		key := ssh.ServerKey(srv)
+		if _, ok := names[key]; !ok {
+			names[key] = srv.Name
+		}
+		keys[srv.Name] = key
+	}
+
+	if exportPath != "" {
+		shared := store.Export(func(key string) string {
+			if name, ok := names[key]; ok {
+				return name
+			}
+			return key
+		})
+		if err := state.WriteShared(exportPath, shared); err != nil {
+			return err
+		}
+		fmt.Printf("Exported notes on %d server(s) to %s\n", len(shared.Notes), exportPath)
+	}
+
+	if importPath != "" {
+		shared, err := state.ReadShared(importPath)
+		if err != nil {
+			return err
+		}
+		res := store.Import(shared, func(server string) (string, bool) {
+			if key, ok := keys[server]; ok {
+				return key, true
+			}
+			// A server outside the config, exported under its key
+			return server, strings.Contains(server, "@")
+		})
+		if err := store.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d note(s) into %s\n", res.Added, statePath)
+		if res.Kept > 0 {
+			fmt.Printf("Kept your own note on %d file(s) with a different one in %s\n", res.Kept, importPath)
+		}
+		if len(res.Unknown) > 0 {
+			fmt.Printf("Skipped servers not in the config: %s\n", strings.Join(res.Unknown, ", "))
+		}
+	}
+	return nil
+}